@@ -18,6 +18,7 @@ const VerifyOTP = ({ navigation}) => {
   const [email,setEmail] = useState("");
   const [newPassword,setNewpassword] = useState("");
   const [conNewPassword,setconNewPassword] = useState("");
+  const [resetTicket,setResetTicket] = useState("");
   const [state,setState] = useState(1);
   useEffect(() => {
     sendmessage();
@@ -104,12 +105,12 @@ const VerifyOTP = ({ navigation}) => {
     if (code.length === 6) {
       try{
         if (Auth.user) {
-        const res = api.post("/checkotp",{ otp: code, email: Profile?.email});
+        const res = await api.post("/checkotp",{ otp: code, email: Profile?.email});
         console.log("checkOTP Success", res?.status);
         
         const updatadata = await api.put(
           `/verifiedEmail/${Auth?.user}`,
-          {},{headers: { Authorization: `Bearer ${Auth.token}` },}
+          { verify_ticket: res.data?.verify_ticket },{headers: { Authorization: `Bearer ${Auth.token}` },}
         );
         console.log("Verified success:", updatadata?.data);
         if(Auth.role==="vendor"){
@@ -118,8 +119,9 @@ const VerifyOTP = ({ navigation}) => {
           navigation.navigate("HomeUser")
         }
       }else{
-        const res = await api.post(`/checkotp`, { otp: code, email:email });
+        const res = await api.post(`/checkotp`, { otp: code, email:email, purpose:"reset_password" });
         console.log("checkOTP Success", res?.status);
+        setResetTicket(res.data?.reset_ticket);
         setState(2);
       }
       }catch(err){
@@ -141,7 +143,7 @@ const VerifyOTP = ({ navigation}) => {
           }
 
           setErrmsg("")            
-          const res = await api.put("/changepassword", {email:email,password:newPassword});
+          const res = await api.put("/changepassword", {email:email,password:newPassword,reset_ticket:resetTicket});
           console.log("changePassword Success",res)
           navigation.replace("Splash")
       }catch(err){
@@ -6,17 +6,30 @@ import (
 
 	"cloud.google.com/go/firestore"
 	firebase "firebase.google.com/go"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
 	"google.golang.org/api/option"
 )
 
 var Client *firestore.Client
-var User *firestore.CollectionRef
-var Shops *firestore.CollectionRef
-var Vendor *firestore.CollectionRef
-var OTP *firestore.CollectionRef
+
+// DB คือ persistence layer ที่ handler ทุกตัวใช้ (Firestore หรือ memory)
+var DB store.Store
 var Ctx = context.Background()
 
-func InitFirebase(){
+// InitStore เลือก backend ตาม STORE_DRIVER: "memory" หรือ "firestore" (ค่าเริ่มต้น)
+func InitStore(driver string) {
+	switch driver {
+	case "memory":
+		log.Println("store: using in-memory backend (data is lost on restart)")
+		DB = store.NewMemory()
+	case "", "firestore":
+		InitFirebase()
+	default:
+		log.Fatalf("unknown STORE_DRIVER %q (want memory or firestore)", driver)
+	}
+}
+
+func InitFirebase() {
 	opt := option.WithCredentialsFile("./config/meeble-project-firebaseKey.json")
 
 	app, err := firebase.NewApp(Ctx, nil, opt)
@@ -29,10 +42,5 @@ func InitFirebase(){
 		log.Fatalf("error initializing firestore: %v", err)
 	}
 
-	
-	User = Client.Collection("users")
-	Shops = Client.Collection("shops")
-	Vendor = Client.Collection("vendors")
-	OTP = Client.Collection("otp")
+	DB = store.NewFirestore(Client)
 }
-
@@ -2,21 +2,22 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/middlewares"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	services "github.com/PPEACH21/MoblieApp_MeebleProject/service"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
-	"google.golang.org/api/iterator"
 )
 
+// PUT /verifiedEmail/:id   { "verify_ticket": "..." } (ได้จาก POST /checkotp)
 func VerifiedUser(c *fiber.Ctx) error {
 	userId := c.Params("id")
 	if userId == "" {
@@ -24,167 +25,79 @@ func VerifiedUser(c *fiber.Ctx) error {
 	}
 
 	accountType := strings.ToLower(c.Query("type", "")) // "user", "vendor", หรือ ""
-	var (
-		docRef  *firestore.DocumentSnapshot
-		role    string
-		err     error
-		dataRef *firestore.DocumentRef
-	)
-
-	// ✅ helper ฟังก์ชันอัปเดต verified
-	updateVerified := func(ref *firestore.DocumentRef) (*firestore.DocumentSnapshot, error) {
-		snap, err := ref.Get(config.Ctx)
-		if err != nil || !snap.Exists() {
-			return nil, fiber.ErrNotFound
-		}
-		if _, err := ref.Update(config.Ctx, []firestore.Update{
-			{Path: "verified", Value: true},
-		}); err != nil {
-			return nil, err
-		}
-		return ref.Get(config.Ctx)
-	}
 
 	// ✅ ตรวจประเภทบัญชี
+	var (
+		member *models.User
+		err    error
+	)
 	switch accountType {
-	case "vendor":
-		dataRef = config.Vendor.Doc(userId)
-		docRef, err = updateVerified(dataRef)
-		role = "vendor"
-
-	case "user":
-		dataRef = config.User.Doc(userId)
-		docRef, err = updateVerified(dataRef)
-		role = "user"
-
+	case store.RoleVendor, store.RoleUser:
+		member, err = config.DB.Accounts().GetAs(config.Ctx, accountType, userId)
 	default:
-		// auto detect
-		if snap, e := config.User.Doc(userId).Get(config.Ctx); e == nil && snap.Exists() {
-			dataRef = config.User.Doc(userId)
-			docRef, err = updateVerified(dataRef)
-			role = "user"
-		} else if snap, e := config.Vendor.Doc(userId).Get(config.Ctx); e == nil && snap.Exists() {
-			dataRef = config.Vendor.Doc(userId)
-			docRef, err = updateVerified(dataRef)
-			role = "vendor"
-		} else {
-			return c.Status(404).SendString("Account not found in users or vendors")
-		}
+		// auto detect (users ก่อน แล้วค่อย vendors)
+		member, err = config.DB.Accounts().Get(config.Ctx, userId)
 	}
-
 	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(404).SendString("Account not found in users or vendors")
+		}
 		return c.Status(400).SendString(fmt.Sprintf("Update error: %v", err))
 	}
+	role := member.Role
 
-	// ✅ แปลงข้อมูล
-	var member models.User
-	if err := docRef.DataTo(&member); err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Error parsing user data")
+	// ✅ ต้องผ่าน OTP ของ email บัญชีนี้มาแล้ว: ตรวจ + ใช้ ticket + อัปเดต verified ใน transaction เดียว
+	var body models.VerifyEmailReq
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+		}
 	}
-
-	// ✅ สร้าง JWT ใหม่
-	claims := jwt.MapClaims{
-		"user_id":  docRef.Ref.ID,
-		"email":    member.Email,
-		"username": member.Username,
-		"verified": member.Verified,
-		"role":     role,
-		"exp":      time.Now().Add(60 * time.Minute).Unix(),
+	if body.VerifyTicket == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "verify_ticket is required"})
 	}
+	err = config.DB.RunTransaction(config.Ctx, func(ctx context.Context, tx store.Repos) error {
+		if err := services.CheckOTPTicket(ctx, tx, models.OTPVerifyEmail, member.Email, body.VerifyTicket); err != nil {
+			return err
+		}
+		if err := tx.Accounts().Update(ctx, role, member.ID, map[string]any{"verified": true}); err != nil {
+			return err
+		}
+		return services.ConsumeOTPTicket(ctx, tx, models.OTPVerifyEmail, member.Email)
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTicket) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(400).SendString(fmt.Sprintf("Update error: %v", err))
+	}
+	member.Verified = true
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	secret := os.Getenv("JWT_SECRET")
-	t, err := token.SignedString([]byte(secret))
+	// ✅ ออก token ใหม่ให้ session เดิม (claim verified = true)
+	pair, err := services.ReissueSession(config.Ctx, middlewares.SessionID(c), member)
 	if err != nil {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
-	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+	return c.Status(fiber.StatusAccepted).JSON(pair.Into(fiber.Map{
 		"success":  true,
 		"message":  "Verified successfully",
 		"role":     role,
-		"token":    t,
-		"user_id":  docRef.Ref.ID,
+		"user_id":  member.ID,
 		"email":    member.Email,
 		"username": member.Username,
-	})
-}
-func topCartDoc(customerID string) *firestore.DocumentRef {
-	if customerID == "" {
-		customerID = "anon"
-	}
-	return config.Client.Collection("cart").Doc(customerID)
-}
-
-func ordersCol(vendorID, shopID string) *firestore.CollectionRef {
-	return config.Client.
-		Collection("vendors").Doc(vendorID).
-		Collection("shops").Doc(shopID).
-		Collection("orders")
-}
-
-func loadMenuByID(vendorID, menuID string) (*models.Menu, error) {
-	snap, err := config.Client.
-		Collection("vendors").Doc(vendorID).
-		Collection("menu").Doc(menuID).
-		Get(config.Ctx)
-	if err != nil || !snap.Exists() {
-		return nil, fmt.Errorf("menu not found")
-	}
-	var m models.Menu
-	if err := snap.DataTo(&m); err != nil {
-		return nil, err
-	}
-	m.ID = menuID
-	return &m, nil
-}
-
-func toFloat(v interface{}) float64 {
-	switch t := v.(type) {
-	case int:
-		return float64(t)
-	case int64:
-		return float64(t)
-	case float64:
-		return t
-	case float32:
-		return float64(t)
-	case string:
-		var f float64
-		_, _ = fmt.Sscan(t, &f)
-		return f
-	default:
-		return 0
-	}
-}
-func toInt(v interface{}) int {
-	switch t := v.(type) {
-	case int:
-		return t
-	case int64:
-		return int(t)
-	case float64:
-		return int(t)
-	case float32:
-		return int(t)
-	case string:
-		var i int
-		_, _ = fmt.Sscan(t, &i)
-		return i
-	default:
-		return 0
-	}
+	}))
 }
 
 // GET /api/cart?customerId=
 func GetCart(c *fiber.Ctx) error {
-	customerID := c.Query("customerId")
+	customerID := middlewares.UserID(c) // ตะกร้าของคนที่ login เท่านั้น
 	if customerID == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "customerId is required"})
 	}
 
-	snap, err := topCartDoc(customerID).Get(config.Ctx)
-	if err != nil || !snap.Exists() {
+	cart, err := config.DB.Carts().Get(config.Ctx, customerID)
+	if err != nil {
 		// ยังไม่มี cart -> คืนว่าง (key เล็กให้ตรง FE)
 		return c.JSON(fiber.Map{
 			"customerId": customerID,
@@ -195,8 +108,8 @@ func GetCart(c *fiber.Ctx) error {
 		})
 	}
 
-	// คืน map จาก Firestore ตรง ๆ เพื่อรักษา key เป็นตัวเล็ก
-	return c.JSON(snap.Data())
+	// json tag ของ models.Cart เป็น key ตัวเล็กตรงกับ FE อยู่แล้ว
+	return c.JSON(cart)
 }
 
 // POST /api/cart/add
@@ -208,6 +121,7 @@ func AddToCart(c *fiber.Ctx) error {
 			"error": "BodyParser error", "msg": err.Error(),
 		})
 	}
+	req.CustomerID = middlewares.UserID(c)
 
 	// รองรับกรณี FE ส่ง shopName แทน shop_name
 	// (อ่านทับอีกครั้งเฉพาะฟิลด์ shopName)
@@ -257,13 +171,12 @@ func AddToCart(c *fiber.Ctx) error {
 		})
 	}
 
-	ref := topCartDoc(req.CustomerID)
-
-	err := config.Client.RunTransaction(config.Ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+	var priceChanges []services.PriceChange
+	err := config.DB.RunTransaction(config.Ctx, func(ctx context.Context, tx store.Repos) error {
 		// โหลดตะกร้าเดิม
 		var cart models.Cart
-		snap, err := tx.Get(ref)
-		if err != nil || !snap.Exists() {
+		existing, err := tx.Carts().Get(ctx, req.CustomerID)
+		if errors.Is(err, store.ErrNotFound) {
 			cart = models.Cart{
 				CustomerID: req.CustomerID,
 				Shop_name:  req.Shop_name,
@@ -272,8 +185,10 @@ func AddToCart(c *fiber.Ctx) error {
 				Total:      0,
 				UpdatedAt:  time.Now(),
 			}
-		} else if err := snap.DataTo(&cart); err != nil {
+		} else if err != nil {
 			return err
+		} else {
+			cart = *existing
 		}
 
 		// 🔒 ล็อกตะกร้าให้สั่งได้จากร้านเดียว
@@ -290,27 +205,61 @@ func AddToCart(c *fiber.Ctx) error {
 				)
 			}
 		}
+		// ราคา/ชื่อ/รูปเอาจากเมนูจริง (shops/{id}/menu/{menuId}) ไม่ใช้ req.Item.Price
+		shop, err := tx.Shops().Get(ctx, req.ShopID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		priced, err := services.PriceOrderItems(ctx, tx, req.ShopID, []models.OrderItem{{
+			ID:      req.Item.MenuID,
+			Name:    req.Item.Name,
+			Price:   req.Item.Price,
+			Qty:     req.Qty,
+			Options: req.Item.Options,
+		}}, nil, true)
+		if err != nil {
+			return err
+		}
+		menu := priced.Items[0]
+		priceChanges = priced.Changes
+		// เมนูที่สต็อกหมด/ไม่พอ (รวมที่อยู่ในตะกร้าแล้ว) ไม่ให้เพิ่ม
+		inCart := 0
+		for _, it := range cart.Items {
+			if it.ID == menu.ID {
+				inCart += it.Qty
+			}
+		}
+		want := *priced
+		want.Items = []models.OrderItem{{ID: menu.ID, Qty: menu.Qty + inCart}}
+		loc, at := services.StockLocation(shop), time.Now()
+		if err := services.CheckStock(&want, req.ShopID, models.StockDay(at, loc)); err != nil {
+			return err
+		}
+		// นอกช่วงขาย (เช่นเมนูอาหารเช้า) ก็ไม่ให้เพิ่ม
+		if err := services.CheckAvailability(priced, req.ShopID, loc, at); err != nil {
+			return err
+		}
+
 		// อัปเดตข้อมูลระดับ cart ให้ตรงกับร้านที่กำลังสั่ง
 		cart.ShopID = req.ShopID
 		cart.Shop_name = req.Shop_name
 
-		// รวมรายการซ้ำ (ตาม shopId + menuId)
+		// รวมรายการซ้ำ (ตาม shopId + menuId + ตัวเลือก) เลือกตัวเลือกต่างกัน = คนละบรรทัด
+		lineID := models.CartLineID(menu.ID, menu.Options)
 		found := false
 		for i := range cart.Items {
-			if cart.Items[i].ShopID == req.ShopID && cart.Items[i].ID == req.Item.MenuID {
+			if cart.Items[i].ShopID == req.ShopID && cart.Items[i].Line() == lineID {
 				cart.Items[i].Qty += req.Qty
-				// อัปเดตราคา/ชื่อ/รูป/คำอธิบาย ถ้าส่งมา
-				if req.Item.Price > 0 {
-					cart.Items[i].Price = req.Item.Price
-				}
-				if req.Item.Name != "" && cart.Items[i].Name == "" {
-					cart.Items[i].Name = req.Item.Name
-				}
-				if req.Item.Image != "" && cart.Items[i].Image == "" {
-					cart.Items[i].Image = req.Item.Image
+				// ข้อมูลเมนูล่าสุดทับของเดิม
+				cart.Items[i].Price = menu.Price
+				cart.Items[i].Name = menu.Name
+				cart.Items[i].LineID = lineID
+				cart.Items[i].Options = menu.Options
+				if menu.Image != "" {
+					cart.Items[i].Image = menu.Image
 				}
-				if req.Item.Description != "" && cart.Items[i].Description == "" {
-					cart.Items[i].Description = req.Item.Description
+				if menu.Description != "" {
+					cart.Items[i].Description = menu.Description
 				}
 				found = true
 				break
@@ -318,13 +267,15 @@ func AddToCart(c *fiber.Ctx) error {
 		}
 		if !found {
 			cart.Items = append(cart.Items, models.CartItem{
-				ID:          req.Item.MenuID,
-				Name:        req.Item.Name,
+				ID:          menu.ID,
+				Name:        menu.Name,
 				Qty:         req.Qty,
-				Price:       req.Item.Price,
-				Image:       req.Item.Image,
-				Description: req.Item.Description,
+				Price:       menu.Price,
+				Image:       menu.Image,
+				Description: menu.Description,
 				ShopID:      req.ShopID,
+				LineID:      lineID,
+				Options:     menu.Options,
 				// VendorID/MenuRef ไม่ใช้แล้ว -> ปล่อยว่าง
 			})
 		}
@@ -337,17 +288,7 @@ func AddToCart(c *fiber.Ctx) error {
 		cart.Total = total
 		cart.UpdatedAt = time.Now()
 
-		// เขียนกลับเฉพาะฟิลด์ที่ FE ใช้จริง
-		writeData := map[string]interface{}{
-			"customerId": cart.CustomerID,
-			"shopId":     cart.ShopID,
-			"shop_name":  cart.Shop_name,
-			"items":      cart.Items,
-			"total":      cart.Total,
-			"updatedAt":  cart.UpdatedAt,
-		}
-
-		return tx.Set(ref, writeData)
+		return tx.Carts().Put(ctx, &cart)
 	})
 
 	if err != nil {
@@ -358,10 +299,13 @@ func AddToCart(c *fiber.Ctx) error {
 				"msg":   fe.Message,
 			})
 		}
+		if ok, resp := respondPricingError(c, err); ok {
+			return resp
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to add to cart", "msg": err.Error()})
 	}
 
-	return c.JSON(fiber.Map{"message": "added to cart"})
+	return c.JSON(fiber.Map{"message": "added to cart", "price_changes": priceChanges})
 }
 
 // POST /api/cart/checkout
@@ -370,136 +314,161 @@ func CheckoutCartFromDB(c *fiber.Ctx) error {
 	type Req struct {
 		UserID     string `json:"userId"`
 		CustomerID string `json:"customerId"`
+		// true = ยอมรับราคาใหม่ที่ได้จาก 409 PRICE_CHANGED
+		AcceptPriceChanges bool `json:"acceptPriceChanges"`
 	}
 	var req Req
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "BodyParser error", "msg": err.Error()})
 	}
+	// จ่ายด้วยบัญชีของคนที่ login เท่านั้น (ไม่เชื่อ userId/customerId ใน body)
+	req.UserID = middlewares.UserID(c)
+	req.CustomerID = req.UserID
 	if strings.TrimSpace(req.UserID) == "" || strings.TrimSpace(req.CustomerID) == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "userId/customerId is required"})
 	}
 
-	cartRef := config.Client.Collection("cart").Doc(req.CustomerID)
-	userRef := config.Client.Collection("users").Doc(req.UserID)
-
-	var createdHistoryID string
+	var created models.Order
+	var priceChanges []services.PriceChange
+	var stock *services.StockResult
 
-	err := config.Client.RunTransaction(config.Ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+	err := config.DB.RunTransaction(config.Ctx, func(ctx context.Context, tx store.Repos) error {
 		// load cart
-		cs, err := tx.Get(cartRef)
-		if err != nil || !cs.Exists() {
-			return fiber.NewError(fiber.StatusNotFound, "cart not found")
-		}
-		var cart struct {
-			CustomerID string                   `firestore:"customerId"`
-			UserIDPath interface{}              `firestore:"user_id"`
-			ShopID     string                   `firestore:"shopId"`
-			ShopName   string                   `firestore:"shop_name"`
-			Items      []map[string]interface{} `firestore:"items"`
-			Total      interface{}              `firestore:"total"`
-		}
-		if err := cs.DataTo(&cart); err != nil {
+		cart, err := tx.Carts().Get(ctx, req.CustomerID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "cart not found")
+			}
 			return fiber.NewError(fiber.StatusInternalServerError, "invalid cart data")
 		}
 		if len(cart.Items) == 0 {
 			return fiber.NewError(fiber.StatusBadRequest, "cart empty")
 		}
+		shop, err := tx.Shops().Get(ctx, cart.ShopID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "shop not found")
+			}
+			return err
+		}
+		if err := services.CheckShopOpen(shop, services.ShopServiceOrder, time.Now()); err != nil {
+			return err
+		}
 
-		// recompute total
-		var recomputed float64
+		// resolve ทุกรายการกับเมนูจริง แล้วคิดยอดจากราคาในเมนู
+		lines := make([]models.OrderItem, 0, len(cart.Items))
+		lineShops := make([]string, 0, len(cart.Items))
 		for _, it := range cart.Items {
-			price := toFloat(it["price"])
-			if price == 0 {
-				price = toFloat(it["Price"])
-			}
-			qty := toInt(it["qty"])
-			if qty == 0 {
-				qty = toInt(it["Qty"])
-			}
-			if price > 0 && qty > 0 {
-				recomputed += price * float64(qty)
-			}
+			lines = append(lines, models.OrderItem{
+				ID:          it.ID,
+				Name:        it.Name,
+				Image:       it.Image,
+				Description: it.Description,
+				Price:       it.Price,
+				Qty:         it.Qty,
+				Options:     it.Options,
+			})
+			lineShops = append(lineShops, it.ShopID)
 		}
-		if recomputed <= 0 {
-			recomputed = toFloat(cart.Total) // fallback
+		priced, err := services.PriceOrderItems(ctx, tx, cart.ShopID, lines, lineShops, req.AcceptPriceChanges)
+		if err != nil {
+			return err
 		}
+		items := priced.Items
+		recomputed := priced.Total
+		priceChanges = priced.Changes
 		if recomputed <= 0 {
 			return fiber.NewError(fiber.StatusBadRequest, "cannot compute total")
 		}
 
 		// check user balance
-		us, err := tx.Get(userRef)
-		if err != nil || !us.Exists() {
-			return fiber.NewError(fiber.StatusNotFound, "user not found")
-		}
-		var currentCost float64
-		if v, ok := us.Data()["Cost"]; ok && v != nil {
-			switch t := v.(type) {
-			case int64:
-				currentCost = float64(t)
-			case int:
-				currentCost = float64(t)
-			case float64:
-				currentCost = t
-			case string:
-				currentCost = toFloat(t)
-			default:
-				return fiber.NewError(fiber.StatusInternalServerError, "invalid Cost type on user")
+		user, err := tx.Accounts().GetAs(ctx, store.RoleUser, req.UserID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "user not found")
 			}
+			return fiber.NewError(fiber.StatusInternalServerError, "invalid Cost type on user")
 		}
+		currentCost := user.Cost
 		if currentCost < recomputed {
 			return fiber.NewError(402, fmt.Sprintf("insufficient funds: have %.2f, need %.2f", currentCost, recomputed))
 		}
 
+		// checkout ตัดเงินแล้ว → ระบบสร้างออเดอร์เข้าครัว (prepare) ผ่าน state machine
+		if err := models.CheckOrderCreate(models.OrderPrepare, models.ActorSystem); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		}
+
 		// create history (orders collection level-top)
-		historyRef := config.Client.Collection("orders").NewDoc()
-		createdHistoryID = historyRef.ID
-		if err := tx.Set(historyRef, map[string]interface{}{
-			"historyId":  createdHistoryID,
-			"userId":     req.UserID,
-			"userRef":    userRef,
-			"customerId": req.CustomerID,
-			"shopId":     cart.ShopID,
-			"shop_name":  cart.ShopName,
-			"items":      cart.Items,
-			"total":      recomputed,
-			"status":     "prepare",
-			"createdAt":  time.Now(),
-			"updatedAt":  time.Now(),
-		}); err != nil {
+		nowT := time.Now()
+		if err := services.CheckAvailability(priced, shop.ID, services.StockLocation(shop), nowT); err != nil {
+			return err
+		}
+		stock, err = services.TakeStock(ctx, tx, shop, priced, nowT)
+		if err != nil {
 			return err
 		}
+		order := models.Order{
+			ShopID:     cart.ShopID,
+			CustomerID: req.CustomerID,
+			UserID:     req.UserID,
+			Status:     models.OrderPrepare,
+			Items:      items,
+			Total:      recomputed,
+			PaidAmount: recomputed,
+			CreatedAt:  nowT,
+			UpdatedAt:  nowT,
+			ShopName:   cart.Shop_name,
+			StockHeld:  stock.Held,
+		}
+		if err := tx.Orders().Create(ctx, &order); err != nil {
+			return err
+		}
+		created = order
 
-		// charge user
-		if err := tx.Update(userRef, []firestore.Update{
-			{Path: "Cost", Value: currentCost - recomputed},
-			{Path: "updatedAt", Value: time.Now()},
+		// charge user ผ่าน wallet ledger (debit อ้างอิงออเดอร์)
+		if _, err := services.ApplyWalletEntry(ctx, tx, user, services.WalletEntry{
+			Type:    models.WalletDebit,
+			Amount:  recomputed,
+			RefType: models.WalletRefOrder,
+			RefID:   order.ID,
+			Note:    "checkout " + cart.Shop_name,
 		}); err != nil {
 			return err
 		}
 
 		// clear cart
-		return tx.Set(cartRef, map[string]interface{}{
-			"user_id":    cart.UserIDPath,
-			"customerId": req.CustomerID,
-			"shopId":     "",
-			"shop_name":  "",
-			"items":      []interface{}{},
-			"total":      0,
-			"updatedAt":  time.Now(),
-		}, firestore.MergeAll)
+		return tx.Carts().Put(ctx, &models.Cart{
+			CustomerID: req.CustomerID,
+			Items:      []models.CartItem{},
+			UpdatedAt:  nowT,
+		})
 	})
 
 	if err != nil {
 		if fe, ok := err.(*fiber.Error); ok {
 			return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
 		}
+		if ok, resp := respondPricingError(c, err); ok {
+			return resp
+		}
+		if ok, resp := respondShopClosed(c, err); ok {
+			return resp
+		}
+		var fe *services.InsufficientFundsError
+		if errors.As(err, &fe) {
+			return c.Status(402).JSON(fiber.Map{"error": fe.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "checkout failed", "msg": err.Error()})
 	}
 
+	services.OrderEvents.PublishOrder(services.OrderEventCreated, &created, "")
+	services.AfterStockChange(config.Ctx, stock)
+
 	return c.JSON(fiber.Map{
-		"message":   "history created & user charged & cart cleared",
-		"historyId": createdHistoryID,
+		"message":       "history created & user charged & cart cleared",
+		"historyId":     created.ID,
+		"price_changes": priceChanges,
 	})
 }
 
@@ -510,26 +479,24 @@ func UpdateCartQty(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "BodyParser error", "msg": err.Error()})
 	}
-	if req.CustomerID == "" || req.MenuID == "" {
+	req.CustomerID = middlewares.UserID(c)
+	if req.CustomerID == "" || (req.MenuID == "" && req.LineID == "") {
 		return c.Status(400).JSON(fiber.Map{"error": "customerId/menuId is required"})
 	}
 
-	ref := topCartDoc(req.CustomerID)
-
-	err := config.Client.RunTransaction(config.Ctx, func(ctx context.Context, tx *firestore.Transaction) error {
-		snap, err := tx.Get(ref)
-		if err != nil || !snap.Exists() {
+	err := config.DB.RunTransaction(config.Ctx, func(ctx context.Context, tx store.Repos) error {
+		cart, err := tx.Carts().Get(ctx, req.CustomerID)
+		if errors.Is(err, store.ErrNotFound) {
 			return fiber.ErrNotFound
 		}
-		var cart models.Cart
-		if err := snap.DataTo(&cart); err != nil {
+		if err != nil {
 			return err
 		}
 
-		// หา item ตาม menuId (== CartItem.ID)
+		// หา item ตาม lineId (ถ้าส่งมา) ไม่งั้นบรรทัดแรกของ menuId (== CartItem.ID)
 		idx := -1
 		for i, it := range cart.Items {
-			if it.ID == req.MenuID {
+			if (req.LineID != "" && it.Line() == req.LineID) || (req.LineID == "" && it.ID == req.MenuID) {
 				idx = i
 				break
 			}
@@ -560,19 +527,15 @@ func UpdateCartQty(c *fiber.Ctx) error {
 			total += float64(it.Qty) * it.Price
 		}
 
-		updates := []firestore.Update{
-			{Path: "customerId", Value: cart.CustomerID},
-			{Path: "items", Value: cart.Items},
-			{Path: "total", Value: total},
-			{Path: "updatedAt", Value: time.Now()},
-		}
+		cart.Total = total
+		cart.UpdatedAt = time.Now()
 
 		// ถ้าตะกร้าว่าง → ล้างชื่อร้าน
 		if len(cart.Items) == 0 || total <= 0 {
-			updates = append(updates, firestore.Update{Path: "shop_name", Value: ""})
+			cart.Shop_name = ""
 		}
 
-		return tx.Update(ref, updates)
+		return tx.Carts().Put(ctx, cart)
 	})
 
 	if err != nil {
@@ -606,53 +569,28 @@ func ListUserHistory(c *fiber.Ctx) error {
 	limit := toLimit(c.Query("limit"), 20)      // default 20
 	startAfterId := c.Query("startAfterId", "") // ใช้ doc id ทำหน้า next page
 
-	col := config.Client.Collection("users").Doc(userId).Collection("history")
+	q := store.HistoryQuery{Status: status, Limit: limit}
 
-	// เลือก field สำหรับ sort: ถ้ามี movedToHistoryAt ให้ใช้เป็นหลัก
-	// (ถ้าบางเอกสารไม่มี ฟังก์ชันนี้ยังดึงได้ เพราะเรา fallback ด้วย get field จาก snapshot เดิม)
-	q := col.OrderBy("movedToHistoryAt", firestore.Desc).Limit(limit)
-	if status != "" {
-		q = q.Where("status", "==", status)
-	}
-
-	// pagination
+	// pagination: ใช้ค่า movedToHistoryAt ของ doc ที่อ้างอิงเป็น anchor
 	if startAfterId != "" {
-		snap, err := col.Doc(startAfterId).Get(config.Ctx)
-		if err == nil {
-			// ใช้ค่า movedToHistoryAt ของ doc ที่อ้างอิงเป็น anchor
-			mv := snap.Data()["movedToHistoryAt"]
-			q = q.StartAfter(mv)
+		if anchor, err := config.DB.History().GetForUser(config.Ctx, userId, startAfterId); err == nil {
+			q.StartAfter = anchor.MovedToHistoryAt
 		}
 	}
 
-	iter := q.Documents(config.Ctx)
-	defer iter.Stop()
-
-	out := make([]models.HistoryItem, 0, limit)
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
-		}
-
-		var it models.HistoryItem
-		if err := doc.DataTo(&it); err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "parse error: " + err.Error()})
-		}
-		// fallback: ถ้าไม่มี movedToHistoryAt (เวอร์ชันเก่า)
-		if it.MovedToHistoryAt.IsZero() {
-			// ใช้ updatedAt ถ้าไม่มีอีก ใช้ createdAt
-			if !it.UpdatedAt.IsZero() {
-				it.MovedToHistoryAt = it.UpdatedAt
-			} else if !it.CreatedAt.IsZero() {
-				it.MovedToHistoryAt = it.CreatedAt
+	out, err := config.DB.History().ListByUser(config.Ctx, userId, q)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	for i := range out {
+		// fallback: ถ้าไม่มี movedToHistoryAt (เวอร์ชันเก่า) ใช้ updatedAt ถ้าไม่มีอีก ใช้ createdAt
+		if out[i].MovedToHistoryAt.IsZero() {
+			if !out[i].UpdatedAt.IsZero() {
+				out[i].MovedToHistoryAt = out[i].UpdatedAt
+			} else {
+				out[i].MovedToHistoryAt = out[i].CreatedAt
 			}
 		}
-		it.ID = doc.Ref.ID
-		out = append(out, it)
 	}
 
 	return c.JSON(fiber.Map{
@@ -668,16 +606,13 @@ func UpdateProfile(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).SendString("cannot parse JSON")
 	}
 
-	docRef := config.User.Doc(userID)
-	docSnap, err := docRef.Get(config.Ctx)
+	dbuser, err := config.DB.Accounts().Get(config.Ctx, userID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "user not found",
-		})
-	}
-
-	var dbuser models.User
-	if err := docSnap.DataTo(&dbuser); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "user not found",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to parse existing data",
 		})
@@ -686,12 +621,15 @@ func UpdateProfile(c *fiber.Ctx) error {
 	updateData := make(map[string]interface{})
 	if newdata.Firstname != "" && newdata.Firstname != dbuser.Firstname {
 		updateData["firstname"] = newdata.Firstname
+		dbuser.Firstname = newdata.Firstname
 	}
 	if newdata.Lastname != "" && newdata.Lastname != dbuser.Lastname {
 		updateData["lastname"] = newdata.Lastname
+		dbuser.Lastname = newdata.Lastname
 	}
 	if newdata.Avatar != "" && newdata.Avatar != dbuser.Avatar {
 		updateData["avatar"] = newdata.Avatar
+		dbuser.Avatar = newdata.Avatar
 	}
 
 	if len(updateData) == 0 {
@@ -701,22 +639,12 @@ func UpdateProfile(c *fiber.Ctx) error {
 		})
 	}
 
-	_, err = docRef.Set(config.Ctx, updateData, firestore.MergeAll)
-	if err != nil {
+	if err := config.DB.Accounts().Update(config.Ctx, dbuser.Role, dbuser.ID, updateData); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to update user",
 		})
 	}
 
-	for key, val := range updateData {
-		switch key {
-		case "Firstname":
-			dbuser.Firstname = val.(string)
-		case "Lastname":
-			dbuser.Lastname = val.(string)
-		}
-	}
-
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "profile updated successfully",
 		"user":    dbuser,
@@ -730,11 +658,7 @@ func GetUserReservations(c *fiber.Ctx) error {
 		})
 	}
 
-	ctx := config.Ctx
-	client := config.Client
-
-	q := client.Collection("users").Doc(userId).Collection("reservations")
-	docs, err := q.Documents(ctx).GetAll()
+	out, err := config.DB.Reservations().ListByUser(config.Ctx, userId)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to query reservations",
@@ -742,20 +666,6 @@ func GetUserReservations(c *fiber.Ctx) error {
 		})
 	}
 
-	out := make([]models.Reservation, 0, len(docs))
-	for _, doc := range docs {
-		var r models.Reservation
-		if err := doc.DataTo(&r); err != nil {
-			continue
-		}
-
-		if r.ID == "" {
-			r.ID = doc.Ref.ID
-		}
-
-		out = append(out, r)
-	}
-
 	return c.JSON(fiber.Map{
 		"ok":    true,
 		"items": out,
@@ -769,23 +679,15 @@ func GetShopNameById(c *fiber.Ctx) error {
 		})
 	}
 
-	doc, err := config.Client.Collection("shops").Doc(shopId).Get(config.Ctx)
-	if err != nil || !doc.Exists() {
+	shop, err := config.DB.Shops().Get(config.Ctx, shopId)
+	if err != nil {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{
 			"error": "shop not found",
 		})
 	}
 
-	// อ่าน field 'name'
-	name, err := doc.DataAt("name")
-	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "name field missing in shop doc",
-		})
-	}
-
 	return c.JSON(fiber.Map{
 		"shop_id":   shopId,
-		"shop_name": name,
+		"shop_name": shop.ShopName,
 	})
 }
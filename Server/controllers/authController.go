@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/middlewares"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	services "github.com/PPEACH21/MoblieApp_MeebleProject/service"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// POST /auth/refresh  { "refresh_token": "..." }  (ไม่ต้องมี access token)
+func RefreshToken(c *fiber.Ctx) error {
+	var body models.RefreshReq
+	if err := c.BodyParser(&body); err != nil || strings.TrimSpace(body.RefreshToken) == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "refresh_token required"})
+	}
+
+	pair, err := services.RefreshSession(config.Ctx, strings.TrimSpace(body.RefreshToken))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefresh) || errors.Is(err, services.ErrSessionRevoked) ||
+			errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(pair.Into(fiber.Map{"message": "token refreshed"}))
+}
+
+// POST /auth/logout  ปิด session ของ token ปัจจุบัน
+func Logout(c *fiber.Ctx) error {
+	if err := services.RevokeSession(config.Ctx, middlewares.SessionID(c)); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "logged out"})
+}
+
+// GET /auth/sessions  อุปกรณ์ที่ยัง login อยู่ของบัญชีนี้
+func ListSessions(c *fiber.Ctx) error {
+	list, err := services.ActiveSessions(config.Ctx, middlewares.UserID(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	current := middlewares.SessionID(c)
+	type sessionResp struct {
+		models.Session
+		Current bool `json:"current"`
+	}
+	out := make([]sessionResp, 0, len(list))
+	for _, s := range list {
+		out = append(out, sessionResp{Session: s, Current: s.ID == current})
+	}
+	return c.JSON(fiber.Map{"sessions": out})
+}
+
+// DELETE /auth/sessions/:id  ลบอุปกรณ์ (revoke session ของตัวเองเท่านั้น)
+func RevokeSession(c *fiber.Ctx) error {
+	sid := c.Params("id")
+	sess, err := config.DB.Sessions().Get(config.Ctx, sid)
+	if err != nil || sess.UserID != middlewares.UserID(c) {
+		// ไม่บอกว่ามี session ของคนอื่นอยู่หรือไม่
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "session not found"})
+	}
+	if err := services.RevokeSession(config.Ctx, sid); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "session revoked", "id": sid})
+}
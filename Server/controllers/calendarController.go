@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	services "github.com/PPEACH21/MoblieApp_MeebleProject/service"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// issueCalendarFeed ออก token ใหม่แล้วตอบ URL สำหรับ subscribe (token เดิมใช้ไม่ได้ทันที)
+func issueCalendarFeed(c *fiber.Ctx, kind, ownerID, path string) error {
+	token, err := services.IssueCalendarFeed(config.Ctx, kind, ownerID, now())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"url":   c.BaseURL() + path + "?token=" + url.QueryEscape(token),
+		"token": token, // แสดงครั้งเดียว เก็บไว้แค่ hash
+	})
+}
+
+// revokeCalendarFeed ปิด feed (URL เดิมตอบ 401)
+func revokeCalendarFeed(c *fiber.Ctx, kind, ownerID string) error {
+	if err := config.DB.CalendarFeeds().Delete(config.Ctx, kind, ownerID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "calendar feed revoked"})
+}
+
+// sendCalendar ตอบไฟล์ .ics
+func sendCalendar(c *fiber.Ctx, body []byte) error {
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="reservations.ics"`)
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	return c.Send(body)
+}
+
+// POST /shop/:id/calendar-feed — ออก (หรือออกใหม่) URL ของ feed การจองของร้าน
+func IssueShopCalendarFeed(c *fiber.Ctx) error {
+	id := c.Params("id")
+	return issueCalendarFeed(c, models.CalendarFeedShop, id, "/shop/"+id+"/reservations.ics")
+}
+
+// DELETE /shop/:id/calendar-feed
+func RevokeShopCalendarFeed(c *fiber.Ctx) error {
+	return revokeCalendarFeed(c, models.CalendarFeedShop, c.Params("id"))
+}
+
+// POST /users/:userId/calendar-feed — ออก (หรือออกใหม่) URL ของ feed การจองของ user
+func IssueUserCalendarFeed(c *fiber.Ctx) error {
+	id := c.Params("userId")
+	return issueCalendarFeed(c, models.CalendarFeedUser, id, "/users/"+id+"/reservations.ics")
+}
+
+// DELETE /users/:userId/calendar-feed
+func RevokeUserCalendarFeed(c *fiber.Ctx) error {
+	return revokeCalendarFeed(c, models.CalendarFeedUser, c.Params("userId"))
+}
+
+// GET /shop/:id/reservations.ics?token=... (ไม่ผ่าน JWT: แอปปฏิทินใช้ token ของ feed แทน)
+func ShopReservationsICS(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := services.CheckCalendarFeed(config.Ctx, models.CalendarFeedShop, id, c.Query("token")); err != nil {
+		return respondCalendarFeedError(c, err)
+	}
+	s, err := config.DB.Shops().Get(config.Ctx, id)
+	if err != nil || s.IsDeleted() {
+		if err == nil || errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	resvs, err := config.DB.Reservations().ListByShop(config.Ctx, id)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	body, err := services.ReservationCalendar(config.Ctx, models.CalendarFeedShop, s.ShopName+" reservations", resvs, now())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return sendCalendar(c, body)
+}
+
+// GET /users/:userId/reservations.ics?token=... (อ่านจาก users/{userId}/reservations)
+func UserReservationsICS(c *fiber.Ctx) error {
+	id := c.Params("userId")
+	if err := services.CheckCalendarFeed(config.Ctx, models.CalendarFeedUser, id, c.Query("token")); err != nil {
+		return respondCalendarFeedError(c, err)
+	}
+	resvs, err := config.DB.Reservations().ListByUser(config.Ctx, id)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	body, err := services.ReservationCalendar(config.Ctx, models.CalendarFeedUser, "My reservations", resvs, now())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return sendCalendar(c, body)
+}
+
+func respondCalendarFeedError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrCalendarFeedToken) {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
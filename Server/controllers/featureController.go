@@ -2,58 +2,93 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gofiber/fiber/v2"
-	"google.golang.org/api/iterator"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 
 	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/middlewares"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	services "github.com/PPEACH21/MoblieApp_MeebleProject/service"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
 )
 
-const (
-	OrderPrepare   = "prepare"
-	OrderReady     = "ongoing"
-	OrderCompleted = "done"
-)
+// -------- helpers --------
+func now() time.Time { return time.Now() }
 
-var AllowedOrderStatus = map[string]bool{
-	OrderPrepare:   true,
-	OrderReady:     true,
-	OrderCompleted: true,
+// orderActor อ่าน role จาก JWT แล้วแปลงเป็นฝั่งที่เปลี่ยนสถานะออเดอร์
+func orderActor(c *fiber.Ctx) models.OrderActor {
+	return models.ActorFromRole(middlewares.Role(c))
 }
 
-const ColOrders = "orders"
+// orderTransitionStatus เลือก HTTP status ให้ error จาก state machine
+func orderTransitionStatus(err error) (int, bool) {
+	var te *models.OrderTransitionError
+	if !errors.As(err, &te) {
+		return 0, false
+	}
+	if te.RoleDenied {
+		return http.StatusForbidden, true
+	}
+	if !models.AllowedOrderStatus[te.To] {
+		return http.StatusBadRequest, true
+	}
+	return http.StatusConflict, true
+}
 
-// -------- helpers --------
-func computeTotal(items []models.OrderItem) float64 {
-	var sum float64
-	for _, it := range items {
-		q := it.Qty
-		if q <= 0 {
-			q = 1
-		}
-		sum += it.Price * float64(q)
+// respondPricingError ตอบ error จากการตรวจเมนู/ราคา (services.PriceOrderItems) ถ้าเป็นชนิดที่รู้จัก
+func respondPricingError(c *fiber.Ctx, err error) (bool, error) {
+	var ue *services.ItemsUnavailableError
+	if errors.As(err, &ue) {
+		return true, c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "some items are unavailable",
+			"code":  "ITEMS_UNAVAILABLE",
+			"items": ue.Problems,
+		})
+	}
+	var pe *services.PriceChangedError
+	if errors.As(err, &pe) {
+		return true, c.Status(http.StatusConflict).JSON(fiber.Map{
+			"error":         "prices changed, please review and accept",
+			"code":          "PRICE_CHANGED",
+			"price_changes": pe.Changes,
+			"total":         pe.Total,
+		})
 	}
-	return sum
+	return false, nil
 }
 
-func now() time.Time { return time.Now() }
+// historyToOrder แปลง history กลับเป็น shape ของ order (response เดิมของ client)
+func historyToOrder(h models.HistoryItem) models.Order {
+	return models.Order{
+		ID:         h.ID,
+		ShopID:     h.ShopID,
+		CustomerID: h.UserID,
+		Status:     h.Status,
+		Items:      h.Items,
+		Total:      h.Total,
+		CreatedAt:  h.CreatedAt,
+		UpdatedAt:  h.UpdatedAt,
+		ShopName:   h.ShopName,
+
+		CancelReason: h.CancelReason,
+		CancelledBy:  h.CancelledBy,
+	}
+}
 
 // -------- handlers --------
 
-// POST /orders  (กดสั่ง/สร้างออเดอร์)
+// POST /orders  (กดสั่ง/สร้างออเดอร์) — customer_id มาจาก token เสมอ
 func CreateOrder(c *fiber.Ctx) error {
 	var body models.CreateOrderReq
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
 	}
+	body.CustomerID = middlewares.UserID(c)
 	if body.ShopID == "" {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "shop_id required"})
 	}
@@ -64,32 +99,72 @@ func CreateOrder(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "items required"})
 	}
 
-	total := computeTotal(body.Items)
-	doc := config.Client.Collection(ColOrders).NewDoc()
-	nowT := now()
-
-	order := models.Order{
-		ID:           doc.ID, // set back to caller
-		ShopID:       body.ShopID,
-		CustomerID:   body.CustomerID,
-		Status:       models.OrderPending, // เริ่มที่ pending
-		Items:        body.Items,
-		Note:         body.Note,
-		Total:        total,
-		CreatedAt:    nowT,
-		UpdatedAt:    nowT,
-		CustomerName: body.CustomerName,
+	if err := models.CheckOrderCreate(models.OrderPending, orderActor(c)); err != nil {
+		code, _ := orderTransitionStatus(err)
+		return c.Status(code).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// เขียนลง Firestore
-	_, err := doc.Set(config.Ctx, order)
+	shop, err := config.DB.Shops().Get(config.Ctx, body.ShopID)
 	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "failed to get shop", "msg": err.Error()})
+	}
+	if err := services.CheckShopOpen(shop, services.ShopServiceOrder, now()); err != nil {
+		_, resp := respondShopClosed(c, err)
+		return resp
+	}
+
+	var (
+		order  models.Order
+		priced *services.PricedItems
+		stock  *services.StockResult
+	)
+	err = config.DB.RunTransaction(config.Ctx, func(ctx context.Context, tx store.Repos) error {
+		// ราคา/สถานะเมนูใช้ของจริงใน shops/{id}/menu ไม่ใช้ราคาที่ client ส่งมา
+		var err error
+		priced, err = services.PriceOrderItems(ctx, tx, body.ShopID, body.Items, nil, body.AcceptPriceChanges)
+		if err != nil {
+			return err
+		}
+
+		nowT := now()
+		if err := services.CheckAvailability(priced, shop.ID, services.StockLocation(shop), nowT); err != nil {
+			return err
+		}
+		// ตัดสต็อกใน transaction เดียวกับการสร้างออเดอร์
+		stock, err = services.TakeStock(ctx, tx, shop, priced, nowT)
+		if err != nil {
+			return err
+		}
+
+		order = models.Order{
+			ShopID:       body.ShopID,
+			CustomerID:   body.CustomerID,
+			Status:       models.OrderPending, // เริ่มที่ pending
+			Items:        priced.Items,
+			Note:         body.Note,
+			Total:        priced.Total,
+			CreatedAt:    nowT,
+			UpdatedAt:    nowT,
+			CustomerName: body.CustomerName,
+			ShopName:     shop.ShopName,
+			StockHeld:    stock.Held,
+		}
+		// เขียนลง store (ID จะถูกแนบกลับให้)
+		return tx.Orders().Create(ctx, &order)
+	})
+	if err != nil {
+		if ok, resp := respondPricingError(c, err); ok {
+			return resp
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create order", "msg": err.Error()})
 	}
+	services.OrderEvents.PublishOrder(services.OrderEventCreated, &order, "")
+	services.AfterStockChange(config.Ctx, stock)
 
-	// แนบ ID กลับ
-	order.ID = doc.ID
-	return c.Status(http.StatusCreated).JSON(fiber.Map{"order": order})
+	return c.Status(http.StatusCreated).JSON(fiber.Map{"order": order, "price_changes": priced.Changes})
 }
 
 // GET /orders/:orderId (ดูออเดอร์เดี่ยว)
@@ -99,10 +174,9 @@ func GetOrderByID(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "orderId required"})
 	}
 
-	doc, err := config.Client.Collection(ColOrders).Doc(orderId).Get(config.Ctx)
+	ord, err := config.DB.Orders().Get(config.Ctx, orderId)
 	if err != nil {
-		// ⬇️ ตรวจ not-found แบบถูกต้อง
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "order not found"})
 		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
@@ -110,15 +184,9 @@ func GetOrderByID(c *fiber.Ctx) error {
 			"msg":   err.Error(),
 		})
 	}
-
-	var ord models.Order
-	if err := doc.DataTo(&ord); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to parse order",
-			"msg":   err.Error(),
-		})
+	if !canViewOrder(c, ord) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "not your order"})
 	}
-	ord.ID = doc.Ref.ID
 	return c.JSON(fiber.Map{"order": ord})
 }
 
@@ -130,14 +198,7 @@ func ListOrdersByShop(c *fiber.Ctx) error {
 	}
 	status := c.Query("status", "")
 
-	q := config.Client.Collection("orders").
-		Where("shopId", "==", shopId)
-
-	if status != "" {
-		q = q.Where("status", "==", status)
-	}
-
-	snaps, err := q.Documents(config.Ctx).GetAll()
+	out, err := config.DB.Orders().List(config.Ctx, store.OrderFilter{ShopID: shopId, Status: status})
 	if err != nil {
 		fmt.Printf("🔥 [ListOrdersByShop] shopId=%s status=%s err=%v\n", shopId, status, err)
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
@@ -145,19 +206,21 @@ func ListOrdersByShop(c *fiber.Ctx) error {
 			"msg":   err.Error(),
 		})
 	}
+	return c.JSON(fiber.Map{"orders": out})
+}
 
-	out := make([]models.Order, 0, len(snaps))
-	for _, d := range snaps {
-		var ord models.Order
-		if err := d.DataTo(&ord); err == nil {
-			ord.ID = d.Ref.ID
-			out = append(out, ord)
-		}
+// canViewOrder: เจ้าของออเดอร์ หรือ vendor เจ้าของร้านของออเดอร์
+func canViewOrder(c *fiber.Ctx, ord *models.Order) bool {
+	uid := middlewares.UserID(c)
+	if !middlewares.IsVendor(c) {
+		return uid != "" && (uid == ord.CustomerID || uid == ord.UserID)
 	}
-	return c.JSON(fiber.Map{"orders": out})
+	shop, err := config.DB.Shops().Get(config.Ctx, ord.ShopID)
+	return err == nil && middlewares.OwnsShop(c, shop)
 }
 
 // PUT /orders/:orderId/status   { "status": "prepare" }
+// status = "cancelled" จะวิ่งเข้า flow เดียวกับ POST /orders/:orderId/cancel (ต้องมี reason ถ้าเป็นร้าน)
 func UpdateOrderStatus(c *fiber.Ctx) error {
 	orderId := c.Params("orderId")
 	if orderId == "" {
@@ -169,205 +232,170 @@ func UpdateOrderStatus(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
 	}
 
-	newStatus := body.Status
+	newStatus := models.NormalizeOrderStatus(body.Status)
 	if !models.AllowedOrderStatus[newStatus] {
 		return c.Status(400).JSON(fiber.Map{
 			"error": fmt.Sprintf("status must be one of %v", keys(models.AllowedOrderStatus)),
 		})
 	}
+	return changeOrderStatus(c, orderId, newStatus, body.Reason)
+}
+
+// POST /orders/:orderId/cancel   { "reason": "..." }
+// ลูกค้ายกเลิกได้เฉพาะตอน pending, ร้านยกเลิกได้จนถึง prepare แต่ต้องใส่เหตุผล
+// ถ้าออเดอร์ชำระผ่าน wallet แล้ว จะคืนเงินใน transaction เดียวกัน
+func CancelOrder(c *fiber.Ctx) error {
+	orderId := c.Params("orderId")
+	if orderId == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "orderId required"})
+	}
 
-	ref := config.Client.Collection(ColOrders).Doc(orderId)
+	var body models.CancelOrderReq
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+		}
+	}
+	return changeOrderStatus(c, orderId, models.OrderCancelled, body.Reason)
+}
 
-	var out models.Order
-	err := config.Client.RunTransaction(config.Ctx, func(ctx context.Context, tx *firestore.Transaction) error {
-		// 1) อ่านเอกสารเดิม
-		snap, err := tx.Get(ref)
+// changeOrderStatus คือแกนกลางของการเปลี่ยนสถานะ: ตรวจ state machine แล้วเขียนใน transaction เดียว
+//   - completed / cancelled → ย้ายไป history (shop + user) แล้วลบจาก orders
+//   - cancelled ที่จ่ายเงินแล้ว → คืนเงินเข้า wallet
+func changeOrderStatus(c *fiber.Ctx, orderId, newStatus, reason string) error {
+	actor := orderActor(c)
+	callerID := middlewares.UserID(c)
+	reason = strings.TrimSpace(reason)
+
+	if newStatus == models.OrderCancelled && actor == models.ActorVendor && reason == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "reason required when a shop cancels an order"})
+	}
+
+	var (
+		out        models.Order
+		prevStatus string
+		refund     *models.WalletTxn
+		stock      *services.StockResult
+	)
+	err := config.DB.RunTransaction(config.Ctx, func(ctx context.Context, tx store.Repos) error {
+		// 1) อ่านเอกสารเดิม (store จัดการ fallback shopId / customerId / items ให้แล้ว)
+		ord, err := tx.Orders().Get(ctx, orderId)
 		if err != nil {
-			if strings.Contains(err.Error(), "NotFound") {
+			if errors.Is(err, store.ErrNotFound) {
 				return fiber.NewError(404, "order not found")
 			}
 			return fiber.NewError(500, "failed to get order: "+err.Error())
 		}
 
-		// 2) map ไป struct สำหรับฟิลด์หลัก
-		var ord models.Order
-		if err := snap.DataTo(&ord); err != nil {
-			return fiber.NewError(500, "failed to parse order: "+err.Error())
-		}
-		dataMap := snap.Data()
-
-		// fallback shopId / customerId (userId)
-		if ord.ShopID == "" {
-			if v, ok := dataMap["shopId"].(string); ok {
-				ord.ShopID = v
-			}
+		// ลูกค้ายุ่งได้เฉพาะออเดอร์ของตัวเอง
+		if actor == models.ActorCustomer && callerID != ord.CustomerID && callerID != ord.UserID {
+			return fiber.NewError(403, "not your order")
 		}
-		if ord.CustomerID == "" {
-			if v, ok := dataMap["customerId"].(string); ok {
-				ord.CustomerID = v
-			} else if v, ok := dataMap["userId"].(string); ok {
-				ord.CustomerID = v
-			}
+		// ออเดอร์ที่ไม่มีร้าน ไม่มีร้านไหนเป็นเจ้าของ
+		if actor == models.ActorVendor && ord.ShopID == "" {
+			return fiber.NewError(403, "not your shop")
 		}
 
-		// -------- NEW: หา shop_name ให้แน่นอน --------
-		shopName := ""
-		if v, ok := dataMap["shop_name"].(string); ok && strings.TrimSpace(v) != "" {
-			shopName = v
-		} else if v, ok := dataMap["shopName"].(string); ok && strings.TrimSpace(v) != "" {
-			shopName = v
+		// 2) ตรวจ state machine ก่อนเขียนอะไรทั้งนั้น
+		cur := ord.Status
+		if strings.TrimSpace(cur) == "" {
+			cur = models.OrderPending // เอกสารเก่าที่ไม่มี status
 		}
-		// ถ้ายังไม่มี ลองอ่าน shops/{shopId} ภายใน transaction
-		if shopName == "" && ord.ShopID != "" {
-			shopRef := config.Client.Collection("shops").Doc(ord.ShopID)
-			shopSnap, err := tx.Get(shopRef)
-			if err == nil && shopSnap.Exists() {
-				if s, ok := shopSnap.Data()["name"].(string); ok && strings.TrimSpace(s) != "" {
-					shopName = s
-				} else if s, ok := shopSnap.Data()["shop_name"].(string); ok && strings.TrimSpace(s) != "" {
-					shopName = s
-				} else if s, ok := shopSnap.Data()["title"].(string); ok && strings.TrimSpace(s) != "" {
-					shopName = s
-				}
-			}
+		if err := models.CheckOrderTransition(cur, newStatus, actor); err != nil {
+			return err
 		}
-		// ---------------------------------------------
-
-		// 3) เตรียม normalize รายการเมนู (qty=int, price=float64, เก็บ extras)
-		mapToItem := func(m map[string]interface{}) models.OrderItem {
-			it := models.OrderItem{}
-
-			// id (menuId)
-			if s, ok := m["id"].(string); ok && s != "" {
-				it.ID = s
-			}
-			if it.ID == "" {
-				if s, ok := m["menuId"].(string); ok && s != "" {
-					it.ID = s
-				}
+		prevStatus = cur
+
+		// -------- หา shop_name ให้แน่นอน + ตรวจเจ้าของร้าน --------
+		shopName := strings.TrimSpace(ord.ShopName)
+		// ร้านต้องเป็นเจ้าของ shops/{shopId}; ถ้ายังไม่มีชื่อร้านก็อ่านจากเอกสารเดียวกัน
+		if (actor == models.ActorVendor || shopName == "") && ord.ShopID != "" {
+			shop, err := tx.Shops().Get(ctx, ord.ShopID)
+			if err == nil && shopName == "" {
+				shopName = strings.TrimSpace(shop.ShopName)
 			}
-
-			if s, ok := m["name"].(string); ok {
-				it.Name = s
-			}
-			if s, ok := m["image"].(string); ok {
-				it.Image = s
-			}
-			if s, ok := m["description"].(string); ok {
-				it.Description = s
-			}
-
-			// price -> float64
-			switch v := m["price"].(type) {
-			case float64:
-				it.Price = v
-			case int:
-				it.Price = float64(v)
-			case int64:
-				it.Price = float64(v)
+			if actor == models.ActorVendor && (err != nil || shop.VendorID != callerID) {
+				return fiber.NewError(403, "not your shop")
 			}
+		}
+		ord.ShopName = shopName
+		// ---------------------------------------------
 
-			// qty -> int
-			switch v := m["qty"].(type) {
-			case int:
-				it.Qty = v
-			case int64:
-				it.Qty = int(v)
-			case float64:
-				it.Qty = int(v)
+		// อ่านบัญชีที่ต้องคืนเงินให้เสร็จก่อนเริ่มเขียน (ข้อจำกัด Firestore)
+		var payer *models.User
+		paid := paidAmount(ord)
+		if newStatus == models.OrderCancelled && paid > 0 {
+			payerID := ord.UserID
+			if payerID == "" {
+				payerID = ord.CustomerID
 			}
-
-			// extras (อะไรก็ได้)
-			if ex, ok := m["extras"]; ok {
-				it.Extras = ex
+			payer, err = tx.Accounts().GetAs(ctx, store.RoleUser, payerID)
+			if err != nil {
+				return fiber.NewError(500, "failed to load payer for refund: "+err.Error())
 			}
-
-			return it
 		}
 
-		normalizeItems := func(raw any) []models.OrderItem {
-			out := make([]models.OrderItem, 0)
-			switch v := raw.(type) {
-			case []interface{}:
-				for _, one := range v {
-					m, ok := one.(map[string]interface{})
-					if !ok {
-						continue
-					}
-					out = append(out, mapToItem(m))
-				}
-			case []map[string]interface{}:
-				for _, m := range v {
-					out = append(out, mapToItem(m))
-				}
-			default:
-				// unsupported -> empty
+		// เมนูที่ต้องคืนสต็อก (อ่านก่อนเขียนเช่นกัน)
+		var heldMenus map[string]*models.MenuItem
+		if newStatus == models.OrderCancelled && len(ord.StockHeld) > 0 {
+			heldMenus, err = services.LoadHeldStock(ctx, tx, ord)
+			if err != nil {
+				return fiber.NewError(500, "failed to load menu stock: "+err.Error())
 			}
-			return out
-		}
-
-		var items []models.OrderItem
-		if raw, ok := dataMap["items"]; ok && raw != nil {
-			items = normalizeItems(raw)
-		} else if raw, ok := dataMap["order_items"]; ok && raw != nil { // เผื่อชื่ออื่น
-			items = normalizeItems(raw)
 		}
 
-		// 4) ตั้งค่าจะส่งคืน + อัปเดตเวลา
-		ord.ID = snap.Ref.ID
+		// 3) ตั้งค่าจะส่งคืน + อัปเดตเวลา
 		ord.Status = newStatus
 		ord.UpdatedAt = now()
+		if newStatus == models.OrderCancelled {
+			ord.CancelReason = reason
+			ord.CancelledBy = string(actor)
+		}
 
-		// 5) ถ้า completed → ย้ายไป history (shop + user) แล้วลบจาก orders
-		if newStatus == "completed" {
-			if ord.ShopID == "" {
-				return fiber.NewError(400, "order missing shopId")
-			}
-			if ord.CustomerID == "" {
-				return fiber.NewError(400, "order missing customerId")
+		// 4) ยังไม่จบ → อัปเดตสถานะใน orders
+		if !models.IsTerminalOrderStatus(newStatus) {
+			if err := tx.Orders().Update(ctx, orderId, map[string]any{
+				"status":    newStatus,
+				"updatedAt": ord.UpdatedAt,
+			}); err != nil {
+				return fiber.NewError(500, "failed to update: "+err.Error())
 			}
+			out = *ord
+			return nil
+		}
 
-			shopHistoryRef := config.Client.Collection("shops").Doc(ord.ShopID).Collection("history").Doc(orderId)
-			userHistoryRef := config.Client.Collection("users").Doc(ord.CustomerID).Collection("history").Doc(orderId)
-
-			payload := map[string]interface{}{
-				"historyId":        orderId,
-				"orderId":          ord.ID,
-				"userId":           ord.CustomerID,
-				"shopId":           ord.ShopID,
-				"shop_name":        shopName, // ✅ ใส่ชื่อร้าน
-				"status":           ord.Status,
-				"total":            ord.Total,
-				"createdAt":        ord.CreatedAt, // เก็บของเดิม
-				"updatedAt":        ord.UpdatedAt, // เวลาที่อัปเดตล่าสุด
-				"movedToHistoryAt": now(),         // เวลาเข้า history
-				"items":            items,         // แนบรายการเมนู
-				"item_count":       len(items),    // (ออปชัน) สำหรับสรุปเร็ว ๆ
+		// 5) จบแล้ว → ย้ายไป history แล้วลบจาก orders
+		if err := moveOrderToHistory(ctx, tx, ord); err != nil {
+			return err
+		}
+		if newStatus == models.OrderCompleted {
+			// ความนิยมของร้าน (GET /shops?sort=popular)
+			if err := tx.Shops().AddOrderCount(ctx, ord.ShopID, 1); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return fiber.NewError(500, "failed to update shop stats: "+err.Error())
 			}
+		}
 
-			if err := tx.Set(shopHistoryRef, payload, firestore.MergeAll); err != nil {
-				return fiber.NewError(500, "failed to write shop history: "+err.Error())
+		// 6) คืนสต็อก + คืนเงิน
+		if heldMenus != nil {
+			stock, err = services.ReturnStock(ctx, tx, ord, heldMenus, ord.UpdatedAt)
+			if err != nil {
+				return fiber.NewError(500, "failed to restore stock: "+err.Error())
 			}
-			if err := tx.Set(userHistoryRef, payload, firestore.MergeAll); err != nil {
-				return fiber.NewError(500, "failed to write user history: "+err.Error())
-			}
-			if err := tx.Delete(ref); err != nil {
-				return fiber.NewError(500, "failed to delete original order: "+err.Error())
-			}
-
-			out = ord
-			return nil
 		}
-
-		// 6) ไม่ใช่ completed → อัปเดตสถานะใน orders
-		if err := tx.Update(ref, []firestore.Update{
-			{Path: "status", Value: newStatus},
-			{Path: "updatedAt", Value: ord.UpdatedAt},
-		}); err != nil {
-			return fiber.NewError(500, "failed to update: "+err.Error())
+		if payer != nil {
+			refund, err = services.ApplyWalletEntry(ctx, tx, payer, services.WalletEntry{
+				Type:    models.WalletRefund,
+				Amount:  paid,
+				RefType: models.WalletRefOrder,
+				RefID:   ord.ID,
+				Note:    strings.TrimSuffix("order cancelled: "+reason, ": "),
+			})
+			if err != nil {
+				return fiber.NewError(500, "failed to refund: "+err.Error())
+			}
 		}
 
-		out = ord
+		out = *ord
 		return nil
 	})
 
@@ -375,10 +403,82 @@ func UpdateOrderStatus(c *fiber.Ctx) error {
 		if fe, ok := err.(*fiber.Error); ok {
 			return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
 		}
+		if code, ok := orderTransitionStatus(err); ok {
+			var te *models.OrderTransitionError
+			errors.As(err, &te)
+			return c.Status(code).JSON(fiber.Map{
+				"error":   err.Error(),
+				"from":    te.From,
+				"to":      te.To,
+				"allowed": models.NextOrderStatuses(te.From, actor),
+			})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.JSON(fiber.Map{"order": out})
+	services.OrderEvents.PublishOrder(services.OrderEventStatusChanged, &out, prevStatus)
+	services.AfterStockChange(config.Ctx, stock)
+
+	resp := fiber.Map{"order": out}
+	if refund != nil {
+		resp["refund"] = refund
+	}
+	return c.JSON(resp)
+}
+
+// paidAmount คือยอดที่ตัดจาก wallet ไปแล้วสำหรับออเดอร์นี้
+// ออเดอร์เก่าจาก checkout ไม่มี paidAmount แต่มี userId → ถือว่าจ่ายเต็ม total
+func paidAmount(o *models.Order) float64 {
+	if o.PaidAmount > 0 {
+		return o.PaidAmount
+	}
+	if o.UserID != "" {
+		return o.Total
+	}
+	return 0
+}
+
+// moveOrderToHistory เขียน history ทั้งฝั่งร้านและฝั่ง user แล้วลบออเดอร์เดิม
+// ต้องเรียกหลังอ่านทุกอย่างใน transaction เสร็จแล้ว
+func moveOrderToHistory(ctx context.Context, tx store.Repos, ord *models.Order) error {
+	if ord.ShopID == "" {
+		return fiber.NewError(400, "order missing shopId")
+	}
+	if ord.CustomerID == "" {
+		return fiber.NewError(400, "order missing customerId")
+	}
+
+	if err := tx.History().Put(ctx, &models.HistoryItem{
+		ID:               ord.ID,
+		HistoryID:        ord.ID,
+		OrderID:          ord.ID,
+		UserID:           ord.CustomerID,
+		ShopID:           ord.ShopID,
+		ShopName:         ord.ShopName, // ✅ ใส่ชื่อร้าน
+		Status:           ord.Status,
+		Total:            ord.Total,
+		CreatedAt:        ord.CreatedAt,    // เก็บของเดิม
+		UpdatedAt:        ord.UpdatedAt,    // เวลาที่อัปเดตล่าสุด
+		MovedToHistoryAt: now(),            // เวลาเข้า history
+		Items:            ord.Items,        // แนบรายการเมนู
+		ItemCount:        len(ord.Items),   // (ออปชัน) สำหรับสรุปเร็ว ๆ
+		CancelReason:     ord.CancelReason, // มีเฉพาะ cancelled
+		CancelledBy:      ord.CancelledBy,  // customer / vendor / system
+		RefundAmount:     refundOf(ord),    // ยอดที่คืนเข้า wallet
+	}); err != nil {
+		return fiber.NewError(500, "failed to write history: "+err.Error())
+	}
+	if err := tx.Orders().Delete(ctx, ord.ID); err != nil {
+		return fiber.NewError(500, "failed to delete original order: "+err.Error())
+	}
+	return nil
+}
+
+func refundOf(o *models.Order) float64 {
+	if o.Status != models.OrderCancelled {
+		return 0
+	}
+	return paidAmount(o)
 }
 
 func ListHistoryByShop(c *fiber.Ctx) error {
@@ -399,47 +499,27 @@ func ListHistoryByShop(c *fiber.Ctx) error {
 		}
 	}
 
-	col := config.Client.Collection("shops").Doc(shopId).Collection("history")
-
-	q := col.OrderBy("movedToHistoryAt", firestore.Desc).Limit(limit)
-	if !cursor.IsZero() {
-		q = q.StartAfter(cursor)
+	entries, err := config.DB.History().ListByShop(config.Ctx, shopId, store.HistoryQuery{
+		Limit:      limit,
+		StartAfter: cursor,
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	iter := q.Documents(config.Ctx)
-	defer iter.Stop()
-
-	var out []models.Order
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-		}
-
-		var o models.Order
-		if err := doc.DataTo(&o); err != nil {
-			continue
-		}
-		o.ID = doc.Ref.ID
-
-		out = append(out, o)
+	// ฝั่งร้านยังใช้ shape เดียวกับ order
+	out := make([]models.Order, 0, len(entries))
+	for _, h := range entries {
+		out = append(out, historyToOrder(h))
 	}
 
 	resp := fiber.Map{
 		"shopId":  shopId,
 		"history": out,
 	}
-	if len(out) > 0 {
-		last := out[len(out)-1]
-		// ใช้ createdAt หรือ movedToHistoryAt แล้วแต่ field ที่มี
-		ts := last.UpdatedAt
-		if ts.IsZero() {
-			ts = last.CreatedAt
-		}
-		resp["nextStartAfter"] = ts.Format(time.RFC3339)
+	if len(entries) > 0 {
+		// cursor คือ movedToHistoryAt ของรายการสุดท้าย
+		resp["nextStartAfter"] = entries[len(entries)-1].MovedToHistoryAt.Format(time.RFC3339Nano)
 	}
 
 	return c.JSON(resp)
@@ -454,14 +534,8 @@ func keys(m map[string]bool) []string {
 	return out
 }
 
-// ให้เป็นเวลาไทย (เอาไว้คำนวณ dayKey)
-var tzBKK, _ = time.LoadLocation("Asia/Bangkok")
-
-func dayKeyOf(t time.Time) string {
-	return t.In(tzBKK).Format("2006-01-02")
-}
-
-// POST /shops/:id/reservations
+// POST /shops/:id/reservations   { "people", "phone", "note", "date": "YYYY-MM-DD", "time": "HH:MM" }
+// ร้านที่ตั้งค่า slot ต้องส่ง date + time และถูกตรวจที่ว่างใน transaction
 func CreateReservation(c *fiber.Ctx) error {
 	shopId := c.Params("id")
 	if shopId == "" {
@@ -472,6 +546,7 @@ func CreateReservation(c *fiber.Ctx) error {
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
 	}
+	body.UserID = middlewares.UserID(c) // จองในนามคนที่ login เท่านั้น
 	if body.UserID == "" {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "user_id required"})
 	}
@@ -479,46 +554,35 @@ func CreateReservation(c *fiber.Ctx) error {
 		body.People = 1
 	}
 
-	col := config.Client.Collection(models.ColReservations) // เช่น "reservations"
-	doc := col.NewDoc()
-
-	resv := models.Reservation{
-		ID:        doc.ID,
-		ShopID:    shopId,
-		Phone:     body.Phone,
-		UserID:    body.UserID,
-		People:    body.People,
-		Note:      body.Note,
-		CreatedAt: now(),
-	}
-
-	// ✅ เขียนลง collection หลักก่อน
-	if _, err := doc.Set(config.Ctx, resv); err != nil {
+	// ✅ store เขียนทั้ง collection หลักและสำเนาใต้ users/{id}/reservations พร้อมกัน
+	resv, err := services.CreateReservation(config.Ctx, shopId, body, now())
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+		}
+		if ok, resp := respondShopClosed(c, err); ok {
+			return resp
+		}
+		if ok, resp := respondReservationError(c, err); ok {
+			return resp
+		}
+		var fe *services.InsufficientFundsError
+		if errors.As(err, &fe) {
+			return c.Status(402).JSON(fiber.Map{"error": "insufficient balance for deposit", "msg": fe.Error()})
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to create reservation",
 			"msg":   err.Error(),
 		})
 	}
 
-	// ✅ เขียนซ้ำไปเก็บใต้ user ด้วย
-	// ถ้ามี constant ชื่อ ColUsers ก็ใช้ models.ColUsers แทน "users"
-	userResvDoc := config.Client.
-		Collection("users").
-		Doc(body.UserID).
-		Collection("reservations").
-		Doc(doc.ID)
-
-	if _, err := userResvDoc.Set(config.Ctx, resv); err != nil {
-		// แล้วแต่ดีไซน์ ว่าจะถือว่า error เลย หรือแค่ log ไว้
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to create user reservation",
-			"msg":   err.Error(),
-		})
+	msg := "ส่งคำขอจองแล้ว รอร้านยืนยัน"
+	if resv.Status == models.ReservationConfirmed {
+		msg = "จองสำเร็จ"
 	}
-
 	return c.JSON(fiber.Map{
 		"ok":      true,
-		"message": "จองสำเร็จทันที",
+		"message": msg,
 		"data":    resv,
 	})
 }
@@ -532,41 +596,34 @@ func ListReservationsByShop(c *fiber.Ctx) error {
 		})
 	}
 
-	ctx := config.Ctx
-	client := config.Client
-
-	// 👇 เปลี่ยนมาอ่านจาก collection "reservations"
-	q := client.Collection("reservations").
-		Where("shop_id", "==", shopId)
-
-	docs, err := q.Documents(ctx).GetAll()
+	// ?date=YYYY-MM-DD (dayKey ตามเวลาท้องถิ่นของร้าน) และ ?status=pending,confirmed
+	var (
+		items []models.Reservation
+		err   error
+	)
+	if date := strings.TrimSpace(c.Query("date")); date != "" {
+		items, err = config.DB.Reservations().ListByShopDays(config.Ctx, shopId, []string{date})
+	} else {
+		items, err = config.DB.Reservations().ListByShop(config.Ctx, shopId)
+	}
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to list reservations",
 			"msg":   err.Error(),
 		})
 	}
-
-	// ไม่มีข้อมูล —> ไม่ error แต่ส่งเป็น array ว่าง
-	items := make([]models.Reservation, 0, len(docs))
-
-	for _, d := range docs {
-		var r models.Reservation
-		if err := d.DataTo(&r); err != nil {
-			continue
-		}
-
-		// เผื่อ struct ไม่ได้เก็บ ID
-		if r.ID == "" {
-			r.ID = d.Ref.ID
+	if st := strings.TrimSpace(c.Query("status")); st != "" {
+		want := map[string]bool{}
+		for _, v := range strings.Split(st, ",") {
+			want[strings.ToLower(strings.TrimSpace(v))] = true
 		}
-
-		// เผื่อ struct ไม่ได้ map shop_id กลับ
-		if r.ShopID == "" {
-			r.ShopID = shopId
+		kept := items[:0]
+		for _, r := range items {
+			if want[r.CurrentStatus()] {
+				kept = append(kept, r)
+			}
 		}
-
-		items = append(items, r)
+		items = kept
 	}
 
 	return c.JSON(fiber.Map{
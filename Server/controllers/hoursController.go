@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	services "github.com/PPEACH21/MoblieApp_MeebleProject/service"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// respondShopClosed ตอบ 409 (ร้านที่ถูกลบ = 404) ถ้า err เป็น services.ShopClosedError
+func respondShopClosed(c *fiber.Ctx, err error) (bool, error) {
+	var ce *services.ShopClosedError
+	if !errors.As(err, &ce) {
+		return false, nil
+	}
+	body := fiber.Map{"error": ce.Message, "code": ce.Code}
+	if ce.NextOpen != nil {
+		body["next_open"] = ce.NextOpen
+	}
+	if ce.Code == services.ShopDeletedCode {
+		return true, c.Status(http.StatusNotFound).JSON(body)
+	}
+	return true, c.Status(http.StatusConflict).JSON(body)
+}
+
+// shopHoursView คือ response ของ GET/PUT /shop/:id/hours
+func shopHoursView(s *models.Shop, at time.Time) fiber.Map {
+	out := fiber.Map{
+		"shop_id":  s.ID,
+		"hours":    s.Hours,
+		"status":   s.Status,
+		"schedule": s.Schedule,
+		"open_now": s.Status,
+	}
+	if s.Hours != nil {
+		loc := s.Hours.Location()
+		out["open_now"] = s.Hours.IsOpen(at)
+		out["local_time"] = at.In(loc).Format(time.RFC3339)
+		if next, open, ok := s.Hours.NextChange(at); ok {
+			out["next_change"] = fiber.Map{"at": next.In(loc), "open": open}
+		}
+	}
+	return out
+}
+
+// GET /shop/:id/hours
+func GetShopHours(c *fiber.Ctx) error {
+	s, err := config.DB.Shops().Get(config.Ctx, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(shopHoursView(s, time.Now()))
+}
+
+// PUT /shop/:id/hours   { "hours": { "timezone", "weekly": {"mon": [{"open":"09:00","close":"21:00"}]}, "exceptions": [...] } }
+// hours = null → เลิกใช้ตารางเวลา (ร้านคงสถานะปัจจุบันไว้ เปิด/ปิดเองตามเดิม)
+func SetShopHours(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var body models.SetOpeningHoursReq
+	if err := c.BodyParser(&body); err != nil {
+		return badRequest(c, "invalid body: "+err.Error())
+	}
+	if body.Hours != nil {
+		if err := body.Hours.Validate(); err != nil {
+			return badRequest(c, "hours: "+err.Error())
+		}
+	}
+
+	nowT := time.Now()
+	// schedule เดิมเก็บไว้ (flag ที่จำไว้ตอนปิดอัตโนมัติจะถูกคืนเมื่อตารางใหม่สั่งเปิด)
+	fields := map[string]any{"hours": nil, "schedule": nil, "updatedAt": nowT}
+	if body.Hours != nil {
+		fields["hours"] = *body.Hours
+		delete(fields, "schedule")
+	}
+	if err := config.DB.Shops().Update(config.Ctx, id, fields); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// ใช้ตารางใหม่ทันที ไม่ต้องรอ scheduler รอบถัดไป
+	if _, err := services.ApplyShopSchedule(config.Ctx, id, nowT); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	s, err := config.DB.Shops().Get(config.Ctx, id)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	services.ShopScheduler.Track(s, nowT)
+	services.IndexShop(s)
+	return c.JSON(shopHoursView(s, nowT))
+}
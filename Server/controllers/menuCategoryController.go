@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	services "github.com/PPEACH21/MoblieApp_MeebleProject/service"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+const maxMenuCategoryName = 60
+
+func menuCategoryName(body models.MenuCategoryReq) (string, bool) {
+	name := trim(body.Name)
+	return name, name != "" && len([]rune(name)) <= maxMenuCategoryName
+}
+
+// POST /shop/:id/menu/categories  หมวดใหม่ต่อท้ายสุด
+func CreateMenuCategory(c *fiber.Ctx) error {
+	shopID := c.Params("id")
+	var body models.MenuCategoryReq
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid body", "msg": err.Error()})
+	}
+	name, ok := menuCategoryName(body)
+	if !ok {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "`name` is required (max 60 characters)"})
+	}
+
+	cats, err := config.DB.MenuCategories().List(config.Ctx, shopID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create category", "msg": err.Error()})
+	}
+	pos := 0
+	for _, cat := range cats {
+		if cat.Position >= pos {
+			pos = cat.Position + 1
+		}
+	}
+	now := time.Now()
+	cat := models.MenuCategory{ShopID: shopID, Name: name, Position: pos, CreatedAt: now, UpdatedAt: now}
+	if err := config.DB.MenuCategories().Create(config.Ctx, &cat); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create category", "msg": err.Error()})
+	}
+	return c.Status(http.StatusCreated).JSON(cat)
+}
+
+// GET /shop/:id/menu/categories
+func ListMenuCategories(c *fiber.Ctx) error {
+	cats, err := config.DB.MenuCategories().List(config.Ctx, c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "failed to list categories", "msg": err.Error()})
+	}
+	return c.JSON(fiber.Map{"categories": cats, "count": len(cats)})
+}
+
+// PUT /shop/:id/menu/categories/:categoryId  เปลี่ยนชื่อหมวด (ลำดับใช้ PUT /shop/:id/menu/order)
+func UpdateMenuCategory(c *fiber.Ctx) error {
+	shopID, catID := c.Params("id"), c.Params("categoryId")
+	var body models.MenuCategoryReq
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid body", "msg": err.Error()})
+	}
+	name, ok := menuCategoryName(body)
+	if !ok {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "`name` is required (max 60 characters)"})
+	}
+	if err := config.DB.MenuCategories().Update(config.Ctx, shopID, catID, map[string]any{
+		"name":      name,
+		"updatedAt": time.Now(),
+	}); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "category not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update category", "msg": err.Error()})
+	}
+	cat, err := config.DB.MenuCategories().Get(config.Ctx, shopID, catID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(cat)
+}
+
+// DELETE /shop/:id/menu/categories/:categoryId  เมนูในหมวดย้ายไป "ไม่มีหมวด"
+func DeleteMenuCategory(c *fiber.Ctx) error {
+	moved, err := services.DeleteMenuCategory(config.Ctx, c.Params("id"), c.Params("categoryId"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "category not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete category", "msg": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "deleted", "items_uncategorized": moved})
+}
+
+// PUT /shop/:id/menu/order  จัดลำดับหมวด/เมนูทั้งหมดในครั้งเดียว (ทั้งหมดหรือไม่มีเลย)
+func ReorderMenu(c *fiber.Ctx) error {
+	shopID := c.Params("id")
+	var body models.MenuOrderReq
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid body", "msg": err.Error()})
+	}
+	if len(body.Categories) == 0 && len(body.Items) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "`categories` or `items` is required"})
+	}
+	if err := services.ReorderMenu(config.Ctx, shopID, body); err != nil {
+		var oe *services.MenuOrderError
+		if errors.As(err, &oe) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": oe.Msg})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "failed to reorder menu", "msg": err.Error()})
+	}
+
+	items, err := config.DB.Menus().List(config.Ctx, shopID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	cats, err := config.DB.MenuCategories().List(config.Ctx, shopID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	sections, uncategorized, _ := services.GroupMenu(cats, items)
+	return c.JSON(fiber.Map{"categories": sections, "uncategorized": uncategorized})
+}
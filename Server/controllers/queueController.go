@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/middlewares"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	services "github.com/PPEACH21/MoblieApp_MeebleProject/service"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// action ใน POST /shop/:id/queue/:ticketId/:action → สถานะใหม่
+var queueActions = map[string]string{
+	"call": models.QueueCalled,
+	"skip": models.QueueSkipped,
+	"seat": models.QueueSeated,
+}
+
+// respondQueueError ตอบ error จาก services คิว (ไม่พบ / ร้านปิด / QueueError)
+func respondQueueError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	}
+	if ok, resp := respondShopClosed(c, err); ok {
+		return resp
+	}
+	var qe *services.QueueError
+	if errors.As(err, &qe) {
+		code := http.StatusBadRequest
+		if qe.Conflict {
+			code = http.StatusConflict
+		}
+		body := fiber.Map{"error": qe.Message, "code": qe.Code}
+		if qe.Ticket != nil {
+			body["ticket"] = qe.Ticket
+		}
+		return c.Status(code).JSON(body)
+	}
+	return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
+
+// POST /shops/:id/queue   { "people": 2, "note": "..." }
+func TakeQueueTicket(c *fiber.Ctx) error {
+	var body models.TakeQueueTicketReq
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return badRequest(c, "invalid body")
+		}
+	}
+	if err := body.Validate(); err != nil {
+		return badRequest(c, err.Error())
+	}
+	t, err := services.TakeQueueTicket(config.Ctx, c.Params("id"), middlewares.UserID(c), body, now())
+	if err != nil {
+		return respondQueueError(c, err)
+	}
+	return c.Status(http.StatusCreated).JSON(fiber.Map{"ticket": t})
+}
+
+// GET /shops/:id/queue — สรุปคิวสำหรับลูกค้า (ไม่เปิดเผยข้อมูลคนอื่น)
+func GetQueueSummary(c *fiber.Ctx) error {
+	s, err := config.DB.Shops().Get(config.Ctx, c.Params("id"))
+	if err != nil || s.IsDeleted() {
+		if err == nil || errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	tickets, err := services.ShopQueue(config.Ctx, s.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	waiting, called := 0, make([]int, 0)
+	for _, t := range tickets {
+		if t.Status == models.QueueCalled {
+			called = append(called, t.Number)
+		} else {
+			waiting++
+		}
+	}
+	return c.JSON(fiber.Map{
+		"shop_id":      s.ID,
+		"queue_active": s.QueueActive,
+		"waiting":      waiting,
+		"called":       called, // เลขคิวที่ถูกเรียกอยู่ตอนนี้
+	})
+}
+
+// GET /shop/:id/queue — คิวทั้งหมดของร้าน (called ก่อน แล้ว waiting ตามลำดับ)
+func ListShopQueue(c *fiber.Ctx) error {
+	tickets, err := services.ShopQueue(config.Ctx, c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"tickets": tickets, "count": len(tickets)})
+}
+
+// POST /shop/:id/queue/next — เรียกคิวถัดไป
+func CallNextQueueTicket(c *fiber.Ctx) error {
+	t, err := services.CallNextQueueTicket(config.Ctx, c.Params("id"), now())
+	if err != nil {
+		return respondQueueError(c, err)
+	}
+	return c.JSON(fiber.Map{"ticket": t})
+}
+
+// POST /shop/:id/queue/:ticketId/call|skip|seat
+func MoveShopQueueTicket(c *fiber.Ctx) error {
+	to, ok := queueActions[c.Params("action")]
+	if !ok {
+		return badRequest(c, "action must be call, skip or seat")
+	}
+	t, err := services.MoveQueueTicket(config.Ctx, c.Params("id"), c.Params("ticketId"), to, models.ActorVendor, middlewares.UserID(c), now())
+	if err != nil {
+		return respondQueueError(c, err)
+	}
+	return c.JSON(fiber.Map{"ticket": t})
+}
+
+// GET /queue/tickets — บัตรที่ยังอยู่ในคิวของ user พร้อมลำดับ
+func ListMyQueueTickets(c *fiber.Ctx) error {
+	tickets, err := config.DB.Queue().ListActiveByUser(config.Ctx, middlewares.UserID(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	for i := range tickets {
+		if err := services.QueueTicketWithPosition(config.Ctx, &tickets[i]); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+	return c.JSON(fiber.Map{"tickets": tickets, "count": len(tickets)})
+}
+
+// GET /queue/tickets/:ticketId
+func GetMyQueueTicket(c *fiber.Ctx) error {
+	t, err := config.DB.Queue().Get(config.Ctx, c.Params("ticketId"))
+	if err == nil && t.UserID != middlewares.UserID(c) {
+		err = store.ErrNotFound
+	}
+	if err == nil {
+		err = services.QueueTicketWithPosition(config.Ctx, t)
+	}
+	if err != nil {
+		return respondQueueError(c, err)
+	}
+	return c.JSON(fiber.Map{"ticket": t})
+}
+
+// POST /queue/tickets/:ticketId/leave — ลูกค้าออกจากคิว
+func LeaveQueue(c *fiber.Ctx) error {
+	t, err := services.MoveQueueTicket(config.Ctx, "", c.Params("ticketId"), models.QueueLeft, models.ActorCustomer, middlewares.UserID(c), now())
+	if err != nil {
+		return respondQueueError(c, err)
+	}
+	return c.JSON(fiber.Map{"ticket": t})
+}
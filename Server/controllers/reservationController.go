@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/middlewares"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	services "github.com/PPEACH21/MoblieApp_MeebleProject/service"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// respondReservationError ตอบ 409/400 ถ้า err เป็น services.ReservationError
+func respondReservationError(c *fiber.Ctx, err error) (bool, error) {
+	var re *services.ReservationError
+	if !errors.As(err, &re) {
+		return false, nil
+	}
+	code := http.StatusBadRequest
+	if re.Conflict {
+		code = http.StatusConflict
+	}
+	return true, c.Status(code).JSON(fiber.Map{"error": re.Message, "code": re.Code})
+}
+
+// PUT /shop/:id/reservation-settings   { "settings": { "tables": [{"id","name","seats"}] | "seat_capacity", "slot_minutes", "booking_minutes", ... } }
+// settings = null → เลิกใช้ slot (กลับไปจองแบบไม่ระบุเวลา) การจองเดิมยังอยู่ครบ
+func SetReservationSettings(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var body models.SetReservationSettingsReq
+	if err := c.BodyParser(&body); err != nil {
+		return badRequest(c, "invalid body: "+err.Error())
+	}
+	fields := map[string]any{"reservation_settings": nil, "updatedAt": time.Now()}
+	if body.Settings != nil {
+		if err := body.Settings.Validate(); err != nil {
+			return badRequest(c, "settings: "+err.Error())
+		}
+		fields["reservation_settings"] = *body.Settings
+	}
+	if err := config.DB.Shops().Update(config.Ctx, id, fields); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	s, err := config.DB.Shops().Get(config.Ctx, id)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	services.IndexShop(s)
+	return c.JSON(fiber.Map{"shop_id": s.ID, "settings": s.ReservationSettings})
+}
+
+// GET /shops/:id/availability?date=YYYY-MM-DD&people=2
+// คืนทุก slot ของวันนั้น (ไม่ส่ง date = วันนี้ตามเวลาท้องถิ่นของร้าน) พร้อมที่ว่างและ bookable
+func GetShopAvailability(c *fiber.Ctx) error {
+	s, err := config.DB.Shops().Get(config.Ctx, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if s.IsDeleted() {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+	}
+
+	people := 1
+	if v := c.Query("people"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return badRequest(c, "people must be a positive number")
+		}
+		people = n
+	}
+	nowT := now()
+	loc := services.StockLocation(s)
+	date := c.Query("date", models.StockDay(nowT, loc))
+
+	slots, err := services.ReservationAvailability(config.Ctx, s, date, people, nowT)
+	if err != nil {
+		if ok, resp := respondReservationError(c, err); ok {
+			return resp
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	open := 0
+	for _, sl := range slots {
+		if sl.Bookable {
+			open++
+		}
+	}
+	return c.JSON(fiber.Map{
+		"shop_id":         s.ID,
+		"date":            date,
+		"timezone":        loc.String(),
+		"people":          people,
+		"accepting":       services.CheckShopReservable(s) == nil,
+		"slot_minutes":    s.ReservationSettings.SlotMinutes,
+		"booking_minutes": s.ReservationSettings.BookingMinutes,
+		"slots":           slots,
+		"open_count":      open,
+	})
+}
+
+// reservationTransitionStatus เลือก HTTP status ให้ error จาก state machine ของการจอง
+func reservationTransitionStatus(err error) (int, bool) {
+	var te *models.ReservationTransitionError
+	if !errors.As(err, &te) {
+		return 0, false
+	}
+	if te.RoleDenied {
+		return http.StatusForbidden, true
+	}
+	if !models.AllowedReservationStatus[te.To] {
+		return http.StatusBadRequest, true
+	}
+	return http.StatusConflict, true
+}
+
+// GET /reservations/:reservationId (เจ้าของการจอง หรือร้านเจ้าของ)
+func GetReservation(c *fiber.Ctx) error {
+	resv, err := config.DB.Reservations().Get(config.Ctx, c.Params("reservationId"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "reservation not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	callerID := middlewares.UserID(c)
+	if orderActor(c) == models.ActorVendor {
+		shop, err := config.DB.Shops().Get(config.Ctx, resv.ShopID)
+		if err != nil || shop.VendorID != callerID {
+			return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "not your shop"})
+		}
+	} else if resv.UserID != callerID {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": "not your reservation"})
+	}
+	return c.JSON(fiber.Map{
+		"reservation": resv,
+		"allowed":     models.NextReservationStatuses(resv.CurrentStatus(), orderActor(c)),
+	})
+}
+
+// PUT /reservations/:reservationId/status   { "status": "confirmed|declined|seated|completed|no_show|cancelled_by_user|cancelled_by_shop", "reason" }
+func UpdateReservationStatus(c *fiber.Ctx) error {
+	var body models.UpdateReservationStatusReq
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+	status := strings.ToLower(strings.TrimSpace(body.Status))
+	if !models.AllowedReservationStatus[status] {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("status must be one of %v", keys(models.AllowedReservationStatus)),
+		})
+	}
+	return changeReservationStatus(c, c.Params("reservationId"), status, body.Reason)
+}
+
+// POST /reservations/:reservationId/cancel   { "reason": "..." }
+// ลูกค้า → cancelled_by_user, ร้าน → cancelled_by_shop (ต้องใส่เหตุผล)
+func CancelReservation(c *fiber.Ctx) error {
+	var body models.CancelOrderReq
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+		}
+	}
+	return changeReservationStatus(c, c.Params("reservationId"), models.CancelStatusFor(orderActor(c)), body.Reason)
+}
+
+// changeReservationStatus ตรวจสิทธิ์ + state machine แล้วเขียนทั้งเอกสารหลักและสำเนาฝั่ง user ใน transaction เดียว
+// ทุกการเปลี่ยนถูกต่อท้ายใน history; ถ้ามีมัดจำจะคืน/โอนให้ร้านใน transaction เดียวกัน
+func changeReservationStatus(c *fiber.Ctx, id, newStatus, reason string) error {
+	actor := orderActor(c)
+	callerID := middlewares.UserID(c)
+	reason = strings.TrimSpace(reason)
+
+	if actor == models.ActorVendor && reason == "" &&
+		(newStatus == models.ReservationDeclined || newStatus == models.ReservationCancelledByShop) {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "reason required when a shop declines or cancels a reservation"})
+	}
+
+	var (
+		out     models.Reservation
+		deposit *models.WalletTxn
+	)
+	nowT := now()
+	err := config.DB.RunTransaction(config.Ctx, func(ctx context.Context, tx store.Repos) error {
+		resv, err := tx.Reservations().Get(ctx, id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return fiber.NewError(404, "reservation not found")
+			}
+			return fiber.NewError(500, "failed to get reservation: "+err.Error())
+		}
+
+		// ลูกค้ายุ่งได้เฉพาะการจองของตัวเอง / ร้านต้องเป็นเจ้าของร้านที่ถูกจอง
+		if actor == models.ActorCustomer && callerID != resv.UserID {
+			return fiber.NewError(403, "not your reservation")
+		}
+		if actor == models.ActorVendor {
+			shop, err := tx.Shops().Get(ctx, resv.ShopID)
+			if err != nil || shop.VendorID != callerID {
+				return fiber.NewError(403, "not your shop")
+			}
+		}
+
+		cur := resv.CurrentStatus()
+		if err := models.CheckReservationTransition(cur, newStatus, actor); err != nil {
+			return err
+		}
+		// no_show ได้หลังถึงเวลาจองแล้วเท่านั้น
+		if newStatus == models.ReservationNoShow && resv.SlotStart != nil && nowT.Before(*resv.SlotStart) {
+			return fiber.NewError(409, "reservation time has not started yet")
+		}
+
+		// มัดจำ: คืนลูกค้า หรือเข้าบัญชีร้าน (อ่านบัญชีก่อนเขียนทุกอย่าง)
+		deposit, err = services.SettleReservationDeposit(ctx, tx, resv, newStatus, nowT)
+		if err != nil {
+			return fiber.NewError(500, "failed to settle deposit: "+err.Error())
+		}
+
+		resv.Status = newStatus
+		resv.UpdatedAt = nowT
+		if newStatus == models.ReservationDeclined || newStatus == models.ReservationCancelledByUser || newStatus == models.ReservationCancelledByShop {
+			resv.CancelReason = reason
+		}
+		resv.History = append(resv.History, models.ReservationStatusChange{
+			From: cur, To: newStatus, By: actor, ByID: callerID, Reason: reason, At: nowT,
+		})
+		if err := tx.Reservations().Save(ctx, resv); err != nil {
+			return fiber.NewError(500, "failed to update reservation: "+err.Error())
+		}
+		out = *resv
+		return nil
+	})
+
+	if err != nil {
+		if fe, ok := err.(*fiber.Error); ok {
+			return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+		}
+		if code, ok := reservationTransitionStatus(err); ok {
+			var te *models.ReservationTransitionError
+			errors.As(err, &te)
+			return c.Status(code).JSON(fiber.Map{
+				"error":   err.Error(),
+				"from":    te.From,
+				"to":      te.To,
+				"allowed": models.NextReservationStatuses(te.From, actor),
+			})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	resp := fiber.Map{"reservation": out}
+	if deposit != nil && deposit.UserID == callerID {
+		resp["deposit"] = deposit // เฉพาะรายการใน wallet ของผู้เรียกเอง
+	}
+	return c.JSON(resp)
+}
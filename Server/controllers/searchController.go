@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/search"
+	services "github.com/PPEACH21/MoblieApp_MeebleProject/service"
+)
+
+const maxSearchQuery = 100 // ตัวอักษร
+
+// searchHit = ผลค้นหา + ชื่อร้าน (เมนูแสดงชื่อร้านคู่กัน)
+type searchHit struct {
+	search.Hit
+	ShopName string `json:"shop_name,omitempty"`
+}
+
+func searchQuery(c *fiber.Ctx) (string, bool) {
+	q := strings.TrimSpace(c.Query("q"))
+	if r := []rune(q); len(r) > maxSearchQuery {
+		q = string(r[:maxSearchQuery])
+	}
+	return q, q != ""
+}
+
+// GET /search?q=&type=shop|menu&limit=
+// ค้นชื่อ/คำอธิบายของร้านและเมนู (ตัดคำไทย, prefix, พิมพ์ผิดเล็กน้อยได้)
+func Search(c *fiber.Ctx) error {
+	q, ok := searchQuery(c)
+	if !ok {
+		return badRequest(c, "q is required")
+	}
+	kind := strings.TrimSpace(c.Query("type"))
+	if kind != "" && kind != search.KindShop && kind != search.KindMenu {
+		return badRequest(c, "type must be shop or menu")
+	}
+
+	hits := services.SearchIndex.Search(q, search.Options{Kind: kind, Limit: toLimit(c.Query("limit"), 20)})
+	out := make([]searchHit, 0, len(hits))
+	for _, h := range hits {
+		sh := searchHit{Hit: h}
+		if shop, ok := services.SearchIndex.Get(search.ShopDocID(h.ShopID)); ok {
+			sh.ShopName = shop.Name
+		}
+		out = append(out, sh)
+	}
+	return c.JSON(fiber.Map{"query": q, "tokens": search.Tokenize(q), "results": out, "count": len(out)})
+}
+
+// GET /search/suggest?q=&limit=   ชื่อร้าน/เมนูสำหรับ autocomplete (ไม่ซ้ำ)
+func SearchSuggest(c *fiber.Ctx) error {
+	q, ok := searchQuery(c)
+	if !ok {
+		return c.JSON(fiber.Map{"suggestions": []string{}})
+	}
+	limit := toLimit(c.Query("limit"), 8)
+
+	hits := services.SearchIndex.Search(q, search.Options{Limit: limit * 3})
+	seen := map[string]bool{}
+	out := make([]string, 0, limit)
+	for _, h := range hits {
+		if len(out) == limit {
+			break
+		}
+		if !seen[h.Name] {
+			seen[h.Name] = true
+			out = append(out, h.Name)
+		}
+	}
+	return c.JSON(fiber.Map{"suggestions": out})
+}
@@ -1,9 +1,10 @@
 package controllers
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
 	"sort"
@@ -11,12 +12,12 @@ import (
 	"strings"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/middlewares"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
 	services "github.com/PPEACH21/MoblieApp_MeebleProject/service"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
 	"github.com/gofiber/fiber/v2"
-	"google.golang.org/genproto/googleapis/type/latlng"
 )
 
 /* ---------------- helpers ---------------- */
@@ -61,6 +62,7 @@ func CreateShop(c *fiber.Ctx) error {
 	if in.PriceMin != nil && in.PriceMax != nil && *in.PriceMin > *in.PriceMax {
 		return badRequest(c, "price_min must be <= price_max")
 	}
+	in.VendorID = middlewares.UserID(c) // ร้านเป็นของ vendor ที่ login อยู่เสมอ
 	if in.VendorID == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "vendor_id is required"})
 	}
@@ -71,201 +73,297 @@ func CreateShop(c *fiber.Ctx) error {
 	now := time.Now()
 	in.CreatedAt = now
 	in.UpdatedAt = now
-
-	var vendorRef *firestore.DocumentRef
-	if in.VendorID != "" {
-		vendorRef = config.Vendor.Doc(in.VendorID)
+	in.OrderCount = 0
+	in.IndexVersion = models.ShopIndexVersion
+	in.Geohash = ""
+	in.Schedule = nil
+	in.DeletedAt, in.PurgeAfter, in.DeletedBy = nil, nil, ""
+	if in.Hours != nil {
+		if err := in.Hours.Validate(); err != nil {
+			return badRequest(c, "hours: "+err.Error())
+		}
+	}
+	if in.Address != nil {
+		in.Geohash = models.EncodeGeohash(in.Address.Latitude, in.Address.Longitude, models.GeohashPrecision)
 	}
 
-	in.VendorRef = vendorRef
-	docRef, _, err := config.Client.Collection("shops").Add(config.Ctx, in)
-
-	if err != nil {
+	// store แปลง vendor_id เป็น reference ไปที่ vendors/{id} ให้เอง
+	if err := config.DB.Shops().Create(config.Ctx, &in); err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	if in.Hours != nil {
+		// เปิด/ปิดตามตารางทันที (ร้านใหม่ status = true เสมอ)
+		if out, err := services.ApplyShopSchedule(config.Ctx, in.ID, now); err == nil && out != nil {
+			in = *out
+		}
+		services.ShopScheduler.Track(&in, now)
+	}
+	services.IndexShop(&in)
+
 	return c.Status(http.StatusCreated).JSON(fiber.Map{
 		"message": "shop created",
-		"id":      docRef.ID,
+		"id":      in.ID,
 	})
 }
 
-// GET /shops
-func GetAllShops(c *fiber.Ctx) error {
-	ctx := config.Ctx
+const (
+	shopPageDefault = 20
+	shopPageMax     = 100
+	// ช่วงราคา/ร้านที่ถูกลบกรองหลัง query จึงอ่านเผื่อเป็นรอบ ๆ; กันไม่ให้อ่านทั้ง collection ในคำขอเดียว
+	shopPageMaxScan = 1000
+)
 
-	docs, err := config.Client.Collection("shops").Documents(ctx).GetAll()
+// GET /shops?type=&status=open|closed&order_active=&reserve_active=&queue_active=&price_min=&price_max=
+//
+//	&sort=newest|price_asc|price_desc|popular&limit=&cursor=
+//
+// คืน next_cursor (ว่าง = หมดแล้ว) ส่งกลับมาเป็น ?cursor= เพื่อขอหน้าถัดไปด้วย filter/sort เดิม
+// price_min/price_max = ช่วงราคาที่ทับกับช่วงราคาของร้าน
+func GetAllShops(c *fiber.Ctx) error {
+	filter, err := parseShopFilter(c)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return badRequest(c, err.Error())
+	}
+	priceLo, priceHi, hasPrice, err := parsePriceRange(c)
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+	sortBy := trim(c.Query("sort"))
+	if sortBy == "" {
+		sortBy = store.ShopSortNewest
+	}
+	if !store.IsShopSort(sortBy) {
+		return badRequest(c, "sort must be one of: newest, price_asc, price_desc, popular")
+	}
+	limit := toLimit(c.Query("limit"), shopPageDefault)
+	if limit > shopPageMax {
+		limit = shopPageMax
 	}
 
-	out := make([]models.Shop, 0, len(docs))
-
-	for _, d := range docs {
-		data := d.Data()
-
-		var s models.Shop
-		s.ID = d.Ref.ID
-
-		// --- Strings ---
-		if v, ok := data["shop_name"].(string); ok {
-			s.ShopName = v
-		}
-		if v, ok := data["description"].(string); ok {
-			s.Description = v
-		}
-		if v, ok := data["type"].(string); ok {
-			s.Type = v
-		}
-		if v, ok := data["image"].(string); ok {
-			s.Image = v
-		}
-		if v, ok := data["status"].(bool); ok {
-			s.Status = v
+	q := store.ShopQuery{
+		Type:          filter.Type,
+		Open:          filter.Open,
+		OrderActive:   filter.OrderActive,
+		ReserveActive: filter.ReserveActive,
+		QueueActive:   filter.QueueActive,
+		Sort:          sortBy,
+		Limit:         limit + 1, // +1 ไว้ดูว่ามีหน้าถัดไปไหม
+	}
+	if cur := trim(c.Query("cursor")); cur != "" {
+		if q.After, err = store.DecodeShopCursor(cur); err != nil {
+			return badRequest(c, err.Error())
 		}
+	}
 
-		// --- Numbers (float64) ---
-		if v, ok := data["price_min"]; ok {
-			switch n := v.(type) {
-			case float64:
-				s.PriceMin = &n
-			case int64:
-				f := float64(n)
-				s.PriceMin = &f
-			case int:
-				f := float64(n)
-				s.PriceMin = &f
-			case string:
-				if f, err := strconv.ParseFloat(n, 64); err == nil {
-					s.PriceMin = &f
-				}
-			}
+	out := make([]models.Shop, 0, limit)
+	more := false
+	for scanned := 0; ; {
+		page, err := config.DB.Shops().Page(config.Ctx, q)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
-		if v, ok := data["price_max"]; ok {
-			switch n := v.(type) {
-			case float64:
-				s.PriceMax = &n
-			case int64:
-				f := float64(n)
-				s.PriceMax = &f
-			case int:
-				f := float64(n)
-				s.PriceMax = &f
-			case string:
-				if f, err := strconv.ParseFloat(n, 64); err == nil {
-					s.PriceMax = &f
+		for i := range page {
+			s := &page[i]
+			// ร้านที่ถูกลบ (รอ purge) และช่วงราคา กรองหลัง query
+			if !s.IsDeleted() && (!hasPrice || shopPriceOverlaps(s, priceLo, priceHi)) {
+				if len(out) == limit {
+					more = true
+					break
 				}
+				out = append(out, *s)
 			}
+			q.After = store.ShopCursorOf(s, sortBy)
 		}
-
-		// --- Booleans ---
-		if v, ok := data["order_active"]; ok {
-			switch b := v.(type) {
-			case bool:
-				s.OrderActive = b
-			case string:
-				s.OrderActive = b == "true" || b == "1"
-			case float64:
-				s.OrderActive = b == 1
-			case int:
-				s.OrderActive = b == 1
-			}
+		scanned += len(page)
+		if more || len(page) < q.Limit {
+			break
 		}
-		if v, ok := data["reserve_active"]; ok {
-			switch b := v.(type) {
-			case bool:
-				s.ReserveActive = b
-			case string:
-				s.ReserveActive = b == "true" || b == "1"
-			case float64:
-				s.ReserveActive = b == 1
-			case int:
-				s.ReserveActive = b == 1
-			}
+		if scanned >= shopPageMaxScan {
+			// ยังไม่ครบหน้าแต่หยุดอ่าน: ให้ client ขอต่อจากตำแหน่งที่อ่านถึง
+			more = true
+			break
 		}
+	}
 
-		// --- Address (ละติจูด/ลองจิจูด) ---
-		if v, ok := data["address"].(map[string]interface{}); ok {
-			if lat, ok1 := v["Latitude"].(float64); ok1 {
-				if lng, ok2 := v["Longitude"].(float64); ok2 {
-					s.Address = &latlng.LatLng{Latitude: lat, Longitude: lng}
-				}
-			}
+	resp := fiber.Map{"shops": out, "count": len(out), "next_cursor": ""}
+	if more && q.After != nil {
+		if len(out) == limit {
+			q.After = store.ShopCursorOf(&out[len(out)-1], sortBy)
 		}
+		resp["next_cursor"] = q.After.Encode()
+	}
+	return c.JSON(resp)
+}
 
-		// --- VendorRef ---
-		if v, ok := data["vendor_id"].(*firestore.DocumentRef); ok {
-			s.VendorRef = v
-			s.VendorID = v.ID
+func parsePriceRange(c *fiber.Ctx) (lo, hi float64, ok bool, err error) {
+	lo, hi = 0, math.Inf(1)
+	if v := trim(c.Query("price_min")); v != "" {
+		if lo, err = strconv.ParseFloat(v, 64); err != nil || lo < 0 {
+			return 0, 0, false, errors.New("price_min must be a number >= 0")
 		}
-
-		// --- Timestamps ---
-		if v, ok := data["createdAt"].(time.Time); ok {
-			s.CreatedAt = v
-		} else if v, ok := data["createdAt"].(string); ok {
-			if t, err := time.Parse(time.RFC3339, v); err == nil {
-				s.CreatedAt = t
-			}
+		ok = true
+	}
+	if v := trim(c.Query("price_max")); v != "" {
+		if hi, err = strconv.ParseFloat(v, 64); err != nil || hi < 0 {
+			return 0, 0, false, errors.New("price_max must be a number >= 0")
 		}
-		if v, ok := data["updatedAt"].(time.Time); ok {
-			s.UpdatedAt = v
-		} else if v, ok := data["updatedAt"].(string); ok {
-			if t, err := time.Parse(time.RFC3339, v); err == nil {
-				s.UpdatedAt = t
-			}
+		ok = true
+	}
+	if lo > hi {
+		return 0, 0, false, errors.New("price_min must be <= price_max")
+	}
+	return lo, hi, ok, nil
+}
+
+// shopPriceOverlaps: ช่วงราคาร้าน [price_min, price_max] ทับกับ [lo, hi]
+// ร้านที่ไม่มีราคาเลยไม่ผ่าน; มีแค่ค่าเดียวถือว่าเป็นราคาเดียว
+func shopPriceOverlaps(s *models.Shop, lo, hi float64) bool {
+	if s.PriceMin == nil && s.PriceMax == nil {
+		return false
+	}
+	pMin, pMax := s.PriceMin, s.PriceMax
+	if pMin == nil {
+		pMin = pMax
+	}
+	if pMax == nil {
+		pMax = pMin
+	}
+	return *pMin <= hi && *pMax >= lo
+}
+
+const (
+	nearbyDefaultRadiusKm = 5.0
+	nearbyMaxRadiusKm     = 50.0
+)
+
+// GET /shops/nearby?lat=&lng=&radius=(km)&type=&status=open|closed&order_active=&reserve_active=&queue_active=&limit=
+// ค้นด้วย geohash prefix (ช่องกลาง + 8 ช่องรอบ) แล้วกรองด้วยระยะจริง เรียงจากใกล้ไปไกล
+func NearbyShops(c *fiber.Ctx) error {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return badRequest(c, "lat and lng are required (lat -90..90, lng -180..180)")
+	}
+	radius := nearbyDefaultRadiusKm
+	if v := c.Query("radius"); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil || r <= 0 {
+			return badRequest(c, "radius must be a positive number (km)")
 		}
-		if s.CreatedAt.IsZero() {
-			s.CreatedAt = time.Now()
+		radius = math.Min(r, nearbyMaxRadiusKm)
+	}
+	filter, err := parseShopFilter(c)
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+	limit := toLimit(c.Query("limit"), 50)
+
+	prec := models.GeohashCoverPrecision(lat, radius)
+	candidates, err := config.DB.Shops().ListByGeohash(config.Ctx, models.GeohashCover(lat, lng, prec))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	out := make([]models.NearbyShop, 0, len(candidates))
+	for _, s := range candidates {
+		if s.Address == nil || !filter.match(&s) {
+			continue
 		}
-		if s.UpdatedAt.IsZero() {
-			s.UpdatedAt = s.CreatedAt
+		d := models.DistanceKm(lat, lng, s.Address.Latitude, s.Address.Longitude)
+		if d > radius {
+			continue
 		}
+		out = append(out, models.NearbyShop{Shop: s, DistanceKm: math.Round(d*1000) / 1000})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].DistanceKm < out[j].DistanceKm })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return c.JSON(fiber.Map{"shops": out, "count": len(out), "radius_km": radius})
+}
+
+// shopFilter คือ filter ร่วมของรายการร้าน (ค่าว่าง / nil = ไม่กรอง)
+type shopFilter struct {
+	Type          string
+	Open          *bool // status: open = true, closed = false
+	OrderActive   *bool
+	ReserveActive *bool
+	QueueActive   *bool
+}
 
-		out = append(out, s)
+func parseShopFilter(c *fiber.Ctx) (shopFilter, error) {
+	var f shopFilter
+	if t := trim(c.Query("type")); t != "" {
+		if !models.IsAllowedType(t) {
+			return f, errors.New("type must be one of: MainCourse, Beverage, FastFoods, Appetizer, Dessert")
+		}
+		f.Type = t
+	}
+	switch strings.ToLower(trim(c.Query("status"))) {
+	case "":
+	case "open", "true":
+		v := true
+		f.Open = &v
+	case "closed", "false":
+		v := false
+		f.Open = &v
+	default:
+		return f, errors.New("status must be open or closed")
+	}
+	for key, dst := range map[string]**bool{"order_active": &f.OrderActive, "reserve_active": &f.ReserveActive, "queue_active": &f.QueueActive} {
+		if v := trim(c.Query(key)); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return f, fmt.Errorf("%s must be true or false", key)
+			}
+			*dst = &b
+		}
 	}
+	return f, nil
+}
 
-	return c.JSON(fiber.Map{"shops": out})
+func (f shopFilter) match(s *models.Shop) bool {
+	if s.IsDeleted() {
+		return false
+	}
+	if f.Type != "" && s.Type != f.Type {
+		return false
+	}
+	if f.Open != nil && s.Status != *f.Open {
+		return false
+	}
+	if f.OrderActive != nil && s.OrderActive != *f.OrderActive {
+		return false
+	}
+	if f.ReserveActive != nil && s.ReserveActive != *f.ReserveActive {
+		return false
+	}
+	if f.QueueActive != nil && s.QueueActive != *f.QueueActive {
+		return false
+	}
+	return true
 }
 
-// GET /shop/:id
+// GET /shop/by-id/:id  (id = vendor id)
 func GetShopByID(c *fiber.Ctx) error {
 	id := c.Params("id")
 	if id == "" {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "vendor id required"})
 	}
 
-	vendorRef := config.Client.Collection("vendors").Doc(id)
-
-	query := config.Client.Collection("shops").
-		Where("vendor_id", "==", vendorRef).
-		Limit(1)
-
-	docs, err := query.Documents(config.Ctx).GetAll()
+	s, err := config.DB.Shops().GetByVendor(config.Ctx, id)
 	if err != nil {
-		// ✅ --- LOG THE ERROR HERE ---
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found for this user"})
+		}
 		log.Printf("🔥 Firebase query error: %v", err)
-		// ---------------------------------
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "query error"})
 	}
-
-	if len(docs) == 0 {
+	// ร้านที่ถูกลบ (รอ purge) เห็นได้เฉพาะเจ้าของ (ไว้กู้คืน)
+	if s.IsDeleted() && !middlewares.OwnsShop(c, s) {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found for this user"})
 	}
-
-	d := docs[0]
-	var s models.Shop
-	if err := d.DataTo(&s); err != nil {
-		// ✅ --- AND LOG THE ERROR HERE ---
-		log.Printf("🔥 Firestore DataTo error: %v", err)
-		// ------------------------------------
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "decode error"})
-	}
-
-	if s.VendorRef != nil {
-		s.VendorID = s.VendorRef.ID
-	}
-
-	s.ID = d.Ref.ID
 	return c.JSON(s)
 }
 func GetShopByShopID(c *fiber.Ctx) error {
@@ -274,28 +372,29 @@ func GetShopByShopID(c *fiber.Ctx) error {
 	if id == "" {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "shop id required"})
 	}
-	d, err := config.Client.Collection("shops").Doc(id).Get(config.Ctx)
+	s, err := config.DB.Shops().Get(config.Ctx, id)
 	if err != nil {
-
 		log.Printf("🔥 Failed to get shop by ID: %v", err)
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
 	}
-
-	var s models.Shop
-	if err := d.DataTo(&s); err != nil {
-		log.Printf("🔥 Firestore DataTo error (GetShopByShopID): %v", err)
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "decode error"})
-	}
-
-	// 3. Populate the string IDs for the JSON response
-	if s.VendorRef != nil {
-		s.VendorID = s.VendorRef.ID
+	if s.IsDeleted() && !middlewares.OwnsShop(c, s) {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
 	}
-	s.ID = d.Ref.ID
-
 	return c.JSON(s)
 }
 
+// ฟิลด์ที่ PUT /shop/:id รับ (address แปลงเป็น GeoPoint + geohash ก่อนเขียน)
+var shopEditableFields = map[string]bool{
+	"shop_name":      true,
+	"description":    true,
+	"type":           true,
+	"image":          true,
+	"address":        true,
+	"order_active":   true,
+	"reserve_active": true,
+	"status":         true,
+}
+
 // PUT /shop/:id   (partial update)
 func UpdateShop(c *fiber.Ctx) error {
 	id := c.Params("id")
@@ -315,31 +414,88 @@ func UpdateShop(c *fiber.Ctx) error {
 	}
 	// ถ้าอยากตรวจ min/max เพิ่มที่นี่ได้ (ระวังชนิด JSON decode)
 
-	in["updatedAt"] = time.Now()
-
-	updates := make([]firestore.Update, 0, len(in))
-	for k, v := range in {
-		updates = append(updates, firestore.Update{Path: k, Value: v})
+	// รับเฉพาะฟิลด์ที่ vendor แก้เองได้ ที่เหลือ (order_count, index_v, price_min/max, hours,
+	// reservation_settings, queue_active, soft delete ฯลฯ) มี endpoint / job ของตัวเอง
+	for k := range in {
+		if !shopEditableFields[k] {
+			delete(in, k)
+		}
 	}
+	if len(in) == 0 {
+		return badRequest(c, "no editable fields")
+	}
+	if v, ok := in["address"]; ok {
+		addr, _ := v.(map[string]any)
+		lat, okLat := addr["latitude"].(float64)
+		lng, okLng := addr["longitude"].(float64)
+		if !okLat || !okLng {
+			return badRequest(c, "address must have numeric latitude and longitude")
+		}
+		for k, v := range models.ShopLocationFields(lat, lng) {
+			in[k] = v
+		}
+	}
+	in["updatedAt"] = time.Now()
 
-	_, err := config.Client.Collection("shops").Doc(id).Update(config.Ctx, updates)
-	if err != nil {
+	if err := config.DB.Shops().Update(config.Ctx, id, in); err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	services.ReindexShop(config.Ctx, id)
 	return c.JSON(fiber.Map{"message": "shop updated"})
 }
 
-// DELETE /shop/:id
+// DELETE /shop/:id   (soft delete: ซ่อนร้าน กู้คืนได้ก่อน purge_after แล้ว purge job จะลบถาวร)
 func DeleteShop(c *fiber.Ctx) error {
 	id := c.Params("id")
 	if id == "" {
 		return badRequest(c, "id required")
 	}
-	_, err := config.Client.Collection("shops").Doc(id).Delete(config.Ctx)
+	s, err := services.SoftDeleteShop(config.Ctx, id, middlewares.UserID(c))
 	if err != nil {
+		var ae *services.ActiveOrdersError
+		var ar *services.ActiveReservationsError
+		switch {
+		case errors.As(err, &ae):
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"error":     "shop has active orders, finish or cancel them first",
+				"code":      "ACTIVE_ORDERS",
+				"order_ids": ae.OrderIDs,
+			})
+		case errors.As(err, &ar):
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"error":           "shop has active reservations or held deposits, decline, cancel or close them first",
+				"code":            "ACTIVE_RESERVATIONS",
+				"reservation_ids": ar.ReservationIDs,
+			})
+		case errors.Is(err, services.ErrShopDeleted):
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, store.ErrNotFound):
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+		}
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(fiber.Map{"message": "shop deleted"})
+	return c.JSON(fiber.Map{
+		"message":     "shop deleted",
+		"deleted_at":  s.DeletedAt,
+		"purge_after": s.PurgeAfter,
+	})
+}
+
+// POST /shop/:id/restore
+func RestoreShop(c *fiber.Ctx) error {
+	s, err := services.RestoreShop(config.Ctx, c.Params("id"), middlewares.UserID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrShopNotDeleted):
+			return c.Status(http.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrShopRestoreExpired):
+			return c.Status(http.StatusGone).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, store.ErrNotFound):
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "shop restored", "shop": s})
 }
 
 // PUT /shop/:id/update  (basic fields only)
@@ -357,7 +513,7 @@ func UpdateShopBasic(c *fiber.Ctx) error {
 		})
 	}
 
-	updates := make([]firestore.Update, 0, 16)
+	updates := make(map[string]any, 16)
 	now := time.Now()
 
 	// ---------- ฟิลด์เดิม ----------
@@ -366,17 +522,11 @@ func UpdateShopBasic(c *fiber.Ctx) error {
 		if name == "" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "shop_name cannot be empty"})
 		}
-		updates = append(updates, firestore.Update{
-			Path:  "shop_name",
-			Value: name,
-		})
+		updates["shop_name"] = name
 	}
 
 	if body.Description != nil {
-		updates = append(updates, firestore.Update{
-			Path:  "description",
-			Value: strings.TrimSpace(*body.Description),
-		})
+		updates["description"] = strings.TrimSpace(*body.Description)
 	}
 
 	if body.Type != nil {
@@ -386,87 +536,65 @@ func UpdateShopBasic(c *fiber.Ctx) error {
 				"error": "type must be one of: MainCourse, Beverage, FastFoods, Appetizer, Dessert",
 			})
 		}
-		updates = append(updates, firestore.Update{
-			Path:  "type",
-			Value: t,
-		})
+		updates["type"] = t
 	}
 
 	if body.Image != nil {
-		updates = append(updates, firestore.Update{
-			Path:  "image",
-			Value: strings.TrimSpace(*body.Image),
-		})
+		updates["image"] = strings.TrimSpace(*body.Image)
 	}
 
 	if body.Address != nil {
 		if body.Address.Latitude != 0 || body.Address.Longitude != 0 {
-			addr := map[string]any{
-				"latitude":  body.Address.Latitude,
-				"longitude": body.Address.Longitude,
+			// เก็บเป็น GeoPoint ให้ตรงกับ models.Shop.Address + geohash สำหรับค้นร้านใกล้ ๆ
+			for k, v := range models.ShopLocationFields(body.Address.Latitude, body.Address.Longitude) {
+				updates[k] = v
 			}
-			updates = append(updates, firestore.Update{
-				Path:  "address",
-				Value: addr,
-			})
 		}
 	}
 
 	// ---------- toggle fields ----------
 	if body.OrderActive != nil {
-		updates = append(updates, firestore.Update{
-			Path:  "order_active",
-			Value: *body.OrderActive, // bool
-		})
+		updates["order_active"] = *body.OrderActive // bool
 	}
 
 	if body.ReserveActive != nil {
-		updates = append(updates, firestore.Update{
-			Path:  "reserve_active",
-			Value: *body.ReserveActive, // bool
-		})
+		updates["reserve_active"] = *body.ReserveActive // bool
+	}
+
+	if body.QueueActive != nil {
+		updates["queue_active"] = *body.QueueActive // bool
 	}
 
 	// ✅ status เป็น bool (true = open, false = closed)
 	if body.Status != nil {
-		updates = append(updates, firestore.Update{
-			Path:  "status",
-			Value: *body.Status,
-		})
+		updates["status"] = *body.Status
 
-		// ถ้าปิดร้าน -> ปิดรับ order / reserve ไปด้วย
+		// ถ้าปิดร้าน -> ปิดรับ order / reserve / queue ไปด้วย
 		if !*body.Status {
-			updates = append(updates,
-				firestore.Update{Path: "order_active", Value: false},
-				firestore.Update{Path: "reserve_active", Value: false},
-			)
+			updates["order_active"] = false
+			updates["reserve_active"] = false
+			updates["queue_active"] = false
 		}
 	}
 
 	// ---------- updatedAt ----------
-	updates = append(updates, firestore.Update{
-		Path:  "updatedAt",
-		Value: now,
-	})
-
-	docRef := config.Client.Collection(models.ColShops).Doc(shopId)
+	updates["updatedAt"] = now
 
-	if len(updates) > 0 {
-		if _, err := docRef.Update(config.Ctx, updates); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "failed to update shop",
-				"msg":   err.Error(),
-			})
+	if err := config.DB.Shops().Update(config.Ctx, shopId, updates); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
 		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to update shop",
+			"msg":   err.Error(),
+		})
 	}
 
-	snap, err := docRef.Get(config.Ctx)
-	if err != nil || !snap.Exists() {
+	out, err := config.DB.Shops().Get(config.Ctx, shopId)
+	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "shop not found after update"})
 	}
-
-	out := snap.Data()
-	out["id"] = snap.Ref.ID
+	services.IndexShop(out)
 
 	return c.JSON(fiber.Map{"shop": out})
 }
@@ -480,8 +608,7 @@ func CreateMenuItem(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "shop id is required"})
 	}
 
-	shopDoc, err := config.Client.Collection(models.ColShops).Doc(shopId).Get(config.Ctx)
-	if err != nil || !shopDoc.Exists() {
+	if _, err := config.DB.Shops().Get(config.Ctx, shopId); err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
 	}
 
@@ -511,24 +638,44 @@ func CreateMenuItem(c *fiber.Ctx) error {
 	}
 	now := time.Now()
 
-	menuCol := config.Client.Collection(models.ColShops).Doc(shopId).Collection(models.SubColMenu)
-	docRef := menuCol.NewDoc()
+	if err := models.ValidateOptionGroups(body.OptionGroups); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid `option_groups`", "msg": err.Error()})
+	}
+	if err := models.ValidateAvailability(body.Availability); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid `availability`", "msg": err.Error()})
+	}
+
+	// เมนูใหม่ต่อท้ายหมวดของตัวเอง
+	catID := trim(body.CategoryID)
+	if catID != "" {
+		if _, err := config.DB.MenuCategories().Get(config.Ctx, shopId, catID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "`category_id` not found"})
+		}
+	}
+	existing, err := config.DB.Menus().List(config.Ctx, shopId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create menu item", "msg": err.Error()})
+	}
 
 	item := models.MenuItem{
-		ID:          docRef.ID,
-		ShopID:      shopId,
-		Name:        name,
-		Description: trim(body.Description),
-		Image:       img,
-		Price:       *body.Price,
-		Active:      active,
-		CreatedAt:   now,
-		UpdatedAt:   now,
-	}
-
-	if _, err := docRef.Set(config.Ctx, item); err != nil {
+		ShopID:       shopId,
+		Name:         name,
+		Description:  trim(body.Description),
+		Image:        img,
+		Price:        *body.Price,
+		Active:       active,
+		CategoryID:   catID,
+		Position:     services.NextMenuPosition(existing, catID),
+		OptionGroups: body.OptionGroups,
+		Availability: body.Availability,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := config.DB.Menus().Create(config.Ctx, &item); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create menu item", "msg": err.Error()})
 	}
+	services.IndexMenuItem(&item)
 
 	updErr := services.UpdateShopPriceRange(config.Ctx, shopId)
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -550,8 +697,8 @@ func ListMenuItems(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "shop id is required"})
 	}
 
-	colRef := config.Client.Collection(models.ColShops).Doc(shopId).Collection(models.SubColMenu)
-	docs, err := colRef.Documents(config.Ctx).GetAll()
+	// store normalize ชื่อฟิลด์เก่าที่เป็นตัวใหญ่ (Name/Price/...) ให้แล้ว
+	items, err := config.DB.Menus().List(config.Ctx, shopId)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to list menu",
@@ -559,37 +706,25 @@ func ListMenuItems(c *fiber.Ctx) error {
 			"path":  fmt.Sprintf("shops/%s/%s", shopId, models.SubColMenu),
 		})
 	}
+	cats, err := config.DB.MenuCategories().List(config.Ctx, shopId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to list menu categories", "msg": err.Error()})
+	}
 
-	out := make([]map[string]any, 0, len(docs))
-	for _, d := range docs {
-		m := d.Data()
-		m["id"] = d.Ref.ID
-		// normalize เผื่อกรณี field เคยเป็นตัวใหญ่
-		if v, ok := m["Name"]; ok {
-			m["name"] = v
-		}
-		if v, ok := m["Price"]; ok {
-			m["price"] = v
-		}
-		if v, ok := m["Image"]; ok {
-			m["image"] = v
-		}
-		if v, ok := m["Description"]; ok {
-			m["description"] = v
-		}
-		if v, ok := m["CreatedAt"]; ok {
-			m["createdAt"] = v
-		}
-		if v, ok := m["UpdatedAt"]; ok {
-			m["updatedAt"] = v
-		}
-		out = append(out, m)
+	var shop *models.Shop
+	if s, err := config.DB.Shops().Get(config.Ctx, shopId); err == nil {
+		shop = s
 	}
+	services.MarkAvailableNow(items, shop, time.Now())
 
+	// items = ลำดับเดียวกับที่แสดง (client เดิมใช้แบบ flat), categories = แยกตามหมวด
+	sections, uncategorized, out := services.GroupMenu(cats, items)
 	return c.JSON(fiber.Map{
-		"items": out,
-		"count": len(out),
-		"path":  fmt.Sprintf("shops/%s/%s", shopId, models.SubColMenu),
+		"items":         out,
+		"categories":    sections,
+		"uncategorized": uncategorized,
+		"count":         len(out),
+		"path":          fmt.Sprintf("shops/%s/%s", shopId, models.SubColMenu),
 	})
 }
 
@@ -606,38 +741,82 @@ func UpdateMenuItem(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body", "msg": err.Error()})
 	}
 
-	updates := []firestore.Update{{Path: "updatedAt", Value: time.Now()}}
+	updates := map[string]any{"updatedAt": time.Now()}
 	if body.Name != nil {
 		n := trim(*body.Name)
 		if n == "" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "`name` cannot be empty"})
 		}
-		updates = append(updates, firestore.Update{Path: "name", Value: n})
+		updates["name"] = n
 	}
 	if body.Description != nil {
-		updates = append(updates, firestore.Update{Path: "description", Value: trim(*body.Description)})
+		updates["description"] = trim(*body.Description)
 	}
 	if body.Image != nil {
 		img := trim(*body.Image)
 		if img != "" && !isURL(img) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "`image` must be http(s) url"})
 		}
-		updates = append(updates, firestore.Update{Path: "image", Value: img})
+		updates["image"] = img
 	}
 	if body.Price != nil {
 		if *body.Price < 0 {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "`price` must be >= 0"})
 		}
-		updates = append(updates, firestore.Update{Path: "price", Value: *body.Price})
+		updates["price"] = *body.Price
 	}
 	if body.Active != nil {
-		updates = append(updates, firestore.Update{Path: "active", Value: *body.Active})
+		updates["active"] = *body.Active
+	}
+	if body.OptionGroups != nil {
+		groups := *body.OptionGroups
+		if err := models.ValidateOptionGroups(groups); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid `option_groups`", "msg": err.Error()})
+		}
+		if len(groups) == 0 {
+			updates["option_groups"] = nil
+		} else {
+			updates["option_groups"] = groups
+		}
+	}
+	if body.Availability != nil {
+		windows := *body.Availability
+		if err := models.ValidateAvailability(windows); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid `availability`", "msg": err.Error()})
+		}
+		if len(windows) == 0 {
+			updates["availability"] = nil
+		} else {
+			updates["availability"] = windows
+		}
+	}
+	if body.CategoryID != nil {
+		// ย้ายหมวด = ไปต่อท้ายหมวดใหม่ (ถ้าหมวดเดิม ลำดับไม่เปลี่ยน)
+		catID := trim(*body.CategoryID)
+		if catID != "" {
+			if _, err := config.DB.MenuCategories().Get(config.Ctx, shopId, catID); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "`category_id` not found"})
+			}
+		}
+		items, err := config.DB.Menus().List(config.Ctx, shopId)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update menu item", "msg": err.Error()})
+		}
+		for _, m := range items {
+			if m.ID == menuId && m.CategoryID != catID {
+				updates["category_id"] = catID
+				updates["position"] = services.NextMenuPosition(items, catID)
+			}
+		}
 	}
 
-	docRef := config.Client.Collection(models.ColShops).Doc(shopId).Collection(models.SubColMenu).Doc(menuId)
-	if _, err := docRef.Update(config.Ctx, updates); err != nil {
+	if err := config.DB.Menus().Update(config.Ctx, shopId, menuId, updates); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "menu item not found"})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update menu item", "msg": err.Error()})
 	}
+	services.ReindexMenuItem(config.Ctx, shopId, menuId)
 
 	updErr := services.UpdateShopPriceRange(config.Ctx, shopId)
 	return c.JSON(fiber.Map{
@@ -660,10 +839,10 @@ func DeleteMenuItem(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "shop id and menu id are required"})
 	}
 
-	docRef := config.Client.Collection(models.ColShops).Doc(shopId).Collection(models.SubColMenu).Doc(menuId)
-	if _, err := docRef.Delete(config.Ctx); err != nil {
+	if err := config.DB.Menus().Delete(config.Ctx, shopId, menuId); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete menu item", "msg": err.Error()})
 	}
+	services.UnindexMenuItem(shopId, menuId)
 
 	updErr := services.UpdateShopPriceRange(config.Ctx, shopId)
 	return c.JSON(fiber.Map{
@@ -678,40 +857,35 @@ func DeleteMenuItem(c *fiber.Ctx) error {
 	})
 }
 
+// GET /orders  vendor เห็นออเดอร์ของร้านตัวเอง, user เห็นของตัวเอง
 func ListAllOrders(c *fiber.Ctx) error {
-	iter := config.Client.Collection(ColOrders).OrderBy("CreatedAt", firestore.Desc).Documents(config.Ctx)
-	docs, err := iter.GetAll()
+	filter := store.OrderFilter{CustomerID: middlewares.UserID(c)}
+	if middlewares.IsVendor(c) {
+		shop, err := config.DB.Shops().GetByVendor(config.Ctx, middlewares.UserID(c))
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return c.JSON(fiber.Map{"orders": []models.Order{}})
+			}
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "failed to list orders", "msg": err.Error()})
+		}
+		filter = store.OrderFilter{ShopID: shop.ID}
+	}
+	orders, err := config.DB.Orders().List(config.Ctx, filter)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "failed to list orders", "msg": err.Error()})
 	}
-
-	orders := make([]models.Order, 0, len(docs))
-	for _, d := range docs {
-		var o models.Order
-		if err := d.DataTo(&o); err == nil {
-			o.ID = d.Ref.ID
-			orders = append(orders, o)
-		}
-	}
 	return c.JSON(fiber.Map{"orders": orders})
 }
 func ListUserOrders(c *fiber.Ctx) error {
-	ctx := config.Ctx
-
-	userId := strings.TrimSpace(c.Query("userId"))
+	userId := middlewares.UserID(c) // ไม่เชื่อ ?userId= จาก client
 	if userId == "" {
 		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
 			"error": "missing userId",
 		})
 	}
 
-	// ใช้ CustomerID ใน Firestore เป็นตัวกรอง
-	iter := config.Client.
-		Collection("orders").
-		Where("customerId", "==", userId).
-		Documents(ctx)
-
-	docs, err := iter.GetAll()
+	// ใช้ CustomerID เป็นตัวกรอง (store เรียงเวลาใหม่สุดขึ้นก่อนให้แล้ว)
+	orders, err := config.DB.Orders().List(config.Ctx, store.OrderFilter{CustomerID: userId})
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to list user orders",
@@ -719,22 +893,6 @@ func ListUserOrders(c *fiber.Ctx) error {
 		})
 	}
 
-	orders := make([]models.Order, 0, len(docs))
-	for _, d := range docs {
-		var o models.Order
-		if err := d.DataTo(&o); err == nil {
-			o.ID = d.Ref.ID
-			orders = append(orders, o)
-		}
-	}
-
-	// 🔹 เรียงเวลาใหม่สุดขึ้นก่อน
-	sort.Slice(orders, func(i, j int) bool {
-		ti := orders[i].CreatedAt
-		tj := orders[j].CreatedAt
-		return ti.After(tj)
-	})
-
 	// 🔹 map ให้ตรงกับฝั่ง RN ที่ต้องการ
 	type OrderResponse struct {
 		ID        string    `json:"id"`
@@ -767,11 +925,9 @@ func GetUserHistoryDetail(c *fiber.Ctx) error {
 		})
 	}
 
-	docRef := config.Client.Collection("users").Doc(uid).Collection("history").Doc(historyId)
-	snap, err := docRef.Get(context.Background())
+	h, err := config.DB.History().GetForUser(config.Ctx, uid, historyId)
 	if err != nil {
-		// ✅ ตรวจ error แบบง่าย ไม่ต้อง import grpc/status
-		if strings.Contains(err.Error(), "NotFound") {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.Status(http.StatusNotFound).JSON(fiber.Map{
 				"error": "history not found",
 			})
@@ -781,20 +937,44 @@ func GetUserHistoryDetail(c *fiber.Ctx) error {
 		})
 	}
 
-	if !snap.Exists() {
-		return c.Status(http.StatusNotFound).JSON(fiber.Map{
-			"error": "history not found",
-		})
-	}
+	return c.JSON(fiber.Map{"order": historyToOrder(*h)})
+}
 
-	var order models.Order
-	if err := snap.DataTo(&order); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to parse: " + err.Error(),
-		})
+// PUT /shop/:id/menu/:menuId/stock  { "mode": "daily"|"absolute"|"off", "quantity": 20, "low_threshold": 5 }
+func SetMenuStock(c *fiber.Ctx) error {
+	shopId, menuId := c.Params("id"), c.Params("menuId")
+	var body models.SetStockReq
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body", "msg": err.Error()})
+	}
+	if err := body.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	shop, err := config.DB.Shops().Get(config.Ctx, shopId)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
 	}
 
-	order.ID = snap.Ref.ID
+	item, res, err := services.SetMenuStock(config.Ctx, shop, menuId, body, time.Now())
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "menu item not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update stock", "msg": err.Error()})
+	}
+	services.AfterStockChange(config.Ctx, res)
+	return c.JSON(fiber.Map{"item": item})
+}
 
-	return c.JSON(fiber.Map{"order": order})
+// GET /shop/:id/menu/low-stock  เมนูที่ใกล้หมด/หมดแล้ว (หน้าจอร้าน)
+func ListLowStock(c *fiber.Ctx) error {
+	shop, err := config.DB.Shops().Get(config.Ctx, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+	}
+	items, err := services.LowStockMenus(config.Ctx, shop, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to list stock", "msg": err.Error()})
+	}
+	return c.JSON(fiber.Map{"items": items, "count": len(items)})
 }
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/middlewares"
+	services "github.com/PPEACH21/MoblieApp_MeebleProject/service"
+)
+
+// ส่ง comment กันสาย idle ถูกตัด (proxy / mobile network)
+const streamHeartbeat = 15 * time.Second
+
+// GET /shop/:shopId/orders/stream  (SSE) — ออเดอร์ใหม่/เปลี่ยนสถานะของร้าน
+func StreamShopOrders(c *fiber.Ctx) error {
+	return streamOrders(c, services.ShopTopic(c.Params("shopId")))
+}
+
+// GET /userOrders/stream  (SSE) — ออเดอร์ของ user ที่ login
+func StreamUserOrders(c *fiber.Ctx) error {
+	uid := middlewares.UserID(c)
+	if uid == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	return streamOrders(c, services.UserTopic(uid))
+}
+
+// streamOrders ส่ง event แบบ text/event-stream
+// resume: header Last-Event-ID (EventSource ส่งให้เองตอนต่อใหม่) หรือ ?lastEventId=
+// ถ้า resume ไม่ได้ครบจะได้ event "resync" ก่อน → client ควรโหลด GET list ใหม่
+// token หมดอายุหรือ session ถูก revoke จะได้ event "unauthorized" แล้วสายถูกปิด → refresh token ก่อนต่อใหม่
+func streamOrders(c *fiber.Ctx, topic string) error {
+	lastRaw := strings.TrimSpace(c.Get("Last-Event-ID"))
+	if lastRaw == "" {
+		lastRaw = c.Query("lastEventId")
+	}
+	var lastID uint64
+	if lastRaw != "" {
+		v, err := strconv.ParseUint(lastRaw, 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid Last-Event-ID"})
+		}
+		lastID = v
+	}
+
+	// c ใช้ใน stream writer ไม่ได้ (fasthttp คืน ctx ไปแล้ว) เก็บค่าที่ต้องตรวจซ้ำไว้ก่อน
+	sid, uid, exp := middlewares.SessionID(c), middlewares.UserID(c), middlewares.TokenExpiry(c)
+	sub := services.OrderEvents.Subscribe(topic, lastID)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		fmt.Fprint(w, "retry: 3000\n\n")
+		if sub.Resync {
+			fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+		}
+		for _, e := range sub.Backlog {
+			writeOrderEvent(w, e)
+		}
+		if w.Flush() != nil {
+			return
+		}
+
+		tick := time.NewTicker(streamHeartbeat)
+		defer tick.Stop()
+		for {
+			select {
+			case e, ok := <-sub.C:
+				if !ok {
+					return // อ่านไม่ทันจน hub ตัดทิ้ง → client ต่อใหม่ด้วย Last-Event-ID
+				}
+				writeOrderEvent(w, e)
+			case <-tick.C:
+				// token หมดอายุ / session ถูก revoke ระหว่างที่ stream เปิดอยู่ → ปิด ให้ client refresh token แล้วต่อใหม่
+				if !streamAuthorized(sid, uid, exp) {
+					fmt.Fprint(w, "event: unauthorized\ndata: {}\n\n")
+					w.Flush()
+					return
+				}
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			// เขียนไม่ได้ = client ปิดการเชื่อมต่อแล้ว
+			if w.Flush() != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// streamAuthorized ตรวจ token/session ของ stream ซ้ำ (error จาก store ไม่ตัดสาย รอบถัดไปตรวจใหม่)
+func streamAuthorized(sid, uid string, exp time.Time) bool {
+	if !exp.IsZero() && time.Now().After(exp) {
+		return false
+	}
+	ok, err := services.SessionActive(config.Ctx, sid, uid)
+	return ok || err != nil
+}
+
+func writeOrderEvent(w *bufio.Writer, e services.OrderEvent) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/middlewares"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	services "github.com/PPEACH21/MoblieApp_MeebleProject/service"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// ยอดเติมเงินสูงสุดต่อครั้ง
+const maxTopUp = 100000
+
+// GET /wallet  ยอดคงเหลือ + ตรวจกับผลรวม ledger
+func GetWallet(c *fiber.Ctx) error {
+	userID := middlewares.UserID(c)
+	if userID == "" {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	acct, err := config.DB.Accounts().GetAs(config.Ctx, store.RoleUser, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	sum, count, err := config.DB.Wallet().Sum(config.Ctx, userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// บัญชีเก่าที่ยังไม่มี ledger ถือว่ายอดยกมา = Cost
+	ledger := sum
+	if !acct.WalletOpened && count == 0 {
+		ledger = acct.Cost
+	}
+
+	return c.JSON(fiber.Map{
+		"userId":         userID,
+		"balance":        acct.Cost,
+		"ledger_balance": ledger,
+		"in_sync":        math.Abs(ledger-acct.Cost) < 0.005,
+		"entries":        count,
+	})
+}
+
+// POST /wallet/topup  { "amount": 500 }
+func TopUpWallet(c *fiber.Ctx) error {
+	userID := middlewares.UserID(c)
+	if userID == "" {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var body models.TopUpRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+	if body.Amount <= 0 || body.Amount > maxTopUp {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "amount must be between 1 and 100000"})
+	}
+
+	var txn *models.WalletTxn
+	err := config.DB.RunTransaction(config.Ctx, func(ctx context.Context, tx store.Repos) error {
+		var err error
+		txn, err = services.ApplyWallet(ctx, tx, userID, services.WalletEntry{
+			Type:    models.WalletTopUp,
+			Amount:  float64(body.Amount),
+			RefType: models.WalletRefTopUp,
+			Note:    "top-up",
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "top-up failed", "msg": err.Error()})
+	}
+
+	return c.Status(http.StatusCreated).JSON(fiber.Map{
+		"transaction": txn,
+		"balance":     txn.BalanceAfter,
+	})
+}
+
+// GET /wallet/transactions?limit=20&type=debit&startAfter=<RFC3339>
+func ListWalletTransactions(c *fiber.Ctx) error {
+	userID := middlewares.UserID(c)
+	if userID == "" {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	q := store.WalletQuery{
+		Type:  c.Query("type", ""),
+		Limit: toLimit(c.Query("limit"), 20),
+	}
+	if s := c.Query("startAfter", ""); s != "" {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "startAfter must be RFC3339"})
+		}
+		q.StartAfter = t
+	}
+
+	items, err := config.DB.Wallet().List(config.Ctx, userID, q)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	resp := fiber.Map{
+		"userId":       userID,
+		"transactions": items,
+	}
+	if len(items) == q.Limit {
+		// cursor คือ createdAt ของรายการสุดท้าย
+		resp["nextStartAfter"] = items[len(items)-1].CreatedAt.Format(time.RFC3339Nano)
+	}
+	return c.JSON(resp)
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/controllers"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/middlewares"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/routes"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/service"
@@ -17,10 +19,45 @@ import (
 
 func main() {
 	godotenv.Load("config/.env")
-	app := fiber.New()
+	// Immutable: ค่าจาก c.Params/c.Query ถูกเก็บต่อใน store (memory) ได้ ไม่โดน buffer ของ fasthttp ทับ
+	app := fiber.New(fiber.Config{Immutable: true})
 
-	config.InitFirebase()
-	defer config.Client.Close()
+	config.InitStore(os.Getenv("STORE_DRIVER"))
+	defer config.DB.Close()
+
+	// ร้านเก่าที่ยังไม่มี geohash / order_count (ค้นร้านใกล้ ๆ, GET /shops)
+	go func() {
+		if n, err := service.BackfillShopIndex(config.Ctx); err != nil {
+			log.Println("shop index backfill:", err)
+		} else if n > 0 {
+			log.Printf("shop index backfill: updated %d shops", n)
+		}
+	}()
+	// full-text search ของร้าน/เมนู (in-process)
+	go func() {
+		if n, err := service.RebuildSearchIndex(config.Ctx); err != nil {
+			log.Println("search index:", err)
+		} else {
+			log.Printf("search index: %d documents", n)
+		}
+	}()
+
+	// เปิด/ปิดร้านอัตโนมัติตามเวลาเปิด-ปิด (PUT /shop/:id/hours)
+	go service.StartShopScheduler(config.Ctx, time.Minute)
+	// ลบร้านที่ถูก soft delete และครบกำหนดกู้คืนแล้ว (SHOP_RESTORE_DAYS)
+	go service.StartShopPurger(config.Ctx, time.Hour)
+	// รีเซ็ตสต็อกรายวันเมื่อร้านขึ้นวันใหม่ (ตามเวลาท้องถิ่นของร้าน)
+	go service.StartStockResetter(config.Ctx, 5*time.Minute)
+	// คำนวณช่วงราคาร้านใหม่เมื่อเมนูเข้า/ออกช่วงขาย (เช่นเมนูอาหารเช้า)
+	go service.StartMenuWindows(config.Ctx, time.Minute)
+	// ลบ topic ของ SSE ที่ไม่มีคนฟังและ backlog หมดอายุแล้ว
+	go service.StartOrderEventSweeper(config.Ctx, time.Minute)
+	// ลบ Idempotency-Key ที่หมดอายุแล้ว
+	go service.StartIdempotencyPurger(config.Ctx, time.Hour)
+	// ปิดบัตรคิว walk-in ที่ค้างจากวันก่อน (ตามเวลาท้องถิ่นของร้าน)
+	go service.StartQueueExpirer(config.Ctx, 5*time.Minute)
+	// ปิดการจองที่เลยเวลาแล้วแต่ร้านไม่ได้ปิด (confirmed -> no_show, seated -> completed)
+	go service.StartReservationCloser(config.Ctx, 5*time.Minute)
 
 	config.ConnectMailer(
 		os.Getenv("MAILER_HOST"),
@@ -44,6 +81,10 @@ func main() {
 	app.Post("/sendotp_repassword", service.OTPrepassword())
 	app.Put("/changepassword", service.ChangePassword)
 	app.Post("/checkotp", service.MathOTP)
+	app.Post("/auth/refresh", controllers.RefreshToken)
+	// feed ปฏิทิน: แอปปฏิทินส่ง JWT ไม่ได้ ใช้ ?token= ของ feed แทน
+	app.Get("/shop/:id/reservations.ics", controllers.ShopReservationsICS)
+	app.Get("/users/:userId/reservations.ics", controllers.UserReservationsICS)
 	app.Use(middlewares.ProtectedAuth())
 	routes.Routes(app)
 
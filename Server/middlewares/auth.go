@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/service"
 	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
@@ -15,9 +16,28 @@ func ProtectedAuth() fiber.Handler {
 		TokenLookup:  "header:Authorization",
 		AuthScheme:   "Bearer",
 		ErrorHandler: jwtError,
+		// token ต้องผูกกับ session ที่ยังไม่ถูก revoke (logout / ลบอุปกรณ์)
+		SuccessHandler: requireActiveSession,
 	})
 }
 
+func requireActiveSession(c *fiber.Ctx) error {
+	sid := SessionID(c)
+	if sid == "" {
+		return jwtError(c, nil)
+	}
+	ok, err := service.SessionActive(config.Ctx, sid, UserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Session revoked",
+		})
+	}
+	return c.Next()
+}
+
 func Profile(c *fiber.Ctx) error {
 	user := c.Locals("user").(*jwt.Token)
 	claims := user.Claims.(jwt.MapClaims)
@@ -25,28 +45,25 @@ func Profile(c *fiber.Ctx) error {
 	role := claims["role"].(string)
 
 	// fmt.Println("user:", user)
-	doc, err := config.User.Doc(userID).Get(config.Ctx)
+	// หาใน users ก่อน แล้วค่อย vendors
+	account, err := config.DB.Accounts().Get(config.Ctx, userID)
 	if err != nil {
-		doc, err = config.Vendor.Doc(userID).Get(config.Ctx)
-		if err != nil {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
-		}
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
 	}
-	data := doc.Data()
 
 	return c.JSON(fiber.Map{
 		"user_id":   userID,
-		"email":     data["email"],
-		"username":  data["username"],
-		"firstname": data["firstname"],
-		"lastname":  data["lastname"],
-		"avatar":    data["avatar"],
-		"phone":     data["phone"],
-		"coin":      data["Cost"],
+		"email":     account.Email,
+		"username":  account.Username,
+		"firstname": account.Firstname,
+		"lastname":  account.Lastname,
+		"avatar":    account.Avatar,
+		"phone":     account.Phone,
+		"coin":      account.Cost,
 		"role":      role,
-		"verified":  data["verified"],
+		"verified":  account.Verified,
 	})
 }
 
@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"errors"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// claim อ่านค่า string จาก JWT claims ที่ ProtectedAuth ใส่ไว้ใน c.Locals("user")
+func claim(c *fiber.Ctx, key string) string {
+	tok, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return ""
+	}
+	claims, ok := tok.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	v, _ := claims[key].(string)
+	return v
+}
+
+// UserID คือ user_id ใน token (ใช้แทน id ที่ส่งมาใน body/query)
+func UserID(c *fiber.Ctx) string { return claim(c, "user_id") }
+
+// Role คือ role ใน token ("user" หรือ "vendor")
+func Role(c *fiber.Ctx) string { return claim(c, "role") }
+
+// SessionID คือ sid ใน token (session ที่ออก token นี้)
+func SessionID(c *fiber.Ctx) string { return claim(c, "sid") }
+
+// TokenExpiry คือเวลาหมดอายุ (exp) ของ token (zero = ไม่มี exp)
+func TokenExpiry(c *fiber.Ctx) time.Time {
+	tok, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return time.Time{}
+	}
+	exp, err := tok.Claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Time{}
+	}
+	return exp.Time
+}
+
+// IsVendor บอกว่า token เป็นของร้าน
+func IsVendor(c *fiber.Ctx) bool { return Role(c) == store.RoleVendor }
+
+func forbidden(c *fiber.Ctx, msg string) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": msg})
+}
+
+// RequireRole อนุญาตเฉพาะ role ที่กำหนด
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role := Role(c)
+		for _, r := range roles {
+			if r == role {
+				return c.Next()
+			}
+		}
+		return forbidden(c, "Forbidden")
+	}
+}
+
+// RequireSelf ให้ path param (เช่น :userId) ต้องตรงกับ user_id ใน token
+func RequireSelf(param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if uid := UserID(c); uid == "" || c.Params(param) != uid {
+			return forbidden(c, "Forbidden")
+		}
+		return c.Next()
+	}
+}
+
+// RequireShopOwner ให้เฉพาะ vendor ที่เป็นเจ้าของร้าน (shops/{param}.vendor_id) ผ่าน
+// ร้านที่โหลดมาจะถูกเก็บไว้ใน c.Locals("shop") ให้ handler ใช้ต่อ
+func RequireShopOwner(param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !IsVendor(c) {
+			return forbidden(c, "vendor only")
+		}
+		shop, err := config.DB.Shops().Get(config.Ctx, c.Params(param))
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "shop not found"})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !OwnsShop(c, shop) {
+			return forbidden(c, "not your shop")
+		}
+		c.Locals("shop", shop)
+		return c.Next()
+	}
+}
+
+// RequireLiveShop ใช้ต่อจาก RequireShopOwner: ร้านที่ถูก soft delete แก้ไขไม่ได้จนกว่าจะกู้คืน
+func RequireLiveShop() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if shop, ok := c.Locals("shop").(*models.Shop); ok && shop.IsDeleted() {
+			return c.Status(fiber.StatusGone).JSON(fiber.Map{
+				"error":       "shop is deleted, restore it first",
+				"purge_after": shop.PurgeAfter,
+			})
+		}
+		return c.Next()
+	}
+}
+
+// OwnsShop ตรวจว่า token เป็น vendor เจ้าของร้านนี้
+func OwnsShop(c *fiber.Ctx, shop *models.Shop) bool {
+	return IsVendor(c) && shop != nil && shop.VendorID != "" && shop.VendorID == UserID(c)
+}
@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Idempotent ทำให้ request ที่มี header Idempotency-Key รันจริงได้ครั้งเดียวต่อ user + key
+//   - retry ด้วย key + body เดิม → ได้ response เดิม (header Idempotent-Replayed: true) โดยไม่รัน handler ซ้ำ
+//   - key เดิมแต่ body ต่าง → 422
+//   - request แรกยังไม่จบ → 409
+//
+// response ที่ถูกเก็บไว้ตอบซ้ำคือ response 2xx แรกเท่านั้น (เก็บ IdempotencyTTL แล้ว purge ทิ้ง)
+// request ที่ไม่สำเร็จ (4xx / 5xx) ไม่ถูกเก็บ: key ถูกปล่อยทันที retry ด้วย key เดิมจะรัน handler ใหม่
+// และได้ผลใหม่ (เช่นหลังเติมเงิน หรือยอมรับราคาใหม่) ไม่ใช่ error เดิม
+// ไม่มี header = ทำงานแบบเดิม
+func Idempotent() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := strings.TrimSpace(c.Get("Idempotency-Key"))
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > service.MaxIdempotencyKey {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Idempotency-Key too long"})
+		}
+		uid := UserID(c)
+		if uid == "" {
+			return jwtError(c, nil)
+		}
+
+		hash := service.IdempotencyRequestHash(c.Method(), c.Path(), c.Body())
+		rec, err := service.BeginIdempotent(config.Ctx, uid, key, hash)
+		switch {
+		case errors.Is(err, service.ErrIdempotencyMismatch):
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, service.ErrIdempotencyInProgress):
+			c.Set(fiber.HeaderRetryAfter, "1")
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case err != nil:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if rec != nil {
+			c.Set("Idempotent-Replayed", "true")
+			if rec.ResponseType != "" {
+				c.Set(fiber.HeaderContentType, rec.ResponseType)
+			}
+			return c.Status(rec.ResponseCode).Send(rec.ResponseBody)
+		}
+
+		if err := c.Next(); err != nil {
+			releaseIdempotent(uid, key)
+			return err
+		}
+
+		code := c.Response().StatusCode()
+		if code < 200 || code >= 300 {
+			releaseIdempotent(uid, key)
+			return nil
+		}
+		ctype := string(c.Response().Header.ContentType())
+		if err := service.CompleteIdempotent(config.Ctx, uid, key, hash, code, ctype, c.Response().Body()); err != nil {
+			// response ไปถึง client แล้ว; key จะค้างเป็น in_progress จนหมดเวลาจอง
+			log.Println("idempotency: store response:", err)
+		}
+		return nil
+	}
+}
+
+func releaseIdempotent(uid, key string) {
+	if err := service.ReleaseIdempotent(config.Ctx, uid, key); err != nil {
+		log.Println("idempotency: release key:", err)
+	}
+}
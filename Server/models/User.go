@@ -3,6 +3,7 @@ package models
 import "time"
 
 type User = struct {
+	ID        string    `json:"id,omitempty" firestore:"id,omitempty"`
 	Email     string    `json:"email" firestore:"email"`
 	Avatar    string    `json:"avatar" firestore:"avatar"`
 	Firstname string    `json:"firstname" firestore:"firstname"`
@@ -12,8 +13,11 @@ type User = struct {
 	Password  string    `json:"password" firestore:"password"`
 	Role      string    `json:"role" firestore:"role"`
 	Verified  bool      `json:"verified" firestore:"verified"`
-	Cost      int       `json:"cost" firestore:"cost"`
+	Cost      float64   `json:"cost" firestore:"Cost"` // ยอดเงินคงเหลือ (cache ของ wallet ledger)
 	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+
+	// WalletOpened = มีรายการยกยอด Cost เดิมเข้า ledger แล้ว
+	WalletOpened bool `json:"-" firestore:"wallet_opened,omitempty"`
 }
 
 type Select = struct {
@@ -27,6 +31,47 @@ type OTP_Verify struct {
 	Email    string `json:"email" firestore:"email"`
 	Username string `json:"username" firestore:"username"`
 	OTP      string `json:"otp" firestore:"otp"`
+	Purpose  string `json:"purpose,omitempty" firestore:"-"`
+}
+
+// จุดประสงค์ของ OTP (รหัสของจุดประสงค์หนึ่งใช้กับอีกจุดประสงค์ไม่ได้)
+const (
+	OTPVerifyEmail   = "verify_email"
+	OTPResetPassword = "reset_password"
+)
+
+func IsOTPPurpose(p string) bool { return p == OTPVerifyEmail || p == OTPResetPassword }
+
+// OTPTicketPurpose คือ key ของ ticket ที่ออกให้หลังยืนยัน OTP สำเร็จ (เช่น verify_email_ticket)
+// ticket ผูกกับ email + purpose และใช้ได้ครั้งเดียว
+func OTPTicketPurpose(purpose string) string { return purpose + "_ticket" }
+
+// ChangePasswordReq: ต้องมี reset_ticket ที่ได้จาก /checkotp (purpose = reset_password)
+type ChangePasswordReq struct {
+	Email       string `json:"email"`
+	ResetTicket string `json:"reset_ticket"`
+	Password    string `json:"password"`
+}
+
+// VerifyEmailReq: ต้องมี verify_ticket ที่ได้จาก /checkotp (purpose = verify_email) ของ email บัญชีนี้
+type VerifyEmailReq struct {
+	VerifyTicket string `json:"verify_ticket"`
+}
+
+// OTPRecord เก็บที่ otp/{purpose}:{email} — เก็บแค่ hash ของรหัส ไม่เก็บตัวรหัส
+type OTPRecord struct {
+	Email     string    `json:"email" firestore:"email"`
+	Purpose   string    `json:"purpose" firestore:"purpose"`
+	CodeHash  string    `json:"-" firestore:"codeHash"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+	ExpireAt  time.Time `json:"expireAt" firestore:"expireAt"`
+
+	Attempts int  `json:"attempts" firestore:"attempts"` // ใส่ผิดไปแล้วกี่ครั้ง
+	Locked   bool `json:"locked" firestore:"locked"`     // ผิดครบ limit → ต้องขอรหัสใหม่
+
+	// ใช้จำกัดการขอรหัสซ้ำต่อ email
+	SendCount   int       `json:"-" firestore:"sendCount"`
+	WindowStart time.Time `json:"-" firestore:"windowStart"`
 }
 
 type OrderDTO struct {
@@ -45,14 +90,22 @@ type TopUpRequest struct {
 // models/history.go
 
 type HistoryItem struct {
-	ID               string    `json:"id" firestore:"-"` // doc id (== historyId)
-	HistoryID        string    `json:"historyId" firestore:"historyId"`
-	OrderID          string    `json:"orderId" firestore:"orderId"`
-	UserID           string    `json:"userId" firestore:"userId"`
-	ShopID           string    `json:"shopId" firestore:"shopId"`
-	Status           string    `json:"status" firestore:"status"`
-	Total            float64   `json:"total" firestore:"total"`
-	CreatedAt        time.Time `json:"createdAt" firestore:"createdAt"`
-	UpdatedAt        time.Time `json:"updatedAt" firestore:"updatedAt"`
-	MovedToHistoryAt time.Time `json:"movedToHistoryAt" firestore:"movedToHistoryAt"`
+	ID               string      `json:"id" firestore:"-"` // doc id (== historyId)
+	HistoryID        string      `json:"historyId" firestore:"historyId"`
+	OrderID          string      `json:"orderId" firestore:"orderId"`
+	UserID           string      `json:"userId" firestore:"userId"`
+	ShopID           string      `json:"shopId" firestore:"shopId"`
+	ShopName         string      `json:"shop_name,omitempty" firestore:"shop_name,omitempty"`
+	Status           string      `json:"status" firestore:"status"`
+	Total            float64     `json:"total" firestore:"total"`
+	Items            []OrderItem `json:"items,omitempty" firestore:"items,omitempty"`
+	ItemCount        int         `json:"item_count" firestore:"item_count"`
+	CreatedAt        time.Time   `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt        time.Time   `json:"updatedAt" firestore:"updatedAt"`
+	MovedToHistoryAt time.Time   `json:"movedToHistoryAt" firestore:"movedToHistoryAt"`
+
+	// มีเฉพาะออเดอร์ที่ถูกยกเลิก
+	CancelReason string  `json:"cancel_reason,omitempty" firestore:"cancelReason,omitempty"`
+	CancelledBy  string  `json:"cancelled_by,omitempty" firestore:"cancelledBy,omitempty"`
+	RefundAmount float64 `json:"refund_amount,omitempty" firestore:"refundAmount,omitempty"`
 }
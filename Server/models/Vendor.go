@@ -51,6 +51,8 @@ type AddToCartRequest struct {
 		Price       float64 `json:"price"`
 		Image       string  `json:"image"`
 		Description string  `json:"description"`
+
+		Options []SelectedOption `json:"options"`
 	} `json:"item"`
 }
 
@@ -59,6 +61,7 @@ type UpdateQtyRequest struct {
 	ShopID     string `json:"shopId"`
 	CustomerID string `json:"customerId"` // ✅ เพิ่มตรงนี้
 	MenuID     string `json:"menuId"`
+	LineID     string `json:"lineId"` // บรรทัดที่ต้องการ (เมนูเดียวกันคนละตัวเลือก); ว่าง = บรรทัดแรกของ menuId
 	Qty        int    `json:"qty"`
 }
 
@@ -79,6 +82,16 @@ type CartItem struct {
 	MenuRef     *firestore.DocumentRef `json:"-" firestore:"menuRef,omitempty"` // ref ไปยังเมนูจริง
 	VendorID    string                 `json:"vendorId,omitempty" firestore:"vendorId,omitempty"`
 	ShopID      string                 `json:"shopId,omitempty"   firestore:"shopId,omitempty"`
+	LineID      string                 `json:"lineId,omitempty" firestore:"lineId,omitempty"` // models.CartLineID
+	Options     []SelectedOption       `json:"options,omitempty" firestore:"options,omitempty"`
+}
+
+// Line คือ key ของบรรทัด (ตะกร้าเก่าที่ยังไม่มี lineId ใช้ menuId)
+func (it CartItem) Line() string {
+	if it.LineID != "" {
+		return it.LineID
+	}
+	return CartLineID(it.ID, it.Options)
 }
 
 type Cart struct {
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// การกระทำที่บันทึกลง audit_logs
+const (
+	AuditShopDelete  = "shop.delete"  // soft delete (ซ่อนร้าน กู้คืนได้)
+	AuditShopRestore = "shop.restore" // กู้คืนก่อนครบกำหนด
+	AuditShopPurge   = "shop.purge"   // ลบถาวรพร้อมข้อมูลที่ผูกกับร้าน
+)
+
+// AuditRecord คือบันทึกการกระทำที่ย้อนกลับไม่ได้/ต้องตรวจสอบภายหลัง (append-only)
+// ActorID ว่าง = ระบบ (เช่น purge job)
+type AuditRecord struct {
+	ID         string         `json:"id" firestore:"-"`
+	Action     string         `json:"action" firestore:"action"`
+	TargetType string         `json:"target_type" firestore:"target_type"`
+	TargetID   string         `json:"target_id" firestore:"target_id"`
+	ActorID    string         `json:"actor_id,omitempty" firestore:"actor_id,omitempty"`
+	Details    map[string]any `json:"details,omitempty" firestore:"details,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt" firestore:"createdAt"`
+}
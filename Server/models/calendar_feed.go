@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ชนิดของ feed ปฏิทิน (.ics)
+const (
+	CalendarFeedShop = "shop" // GET /shop/:id/reservations.ics
+	CalendarFeedUser = "user" // GET /users/:userId/reservations.ics
+)
+
+const ColCalendarFeeds = "calendar_feeds" // {kind}_{ownerId}: หนึ่ง token ต่อ feed
+
+// CalendarFeed คือ token ของ feed ปฏิทินหนึ่งตัว (แอปปฏิทินส่ง JWT ไม่ได้ จึงใช้ ?token= ใน URL)
+// เก็บแค่ hash ของ token; ออกใหม่ = token เดิมใช้ไม่ได้ทันที, ลบ = ปิด feed
+type CalendarFeed struct {
+	Kind      string    `json:"kind" firestore:"kind"`
+	OwnerID   string    `json:"owner_id" firestore:"owner_id"`
+	TokenHash string    `json:"-" firestore:"token_hash"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+}
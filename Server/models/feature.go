@@ -7,26 +7,39 @@ type OrderItem struct {
 	Name        string  `json:"name" firestore:"name"`
 	Image       string  `json:"image,omitempty" firestore:"image,omitempty"`
 	Description string  `json:"description,omitempty" firestore:"description,omitempty"`
-	Price       float64 `json:"price" firestore:"price"`
+	Price       float64 `json:"price" firestore:"price"` // ราคาต่อชิ้นรวมตัวเลือกแล้ว
 	Qty         int     `json:"qty" firestore:"qty"`
-	Extras      any     `json:"extras,omitempty" firestore:"extras,omitempty"`
+	Extras      any     `json:"extras,omitempty" firestore:"extras,omitempty"` // ข้อมูลเก่า อ่านอย่างเดียว
+
+	Options   []SelectedOption `json:"options,omitempty" firestore:"options,omitempty"`
+	BasePrice float64          `json:"base_price,omitempty" firestore:"basePrice,omitempty"` // ราคาเมนูก่อนบวกตัวเลือก
 }
 
 type Order struct {
 	ID         string      `json:"id" firestore:"-"` // Firestore DocID
 	ShopID     string      `json:"shop_id" firestore:"shopId"`
 	CustomerID string      `json:"customer_id" firestore:"customerId"`
+	UserID     string      `json:"user_id,omitempty" firestore:"userId,omitempty"` // doc id ของ users (checkout)
 	Status     string      `json:"status" firestore:"status"`
 	Items      []OrderItem `json:"items" firestore:"items"`
 	Note       string      `json:"note,omitempty" firestore:"note,omitempty"`
 
-	Total     float64   `json:"total" firestore:"total"`
+	Total      float64 `json:"total" firestore:"total"`
+	PaidAmount float64 `json:"paid_amount,omitempty" firestore:"paidAmount,omitempty"` // ยอดที่ตัดจาก wallet แล้ว
+
 	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
 
 	// optional display only (ไม่บังคับ)
 	CustomerName string `json:"customer_name,omitempty" firestore:"customer_name,omitempty"`
 	ShopName     string `json:"shop_name,omitempty" firestore:"shop_name,omitempty"`
+
+	// มีเฉพาะออเดอร์ที่ถูกยกเลิก
+	CancelReason string `json:"cancel_reason,omitempty" firestore:"cancelReason,omitempty"`
+	CancelledBy  string `json:"cancelled_by,omitempty" firestore:"cancelledBy,omitempty"`
+
+	// สต็อกที่ออเดอร์นี้ตัดไป: menuId -> วันของยอด daily ("" = absolute) ใช้คืนสต็อกตอนยกเลิก
+	StockHeld map[string]string `json:"-" firestore:"stockHeld,omitempty"`
 }
 
 type CreateOrderReq struct {
@@ -35,10 +48,18 @@ type CreateOrderReq struct {
 	Items        []OrderItem `json:"items"`
 	Note         string      `json:"note"`
 	CustomerName string      `json:"customer_name,omitempty"`
+
+	// ต้องส่ง true เมื่อยอมรับราคาใหม่ (หลังได้ 409 PRICE_CHANGED)
+	AcceptPriceChanges bool `json:"accept_price_changes,omitempty"`
 }
 
 type UpdateOrderStatusReq struct {
 	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"` // ใช้เมื่อ status = cancelled
+}
+
+type CancelOrderReq struct {
+	Reason string `json:"reason"`
 }
 
 const (
@@ -66,15 +87,38 @@ type Reservation struct {
 	Phone     string    `json:"phone" firestore:"phone"`
 	People    int       `json:"people" firestore:"people"`
 	Note      string    `json:"note,omitempty" firestore:"note,omitempty"`
-	DayKey    string    `json:"dayKey" firestore:"dayKey"`
+	DayKey    string    `json:"dayKey" firestore:"dayKey"` // วันของ SlotStart (หรือวันที่จอง) ตามเวลาท้องถิ่นของร้าน
 	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+
+	// ช่วงเวลาที่จอง (ร้านที่ตั้งค่า ReservationSettings) และโต๊ะที่ได้ (โหมดโต๊ะ)
+	SlotStart *time.Time `json:"slot_start,omitempty" firestore:"slot_start,omitempty"`
+	SlotEnd   *time.Time `json:"slot_end,omitempty" firestore:"slot_end,omitempty"`
+	TableID   string     `json:"table_id,omitempty" firestore:"table_id,omitempty"`
+
+	// สถานะ (ดู reservation_lifecycle.go) และประวัติการเปลี่ยนทั้งหมด
+	Status       string                    `json:"status,omitempty" firestore:"status,omitempty"`
+	CancelReason string                    `json:"cancel_reason,omitempty" firestore:"cancel_reason,omitempty"`
+	History      []ReservationStatusChange `json:"history,omitempty" firestore:"history,omitempty"`
+	UpdatedAt    time.Time                 `json:"updatedAt" firestore:"updatedAt"`
+
+	// มัดจำที่ตัดจาก wallet ตอนจอง (ดู reservation_deposit.go)
+	Deposit         float64    `json:"deposit,omitempty" firestore:"deposit,omitempty"`
+	DepositStatus   string     `json:"deposit_status,omitempty" firestore:"deposit_status,omitempty"`
+	DepositRefundBy *time.Time `json:"deposit_refund_by,omitempty" firestore:"deposit_refund_by,omitempty"`
 }
 
+// CreateReservationReq คือ body ของ POST /shops/:id/reservations
+// date + time (YYYY-MM-DD, HH:MM ตามเวลาท้องถิ่นของร้าน) จำเป็นเมื่อร้านตั้งค่า slot ไว้
 type CreateReservationReq struct {
 	UserID string `json:"user_id"`
 	People int    `json:"people"`
 	Phone  string `json:"phone,omitempty"`
 	Note   string `json:"note,omitempty"`
+	Date   string `json:"date,omitempty"`
+	Time   string `json:"time,omitempty"`
 }
 
-const ColReservations = "reservations"
+const (
+	ColReservations    = "reservations"
+	ColReservationDays = "reservation_days" // {shopId}_{dayKey}: ใช้ lock การจองของร้านในวันนั้น
+)
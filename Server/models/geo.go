@@ -0,0 +1,108 @@
+package models
+
+import (
+	"math"
+	"strings"
+)
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// GeohashPrecision คือความยาว geohash ที่เก็บในเอกสารร้าน (ค้นหาด้วย prefix ที่สั้นกว่าได้)
+const GeohashPrecision = 9
+
+const earthRadiusKm = 6371.0
+
+// EncodeGeohash แปลงพิกัดเป็น geohash ความยาว precision
+func EncodeGeohash(lat, lng float64, precision int) string {
+	latLo, latHi := -90.0, 90.0
+	lngLo, lngHi := -180.0, 180.0
+
+	var sb strings.Builder
+	sb.Grow(precision)
+	bit, ch, even := 0, 0, true
+	for sb.Len() < precision {
+		if even {
+			mid := (lngLo + lngHi) / 2
+			if lng >= mid {
+				ch = ch<<1 | 1
+				lngLo = mid
+			} else {
+				ch <<= 1
+				lngHi = mid
+			}
+		} else {
+			mid := (latLo + latHi) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				latLo = mid
+			} else {
+				ch <<= 1
+				latHi = mid
+			}
+		}
+		even = !even
+		if bit++; bit == 5 {
+			sb.WriteByte(geohashBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return sb.String()
+}
+
+// geohashCellSize คือขนาดช่อง (องศา) ของ geohash ความยาว precision
+func geohashCellSize(precision int) (latDeg, lngDeg float64) {
+	bits := precision * 5
+	lngBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lngBits))
+}
+
+// GeohashCoverPrecision เลือกความยาว prefix ที่ช่องกลาง + 8 ช่องรอบ ๆ ครอบรัศมี radiusKm ได้
+func GeohashCoverPrecision(lat, radiusKm float64) int {
+	for p := GeohashPrecision; p > 1; p-- {
+		latDeg, lngDeg := geohashCellSize(p)
+		hKm := latDeg * math.Pi / 180 * earthRadiusKm
+		wKm := lngDeg * math.Pi / 180 * earthRadiusKm * math.Cos(lat*math.Pi/180)
+		if math.Min(hKm, wKm) >= radiusKm {
+			return p
+		}
+	}
+	return 1
+}
+
+// GeohashCover คืน prefix ของช่องที่มีจุดนี้และ 8 ช่องรอบ ๆ (ไม่ซ้ำ)
+func GeohashCover(lat, lng float64, precision int) []string {
+	latDeg, lngDeg := geohashCellSize(precision)
+	seen := map[string]bool{}
+	out := make([]string, 0, 9)
+	for _, dy := range []float64{0, -1, 1} {
+		for _, dx := range []float64{0, -1, 1} {
+			y := lat + dy*latDeg
+			if y > 90 || y < -90 {
+				continue
+			}
+			x := lng + dx*lngDeg
+			if x >= 180 {
+				x -= 360
+			} else if x < -180 {
+				x += 360
+			}
+			h := EncodeGeohash(y, x, precision)
+			if !seen[h] {
+				seen[h] = true
+				out = append(out, h)
+			}
+		}
+	}
+	return out
+}
+
+// DistanceKm คือระยะทางบนผิวโลก (haversine)
+func DistanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
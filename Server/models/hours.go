@@ -0,0 +1,258 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // ให้ LoadLocation ใช้ได้แม้เครื่องไม่มี zoneinfo
+)
+
+// DefaultShopTimezone ใช้เมื่อร้านไม่ได้ระบุ timezone
+const DefaultShopTimezone = "Asia/Bangkok"
+
+// Weekdays คือ key ของ OpeningHours.Weekly (index ตรงกับ time.Weekday)
+var Weekdays = [7]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+const maxRangesPerDay = 4
+
+// TimeRange คือช่วงเวลาเปิดหนึ่งช่วงในวันเดียว ("HH:MM", close "24:00" ได้)
+// close <= open = เปิดข้ามเที่ยงคืนไปปิดวันถัดไป
+type TimeRange struct {
+	Open  string `json:"open" firestore:"open"`
+	Close string `json:"close" firestore:"close"`
+}
+
+// HoursException คือวันพิเศษ/วันหยุด (แทนตารางปกติของวันนั้นทั้งวัน)
+type HoursException struct {
+	Date   string      `json:"date" firestore:"date"` // YYYY-MM-DD ตาม timezone ของร้าน
+	Closed bool        `json:"closed" firestore:"closed"`
+	Ranges []TimeRange `json:"ranges,omitempty" firestore:"ranges,omitempty"`
+	Note   string      `json:"note,omitempty" firestore:"note,omitempty"`
+}
+
+// OpeningHours คือเวลาเปิด-ปิดรายสัปดาห์ของร้าน
+// วันที่ไม่มีใน Weekly = ปิดทั้งวัน
+type OpeningHours struct {
+	Timezone   string                 `json:"timezone" firestore:"timezone"`
+	Weekly     map[string][]TimeRange `json:"weekly" firestore:"weekly"`
+	Exceptions []HoursException       `json:"exceptions,omitempty" firestore:"exceptions,omitempty"`
+}
+
+// ShopSchedule คือสถานะที่ scheduler เปิด/ปิดร้านให้ล่าสุด
+// เปลี่ยน status เฉพาะตอนตารางเปลี่ยน (open ↔ closed) ร้านปิดเองกลางวันก็ไม่ถูกเปิดกลับจนถึงรอบเปิดถัดไป
+type ShopSchedule struct {
+	State string    `json:"state" firestore:"state"` // ScheduleOpen | ScheduleClosed
+	At    time.Time `json:"at" firestore:"at"`
+	// ค่า order_active / reserve_active / queue_active ก่อนปิดอัตโนมัติ (คืนให้ตอนเปิดอัตโนมัติ)
+	OrderActive   bool `json:"order_active" firestore:"order_active"`
+	ReserveActive bool `json:"reserve_active" firestore:"reserve_active"`
+	QueueActive   bool `json:"queue_active" firestore:"queue_active"`
+}
+
+const (
+	ScheduleOpen   = "open"
+	ScheduleClosed = "closed"
+)
+
+// SetOpeningHoursReq คือ body ของ PUT /shop/:id/hours (hours = null → เลิกใช้ตารางเวลา)
+type SetOpeningHoursReq struct {
+	Hours *OpeningHours `json:"hours"`
+}
+
+// Interval คือช่วงเวลาเปิดจริง (ใช้ตอนคำนวณ)
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// parseClock แปลง "HH:MM" เป็นนาทีนับจากเที่ยงคืน (allow24 = รับ "24:00")
+func parseClock(s string, allow24 bool) (int, error) {
+	hh, mm, ok := strings.Cut(strings.TrimSpace(s), ":")
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if !ok || len(hh) != 2 || len(mm) != 2 || err1 != nil || err2 != nil || m < 0 || m > 59 || h < 0 {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	if h > 23 && !(allow24 && h == 24 && m == 0) {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	return h*60 + m, nil
+}
+
+// Location คืน timezone ของร้าน (ผิด/ว่าง = DefaultShopTimezone)
+func (h *OpeningHours) Location() *time.Location {
+	if h != nil && h.Timezone != "" {
+		if loc, err := time.LoadLocation(h.Timezone); err == nil {
+			return loc
+		}
+	}
+	loc, err := time.LoadLocation(DefaultShopTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Validate ตรวจและจัดรูปแบบ (key ตัวเล็ก, exceptions เรียงตามวัน)
+func (h *OpeningHours) Validate() error {
+	if h.Timezone == "" {
+		h.Timezone = DefaultShopTimezone
+	}
+	if _, err := time.LoadLocation(h.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", h.Timezone)
+	}
+
+	weekly := make(map[string][]TimeRange, len(h.Weekly))
+	for day, ranges := range h.Weekly {
+		key := strings.ToLower(strings.TrimSpace(day))
+		if len(key) > 3 {
+			key = key[:3]
+		}
+		if weekdayIndex(key) < 0 {
+			return fmt.Errorf("unknown weekday %q", day)
+		}
+		if err := validateRanges(ranges); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if len(ranges) > 0 {
+			weekly[key] = append(weekly[key], ranges...)
+		}
+	}
+	h.Weekly = weekly
+
+	seen := map[string]bool{}
+	for i := range h.Exceptions {
+		ex := &h.Exceptions[i]
+		if _, err := time.Parse("2006-01-02", ex.Date); err != nil {
+			return fmt.Errorf("exception date %q must be YYYY-MM-DD", ex.Date)
+		}
+		if seen[ex.Date] {
+			return fmt.Errorf("duplicate exception date %s", ex.Date)
+		}
+		seen[ex.Date] = true
+		if ex.Closed {
+			ex.Ranges = nil
+		} else if len(ex.Ranges) == 0 {
+			return fmt.Errorf("exception %s: ranges required unless closed", ex.Date)
+		}
+		if err := validateRanges(ex.Ranges); err != nil {
+			return fmt.Errorf("exception %s: %w", ex.Date, err)
+		}
+	}
+	sort.Slice(h.Exceptions, func(i, j int) bool { return h.Exceptions[i].Date < h.Exceptions[j].Date })
+	return nil
+}
+
+func validateRanges(ranges []TimeRange) error {
+	if len(ranges) > maxRangesPerDay {
+		return fmt.Errorf("at most %d ranges per day", maxRangesPerDay)
+	}
+	for _, r := range ranges {
+		if _, err := parseClock(r.Open, false); err != nil {
+			return err
+		}
+		if _, err := parseClock(r.Close, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func weekdayIndex(key string) int {
+	for i, d := range Weekdays {
+		if d == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// rangesOn คืนช่วงเวลาของวันที่ (ตาม timezone ของร้าน) — exception มาก่อนตารางปกติ
+func (h *OpeningHours) rangesOn(day time.Time) []TimeRange {
+	date := day.Format("2006-01-02")
+	for _, ex := range h.Exceptions {
+		if ex.Date == date {
+			if ex.Closed {
+				return nil
+			}
+			return ex.Ranges
+		}
+	}
+	return h.Weekly[Weekdays[day.Weekday()]]
+}
+
+// Intervals คืนช่วงเปิดจริงที่ "เริ่ม" ในวันที่ from ถึง to (รวม) เรียงและรวมช่วงที่ต่อกันแล้ว
+func (h *OpeningHours) Intervals(from, to time.Time) []Interval {
+	loc := h.Location()
+	from, to = from.In(loc), to.In(loc)
+	var out []Interval
+	for d := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc); !d.After(to); d = d.AddDate(0, 0, 1) {
+		for _, r := range h.rangesOn(d) {
+			o, err1 := parseClock(r.Open, false)
+			c, err2 := parseClock(r.Close, true)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			start := time.Date(d.Year(), d.Month(), d.Day(), o/60, o%60, 0, 0, loc)
+			end := time.Date(d.Year(), d.Month(), d.Day(), c/60, c%60, 0, 0, loc)
+			if c <= o {
+				end = end.AddDate(0, 0, 1) // ข้ามเที่ยงคืน
+			}
+			out = append(out, Interval{Start: start, End: end})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	merged := out[:0]
+	for _, iv := range out {
+		if n := len(merged); n > 0 && !iv.Start.After(merged[n-1].End) {
+			if iv.End.After(merged[n-1].End) {
+				merged[n-1].End = iv.End
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+// IsOpen บอกว่าเวลา t อยู่ในช่วงเปิดหรือไม่
+func (h *OpeningHours) IsOpen(t time.Time) bool {
+	for _, iv := range h.Intervals(t.AddDate(0, 0, -1), t) {
+		if !t.Before(iv.Start) && t.Before(iv.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextChange คืนเวลาที่สถานะเปิด/ปิดจะเปลี่ยนครั้งถัดไป (ดูล่วงหน้า 2 สัปดาห์)
+// open = สถานะหลังเปลี่ยน; ok = false ถ้าไม่มีการเปลี่ยนในช่วงนั้น
+func (h *OpeningHours) NextChange(t time.Time) (at time.Time, open bool, ok bool) {
+	for _, iv := range h.Intervals(t.AddDate(0, 0, -1), t.AddDate(0, 0, 14)) {
+		if !t.Before(iv.Start) && t.Before(iv.End) {
+			return iv.End, false, true
+		}
+		if iv.Start.After(t) {
+			return iv.Start, true, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+// Clone คัดลอกแบบ deep (store ใน memory ไม่แชร์ map/slice กับผู้เรียก)
+func (h *OpeningHours) Clone() *OpeningHours {
+	if h == nil {
+		return nil
+	}
+	c := &OpeningHours{Timezone: h.Timezone, Weekly: make(map[string][]TimeRange, len(h.Weekly))}
+	for k, v := range h.Weekly {
+		c.Weekly[k] = append([]TimeRange(nil), v...)
+	}
+	for _, ex := range h.Exceptions {
+		ex.Ranges = append([]TimeRange(nil), ex.Ranges...)
+		c.Exceptions = append(c.Exceptions, ex)
+	}
+	return c
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoc(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// ตารางทดสอบ: จันทร์เปิดสองช่วง (ช่วงเย็นข้ามเที่ยงคืน), อังคาร 2026-10-20 หยุด, พุธ 2026-10-21 เปิดพิเศษ
+func testHours() *OpeningHours {
+	return &OpeningHours{
+		Timezone: "Asia/Bangkok",
+		Weekly: map[string][]TimeRange{
+			"mon": {{Open: "10:00", Close: "14:00"}, {Open: "17:00", Close: "02:00"}},
+			"tue": {{Open: "09:00", Close: "18:00"}},
+			"wed": {{Open: "09:00", Close: "18:00"}},
+			"sun": {{Open: "00:00", Close: "24:00"}},
+		},
+		Exceptions: []HoursException{
+			{Date: "2026-10-20", Closed: true},
+			{Date: "2026-10-21", Ranges: []TimeRange{{Open: "12:00", Close: "13:00"}}},
+		},
+	}
+}
+
+func TestOpeningHoursIsOpen(t *testing.T) {
+	bkk := mustLoc(t, "Asia/Bangkok")
+	at := func(day, hh, mm int) time.Time { return time.Date(2026, 10, day, hh, mm, 0, 0, bkk) }
+	h := testHours()
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"monday before open", at(19, 9, 59), false},
+		{"monday open boundary", at(19, 10, 0), true},
+		{"monday close boundary is closed", at(19, 14, 0), false},
+		{"monday gap", at(19, 15, 30), false},
+		{"monday evening", at(19, 23, 0), true},
+		{"overnight after midnight", at(20, 1, 30), true}, // ช่วงของวันจันทร์ ไม่โดน exception วันอังคาร
+		{"overnight close", at(20, 2, 0), false},
+		{"closed exception", at(20, 12, 0), false},
+		{"special hours open", at(21, 12, 30), true},
+		{"special hours replace weekly", at(21, 10, 0), false},
+		{"day without ranges", at(22, 12, 0), false},
+		{"open all day (24:00)", at(25, 23, 59), true},
+		{"same instant in UTC", at(19, 23, 0).UTC(), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.IsOpen(tt.at); got != tt.want {
+				t.Fatalf("IsOpen(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpeningHoursTimezone(t *testing.T) {
+	h := &OpeningHours{
+		Timezone: "Asia/Tokyo",
+		Weekly:   map[string][]TimeRange{"mon": {{Open: "09:00", Close: "17:00"}}},
+	}
+	// 2026-10-19 09:30 ที่โตเกียว = 00:30 UTC = 07:30 ที่กรุงเทพ
+	utc := time.Date(2026, 10, 19, 0, 30, 0, 0, time.UTC)
+	if !h.IsOpen(utc) {
+		t.Fatalf("want open at %s (09:30 Tokyo)", utc)
+	}
+	if h.IsOpen(utc.Add(-time.Hour)) {
+		t.Fatalf("want closed at %s (08:30 Tokyo)", utc.Add(-time.Hour))
+	}
+
+	var unset *OpeningHours
+	if got := unset.Location().String(); got != DefaultShopTimezone {
+		t.Fatalf("nil hours location = %s, want %s", got, DefaultShopTimezone)
+	}
+	if got := (&OpeningHours{Timezone: "Mars/Base"}).Location().String(); got != DefaultShopTimezone {
+		t.Fatalf("bad timezone location = %s, want %s", got, DefaultShopTimezone)
+	}
+}
+
+func TestOpeningHoursIntervals(t *testing.T) {
+	bkk := mustLoc(t, "Asia/Bangkok")
+	at := func(day, hh, mm int) time.Time { return time.Date(2026, 10, day, hh, mm, 0, 0, bkk) }
+
+	tests := []struct {
+		name     string
+		hours    *OpeningHours
+		from, to time.Time
+		want     []Interval
+	}{
+		{
+			name:  "overnight range ends next day",
+			hours: testHours(),
+			from:  at(19, 0, 0), to: at(19, 0, 0),
+			want: []Interval{
+				{Start: at(19, 10, 0), End: at(19, 14, 0)},
+				{Start: at(19, 17, 0), End: at(20, 2, 0)},
+			},
+		},
+		{
+			name:  "exceptions over several days",
+			hours: testHours(),
+			from:  at(20, 0, 0), to: at(21, 0, 0),
+			want: []Interval{
+				{Start: at(21, 12, 0), End: at(21, 13, 0)},
+			},
+		},
+		{
+			name: "touching ranges merge",
+			hours: &OpeningHours{Timezone: "Asia/Bangkok", Weekly: map[string][]TimeRange{
+				"mon": {{Open: "14:00", Close: "16:00"}, {Open: "10:00", Close: "14:00"}},
+			}},
+			from: at(19, 0, 0), to: at(19, 0, 0),
+			want: []Interval{{Start: at(19, 10, 0), End: at(19, 16, 0)}},
+		},
+		{
+			name: "sunday 24h runs into monday",
+			hours: &OpeningHours{Timezone: "Asia/Bangkok", Weekly: map[string][]TimeRange{
+				"sun": {{Open: "00:00", Close: "24:00"}},
+				"mon": {{Open: "00:00", Close: "06:00"}},
+			}},
+			from: at(25, 12, 0), to: at(26, 0, 0),
+			want: []Interval{{Start: at(25, 0, 0), End: at(26, 6, 0)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.hours.Intervals(tt.from, tt.to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d intervals %v, want %v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Start.Equal(tt.want[i].Start) || !got[i].End.Equal(tt.want[i].End) {
+					t.Fatalf("interval %d = %v-%v, want %v-%v", i, got[i].Start, got[i].End, tt.want[i].Start, tt.want[i].End)
+				}
+			}
+		})
+	}
+}
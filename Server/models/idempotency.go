@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// สถานะของ Idempotency-Key
+const (
+	IdempotencyInProgress = "in_progress" // request แรกกำลังรันอยู่
+	IdempotencyDone       = "done"        // มี response เก็บไว้ตอบซ้ำได้
+)
+
+// IdempotencyRecord เก็บที่ idempotency_keys/{IdempotencyDocID(userId, key)}
+// ผูกกับ user + key; RequestHash กันการใช้ key เดิมกับ request คนละตัว
+type IdempotencyRecord struct {
+	UserID       string    `json:"userId" firestore:"userId"`
+	Key          string    `json:"key" firestore:"key"`
+	RequestHash  string    `json:"requestHash" firestore:"requestHash"`
+	Status       string    `json:"status" firestore:"status"`
+	ResponseCode int       `json:"responseCode,omitempty" firestore:"responseCode,omitempty"`
+	ResponseType string    `json:"responseType,omitempty" firestore:"responseType,omitempty"`
+	ResponseBody []byte    `json:"-" firestore:"responseBody,omitempty"`
+	CreatedAt    time.Time `json:"createdAt" firestore:"createdAt"`
+	ExpiresAt    time.Time `json:"expiresAt" firestore:"expiresAt"`
+}
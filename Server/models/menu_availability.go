@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const maxAvailabilityWindows = 10
+
+// AvailabilityWindow คือช่วงที่เมนูขาย เช่น อาหารเช้า 06:00-10:30 หรือเฉพาะ sat/sun
+// Days ว่าง = ทุกวัน; Close <= Open = ข้ามเที่ยงคืน (นับเป็นของวันที่เริ่ม) ใช้ timezone ของร้าน
+type AvailabilityWindow struct {
+	Days  []string `json:"days,omitempty" firestore:"days,omitempty"`
+	Open  string   `json:"open" firestore:"open"`
+	Close string   `json:"close" firestore:"close"`
+}
+
+// ValidateAvailability ตรวจและ normalize ชื่อวัน (เช่น "Monday" → "mon")
+func ValidateAvailability(windows []AvailabilityWindow) error {
+	if len(windows) > maxAvailabilityWindows {
+		return fmt.Errorf("at most %d availability windows", maxAvailabilityWindows)
+	}
+	for i := range windows {
+		w := &windows[i]
+		if err := validateRanges([]TimeRange{{Open: w.Open, Close: w.Close}}); err != nil {
+			return fmt.Errorf("availability[%d]: %w", i, err)
+		}
+		days := make([]string, 0, len(w.Days))
+		for _, d := range w.Days {
+			key := strings.ToLower(strings.TrimSpace(d))
+			if len(key) > 3 {
+				key = key[:3]
+			}
+			if weekdayIndex(key) < 0 {
+				return fmt.Errorf("availability[%d]: unknown weekday %q", i, d)
+			}
+			days = append(days, key)
+		}
+		w.Days = days
+	}
+	return nil
+}
+
+// availabilityHours แปลงช่วงขายเป็นตารางรายสัปดาห์ ใช้ตัวคำนวณเดียวกับเวลาเปิด-ปิดร้าน
+func availabilityHours(windows []AvailabilityWindow, loc *time.Location) *OpeningHours {
+	h := &OpeningHours{Timezone: loc.String(), Weekly: map[string][]TimeRange{}}
+	for _, w := range windows {
+		days := w.Days
+		if len(days) == 0 {
+			days = Weekdays[:]
+		}
+		for _, d := range days {
+			h.Weekly[d] = append(h.Weekly[d], TimeRange{Open: w.Open, Close: w.Close})
+		}
+	}
+	return h
+}
+
+// AvailableAt บอกว่าเวลา t อยู่ในช่วงขายของเมนูหรือไม่ (ไม่มีช่วงขาย = ขายตลอด)
+func (m *MenuItem) AvailableAt(t time.Time, loc *time.Location) bool {
+	if len(m.Availability) == 0 {
+		return true
+	}
+	return availabilityHours(m.Availability, loc).IsOpen(t)
+}
+
+// NextAvailabilityChange คือเวลาที่เมนูจะเริ่ม/หยุดขายครั้งถัดไป (ok = false ถ้าไม่มีช่วงขายหรือไม่เปลี่ยนใน 2 สัปดาห์)
+func (m *MenuItem) NextAvailabilityChange(t time.Time, loc *time.Location) (at time.Time, open bool, ok bool) {
+	if len(m.Availability) == 0 {
+		return time.Time{}, false, false
+	}
+	return availabilityHours(m.Availability, loc).NextChange(t)
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	MaxOptionGroups   = 20
+	MaxGroupOptions   = 50
+	maxOptionNameLen  = 60
+	optionLineHashLen = 12
+)
+
+// OptionGroup คือกลุ่มตัวเลือกของเมนู เช่น ขนาด / ระดับความเผ็ด / ท็อปปิ้ง
+// ต้องเลือกอย่างน้อย Min และไม่เกิน Max ตัว (Max = 0 คือไม่จำกัด)
+type OptionGroup struct {
+	ID      string       `json:"id" firestore:"id"`
+	Name    string       `json:"name" firestore:"name"`
+	Min     int          `json:"min" firestore:"min"`
+	Max     int          `json:"max" firestore:"max"`
+	Options []MenuOption `json:"options" firestore:"options"`
+}
+
+// MenuOption คือตัวเลือกหนึ่งตัว PriceDelta บวก/ลบจากราคาเมนู (ต่อชิ้น)
+type MenuOption struct {
+	ID         string  `json:"id" firestore:"id"`
+	Name       string  `json:"name" firestore:"name"`
+	PriceDelta float64 `json:"price_delta" firestore:"price_delta"`
+}
+
+// SelectedOption คือตัวเลือกที่ลูกค้าเลือก
+// client ส่งแค่ group_id/option_id ส่วนชื่อและราคาเซิร์ฟเวอร์เติมจากเมนูตอนคิดราคา
+type SelectedOption struct {
+	GroupID    string  `json:"group_id" firestore:"group_id"`
+	OptionID   string  `json:"option_id" firestore:"option_id"`
+	GroupName  string  `json:"group_name,omitempty" firestore:"group_name,omitempty"`
+	Name       string  `json:"name,omitempty" firestore:"name,omitempty"`
+	PriceDelta float64 `json:"price_delta" firestore:"price_delta"`
+}
+
+// ValidateOptionGroups ตรวจกลุ่มตัวเลือกของเมนู และเติม id ที่ว่างให้ (ตามชื่อ)
+func ValidateOptionGroups(groups []OptionGroup) error {
+	if len(groups) > MaxOptionGroups {
+		return fmt.Errorf("at most %d option groups", MaxOptionGroups)
+	}
+	groupIDs := map[string]bool{}
+	for gi := range groups {
+		g := &groups[gi]
+		g.Name = strings.TrimSpace(g.Name)
+		if g.Name == "" || len([]rune(g.Name)) > maxOptionNameLen {
+			return fmt.Errorf("option_groups[%d]: name is required (max %d characters)", gi, maxOptionNameLen)
+		}
+		if g.ID = strings.TrimSpace(g.ID); g.ID == "" {
+			g.ID = fmt.Sprintf("g%d", gi+1)
+		}
+		if groupIDs[g.ID] {
+			return fmt.Errorf("option_groups[%d]: duplicate id %q", gi, g.ID)
+		}
+		groupIDs[g.ID] = true
+
+		if len(g.Options) == 0 || len(g.Options) > MaxGroupOptions {
+			return fmt.Errorf("option group %q: needs 1-%d options", g.ID, MaxGroupOptions)
+		}
+		if g.Min < 0 || g.Max < 0 || (g.Max > 0 && g.Max < g.Min) || g.Min > len(g.Options) {
+			return fmt.Errorf("option group %q: invalid min/max (min=%d max=%d)", g.ID, g.Min, g.Max)
+		}
+		optIDs := map[string]bool{}
+		for oi := range g.Options {
+			o := &g.Options[oi]
+			o.Name = strings.TrimSpace(o.Name)
+			if o.Name == "" || len([]rune(o.Name)) > maxOptionNameLen {
+				return fmt.Errorf("option group %q: options[%d] name is required", g.ID, oi)
+			}
+			if o.ID = strings.TrimSpace(o.ID); o.ID == "" {
+				o.ID = fmt.Sprintf("o%d", oi+1)
+			}
+			if optIDs[o.ID] {
+				return fmt.Errorf("option group %q: duplicate option id %q", g.ID, o.ID)
+			}
+			optIDs[o.ID] = true
+		}
+	}
+	return nil
+}
+
+// OptionsTotal คือผลรวม price_delta ของตัวเลือก (ต่อชิ้น)
+func OptionsTotal(opts []SelectedOption) float64 {
+	var sum float64
+	for _, o := range opts {
+		sum += o.PriceDelta
+	}
+	return sum
+}
+
+// CartLineID คือ key ของบรรทัดในตะกร้า: เมนูเดียวกันแต่ตัวเลือกต่างกัน = คนละบรรทัด
+// เมนูที่ไม่มีตัวเลือกใช้ menuId ตรง ๆ (เข้ากับตะกร้าเดิม)
+func CartLineID(menuID string, opts []SelectedOption) string {
+	if len(opts) == 0 {
+		return menuID
+	}
+	keys := make([]string, 0, len(opts))
+	for _, o := range opts {
+		keys = append(keys, o.GroupID+"="+o.OptionID)
+	}
+	sort.Strings(keys)
+	sum := sha1.Sum([]byte(strings.Join(keys, "&")))
+	return menuID + "-" + hex.EncodeToString(sum[:])[:optionLineHashLen]
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCartLineID(t *testing.T) {
+	sweet := SelectedOption{GroupID: "sweet", OptionID: "less"}
+	size := SelectedOption{GroupID: "size", OptionID: "L"}
+	sizeM := SelectedOption{GroupID: "size", OptionID: "M"}
+
+	plain := CartLineID("m1", nil)
+	ab := CartLineID("m1", []SelectedOption{sweet, size})
+	ba := CartLineID("m1", []SelectedOption{size, sweet})
+	other := CartLineID("m1", []SelectedOption{sweet, sizeM})
+	otherMenu := CartLineID("m2", []SelectedOption{sweet, size})
+	// ชื่อ/ราคาไม่อยู่ใน key (เปลี่ยนชื่อตัวเลือกแล้วบรรทัดเดิมยังรวมกันได้)
+	renamed := CartLineID("m1", []SelectedOption{{GroupID: "sweet", OptionID: "less", Name: "Less sugar", PriceDelta: 5}, size})
+
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"no options keeps menu id", plain == "m1"},
+		{"empty slice keeps menu id", CartLineID("m1", []SelectedOption{}) == "m1"},
+		{"prefixed with menu id", strings.HasPrefix(ab, "m1-")},
+		{"fixed hash length", len(ab) == len("m1-")+optionLineHashLen},
+		{"order of options does not matter", ab == ba},
+		{"different option, different line", ab != other},
+		{"different menu, different line", ab != otherMenu},
+		{"names and prices ignored", ab == renamed},
+	}
+	for _, tt := range tests {
+		if !tt.ok {
+			t.Errorf("%s (plain=%s ab=%s ba=%s other=%s)", tt.name, plain, ab, ba, other)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OrderActor คือฝั่งที่ขอเปลี่ยนสถานะออเดอร์
+type OrderActor string
+
+const (
+	ActorCustomer OrderActor = "customer"
+	ActorVendor   OrderActor = "vendor"
+	ActorSystem   OrderActor = "system" // งานฝั่ง server เช่น checkout ที่ตัดเงินแล้ว
+)
+
+// ActorFromRole แปลง role ใน JWT ("user"/"vendor") เป็น OrderActor
+func ActorFromRole(role string) OrderActor {
+	if role == "vendor" {
+		return ActorVendor
+	}
+	return ActorCustomer
+}
+
+// orderNew ใช้แทนสถานะ "ยังไม่มีออเดอร์" ตอนสร้าง
+const orderNew = ""
+
+// orderTransitions: from -> to -> ใครทำได้บ้าง
+//
+//	pending -> confirmed -> prepare -> ready -> completed
+//	   \__________\___________\_________\-----> cancelled
+var orderTransitions = map[string]map[string][]OrderActor{
+	orderNew: {
+		OrderPending: {ActorCustomer, ActorSystem},
+		OrderPrepare: {ActorSystem}, // checkout ที่จ่ายเงินแล้ว เข้าครัวได้ทันที
+	},
+	OrderPending: {
+		OrderConfirmed: {ActorVendor, ActorSystem},
+		OrderPrepare:   {ActorVendor, ActorSystem},
+		OrderCancelled: {ActorCustomer, ActorVendor, ActorSystem},
+	},
+	OrderConfirmed: {
+		OrderPrepare:   {ActorVendor, ActorSystem},
+		OrderCancelled: {ActorVendor, ActorSystem},
+	},
+	OrderPrepare: {
+		OrderReady:     {ActorVendor, ActorSystem},
+		OrderCancelled: {ActorVendor, ActorSystem},
+	},
+	OrderReady: {
+		OrderCompleted: {ActorVendor, ActorSystem},
+	},
+	// completed / cancelled เป็นสถานะสุดท้าย
+}
+
+// legacyOrderStatus คือค่าชุดเก่าที่ยังค้างอยู่ในเอกสารเดิม
+var legacyOrderStatus = map[string]string{
+	"ongoing": OrderReady,
+	"done":    OrderCompleted,
+}
+
+// NormalizeOrderStatus ตัดช่องว่าง/ตัวพิมพ์ และแปลงค่าเก่า (ongoing/done) เป็นชุดปัจจุบัน
+func NormalizeOrderStatus(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if v, ok := legacyOrderStatus[s]; ok {
+		return v
+	}
+	return s
+}
+
+// IsTerminalOrderStatus บอกว่าสถานะนี้ไปต่อไม่ได้แล้ว
+func IsTerminalOrderStatus(s string) bool {
+	s = NormalizeOrderStatus(s)
+	return AllowedOrderStatus[s] && len(orderTransitions[s]) == 0
+}
+
+// OrderTransitionError ถูกคืนเมื่อการเปลี่ยนสถานะไม่ถูกต้อง
+type OrderTransitionError struct {
+	From  string
+	To    string
+	Actor OrderActor
+	// RoleDenied = true แปลว่าเส้นทางนี้มีอยู่ แต่ actor นี้ไม่มีสิทธิ์
+	RoleDenied bool
+}
+
+func (e *OrderTransitionError) Error() string {
+	from := e.From
+	if from == orderNew {
+		from = "(new)"
+	}
+	if e.RoleDenied {
+		return fmt.Sprintf("%s may not change order status %s -> %s", e.Actor, from, e.To)
+	}
+	if !AllowedOrderStatus[e.To] {
+		return fmt.Sprintf("unknown order status %q", e.To)
+	}
+	return fmt.Sprintf("illegal order status transition %s -> %s", from, e.To)
+}
+
+// CheckOrderTransition ตรวจว่า actor เปลี่ยนสถานะ from -> to ได้หรือไม่
+func CheckOrderTransition(from, to string, actor OrderActor) error {
+	if from != orderNew {
+		from = NormalizeOrderStatus(from)
+	}
+	to = NormalizeOrderStatus(to)
+
+	actors, ok := orderTransitions[from][to]
+	if !ok {
+		return &OrderTransitionError{From: from, To: to, Actor: actor}
+	}
+	for _, a := range actors {
+		if a == actor {
+			return nil
+		}
+	}
+	return &OrderTransitionError{From: from, To: to, Actor: actor, RoleDenied: true}
+}
+
+// CheckOrderCreate ตรวจสถานะเริ่มต้นของออเดอร์ใหม่
+func CheckOrderCreate(status string, actor OrderActor) error {
+	return CheckOrderTransition(orderNew, status, actor)
+}
+
+// NextOrderStatuses คืนสถานะที่ actor ไปต่อได้จาก from (ใช้ตอบ error ให้ client)
+func NextOrderStatuses(from string, actor OrderActor) []string {
+	from = NormalizeOrderStatus(from)
+	out := make([]string, 0)
+	for to, actors := range orderTransitions[from] {
+		for _, a := range actors {
+			if a == actor {
+				out = append(out, to)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckOrderTransition(t *testing.T) {
+	tests := []struct {
+		name       string
+		from, to   string
+		actor      OrderActor
+		ok         bool
+		roleDenied bool
+	}{
+		{"customer creates pending", orderNew, OrderPending, ActorCustomer, true, false},
+		{"paid checkout goes to prepare", orderNew, OrderPrepare, ActorSystem, true, false},
+		{"customer cannot skip to prepare", orderNew, OrderPrepare, ActorCustomer, false, true},
+		{"vendor confirms", OrderPending, OrderConfirmed, ActorVendor, true, false},
+		{"customer cannot confirm", OrderPending, OrderConfirmed, ActorCustomer, false, true},
+		{"customer cancels pending", OrderPending, OrderCancelled, ActorCustomer, true, false},
+		{"customer cannot cancel confirmed", OrderConfirmed, OrderCancelled, ActorCustomer, false, true},
+		{"vendor cancels while preparing", OrderPrepare, OrderCancelled, ActorVendor, true, false},
+		{"ready to completed", OrderReady, OrderCompleted, ActorVendor, true, false},
+		{"no skipping prepare", OrderPending, OrderReady, ActorVendor, false, false},
+		{"completed is terminal", OrderCompleted, OrderCancelled, ActorSystem, false, false},
+		{"cancelled is terminal", OrderCancelled, OrderPending, ActorSystem, false, false},
+		{"unknown target", OrderPending, "shipped", ActorVendor, false, false},
+		{"legacy ongoing reads as ready", "ongoing", OrderCompleted, ActorVendor, true, false},
+		{"legacy done is terminal", "done", OrderCancelled, ActorVendor, false, false},
+		{"case and spaces normalised", " Pending ", "CONFIRMED", ActorVendor, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckOrderTransition(tt.from, tt.to, tt.actor)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("want ok, got %v", err)
+				}
+				return
+			}
+			var te *OrderTransitionError
+			if !errors.As(err, &te) {
+				t.Fatalf("want *OrderTransitionError, got %v", err)
+			}
+			if te.RoleDenied != tt.roleDenied {
+				t.Fatalf("RoleDenied = %v, want %v (%v)", te.RoleDenied, tt.roleDenied, err)
+			}
+		})
+	}
+}
+
+func TestIsTerminalOrderStatus(t *testing.T) {
+	tests := map[string]bool{
+		OrderPending:   false,
+		OrderReady:     false,
+		OrderCompleted: true,
+		OrderCancelled: true,
+		"done":         true,
+		"bogus":        false,
+	}
+	for s, want := range tests {
+		if got := IsTerminalOrderStatus(s); got != want {
+			t.Errorf("IsTerminalOrderStatus(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// สถานะบัตรคิว walk-in
+//
+//	waiting -> called -> seated
+//	   |          \----> skipped / left
+//	   \-> seated / left / skipped (system: บัตรค้างข้ามวัน / ร้านถูกลบ)
+const (
+	QueueWaiting = "waiting"
+	QueueCalled  = "called"
+	QueueSeated  = "seated"
+	QueueSkipped = "skipped"
+	QueueLeft    = "left"
+)
+
+const (
+	ColQueueTickets = "queue_tickets"
+	ColQueueDays    = "queue_days" // {shopId}_{day}: เลขคิวล่าสุดของวัน
+	MaxQueueParty   = 50
+)
+
+// queueTransitions: from -> to -> ใครทำได้บ้าง
+var queueTransitions = map[string]map[string][]OrderActor{
+	QueueWaiting: {
+		QueueCalled:  {ActorVendor},
+		QueueSeated:  {ActorVendor},
+		QueueLeft:    {ActorCustomer},
+		QueueSkipped: {ActorSystem},
+	},
+	QueueCalled: {
+		QueueSeated:  {ActorVendor},
+		QueueSkipped: {ActorVendor, ActorSystem},
+		QueueLeft:    {ActorCustomer},
+	},
+	// seated / skipped / left เป็นสถานะสุดท้าย
+}
+
+// QueueTicket คือบัตรคิวหนึ่งใบ (Number เริ่ม 1 ใหม่ทุกวันตามเวลาท้องถิ่นของร้าน)
+type QueueTicket struct {
+	ID        string     `json:"id,omitempty" firestore:"-"`
+	ShopID    string     `json:"shop_id" firestore:"shop_id"`
+	UserID    string     `json:"user_id" firestore:"user_id"`
+	Number    int        `json:"number" firestore:"number"`
+	Day       string     `json:"day" firestore:"day"`
+	People    int        `json:"people" firestore:"people"`
+	Note      string     `json:"note,omitempty" firestore:"note,omitempty"`
+	Status    string     `json:"status" firestore:"status"`
+	CreatedAt time.Time  `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt" firestore:"updatedAt"`
+	CalledAt  *time.Time `json:"called_at,omitempty" firestore:"called_at,omitempty"`
+
+	// Position = ลำดับในคิวที่รออยู่ (1 = คิวถัดไป, 0 = ไม่ได้รอแล้ว) คำนวณตอนตอบ ไม่เก็บ
+	Position int `json:"position" firestore:"-"`
+}
+
+// TakeQueueTicketReq คือ body ของ POST /shops/:id/queue
+type TakeQueueTicketReq struct {
+	People int    `json:"people"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Validate ตรวจจำนวนคน (ไม่ส่ง = 1)
+func (r *TakeQueueTicketReq) Validate() error {
+	if r.People == 0 {
+		r.People = 1
+	}
+	if r.People < 0 || r.People > MaxQueueParty {
+		return fmt.Errorf("people must be 1-%d", MaxQueueParty)
+	}
+	return nil
+}
+
+// IsActive = บัตรยังอยู่ในคิว (รอ หรือถูกเรียกแล้วแต่ยังไม่ได้โต๊ะ)
+func (t *QueueTicket) IsActive() bool {
+	return t.Status == QueueWaiting || t.Status == QueueCalled
+}
+
+// CanMoveQueueTicket บอกว่า actor เปลี่ยนสถานะบัตร from -> to ได้หรือไม่
+func CanMoveQueueTicket(from, to string, actor OrderActor) bool {
+	for _, a := range queueTransitions[from][to] {
+		if a == actor {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// สถานะมัดจำของการจอง
+const (
+	DepositHeld      = "held"      // ตัดจาก wallet ลูกค้าแล้ว รอผลการจอง
+	DepositRefunded  = "refunded"  // คืนเข้า wallet ลูกค้า
+	DepositForfeited = "forfeited" // เข้าบัญชีร้าน (ไม่มา / ยกเลิกช้า)
+)
+
+// DepositOutcome บอกว่ามัดจำที่ถืออยู่จะเป็นอย่างไรเมื่อการจองเปลี่ยนเป็น to ณ เวลา at
+// "" = ยังถือไว้ต่อ (หรือไม่มีมัดจำ)
+//   - ร้านปฏิเสธ / ร้านยกเลิก / ได้โต๊ะแล้ว → คืน
+//   - ลูกค้ายกเลิกทัน DepositRefundBy → คืน, ช้ากว่านั้น → ร้านได้
+//   - ไม่มา → ร้านได้
+func (r *Reservation) DepositOutcome(to string, at time.Time) string {
+	if r.Deposit <= 0 || r.DepositStatus != DepositHeld {
+		return ""
+	}
+	switch to {
+	case ReservationDeclined, ReservationCancelledByShop, ReservationSeated:
+		return DepositRefunded
+	case ReservationCancelledByUser:
+		if r.DepositRefundBy == nil || !at.After(*r.DepositRefundBy) {
+			return DepositRefunded
+		}
+		return DepositForfeited
+	case ReservationNoShow:
+		return DepositForfeited
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// สถานะการจอง (เอกสารเก่าที่ไม่มี status ถือว่า confirmed)
+const (
+	ReservationPending         = "pending"
+	ReservationConfirmed       = "confirmed"
+	ReservationDeclined        = "declined"
+	ReservationCancelledByUser = "cancelled_by_user"
+	ReservationCancelledByShop = "cancelled_by_shop"
+	ReservationSeated          = "seated"
+	ReservationCompleted       = "completed"
+	ReservationNoShow          = "no_show"
+)
+
+var AllowedReservationStatus = map[string]bool{
+	ReservationPending:         true,
+	ReservationConfirmed:       true,
+	ReservationDeclined:        true,
+	ReservationCancelledByUser: true,
+	ReservationCancelledByShop: true,
+	ReservationSeated:          true,
+	ReservationCompleted:       true,
+	ReservationNoShow:          true,
+}
+
+// reservationNew ใช้แทนสถานะ "ยังไม่มีการจอง" ตอนสร้าง
+const reservationNew = ""
+
+// reservationTransitions: from -> to -> ใครทำได้บ้าง
+//
+//	pending -> confirmed -> seated -> completed
+//	   |           |\---> no_show
+//	   |           \----> cancelled_by_user / cancelled_by_shop
+//	   \--> declined / cancelled_by_user
+var reservationTransitions = map[string]map[string][]OrderActor{
+	reservationNew: {
+		ReservationPending:   {ActorCustomer},
+		ReservationConfirmed: {ActorSystem}, // ร้านตั้ง auto_confirm
+	},
+	ReservationPending: {
+		ReservationConfirmed:       {ActorVendor},
+		ReservationDeclined:        {ActorVendor},
+		ReservationCancelledByUser: {ActorCustomer},
+	},
+	ReservationConfirmed: {
+		ReservationSeated:          {ActorVendor},
+		ReservationNoShow:          {ActorVendor, ActorSystem}, // system: เลยเวลาแล้วร้านไม่ได้ปิด
+		ReservationCancelledByUser: {ActorCustomer},
+		ReservationCancelledByShop: {ActorVendor},
+	},
+	ReservationSeated: {
+		ReservationCompleted: {ActorVendor, ActorSystem}, // system: เลยเวลาแล้วร้านไม่ได้ปิด
+	},
+	// declined / cancelled_* / completed / no_show เป็นสถานะสุดท้าย
+}
+
+// ReservationStatusChange คือประวัติการเปลี่ยนสถานะหนึ่งครั้ง (เก็บใน Reservation.History)
+type ReservationStatusChange struct {
+	From   string     `json:"from,omitempty" firestore:"from,omitempty"`
+	To     string     `json:"to" firestore:"to"`
+	By     OrderActor `json:"by" firestore:"by"`
+	ByID   string     `json:"by_id,omitempty" firestore:"by_id,omitempty"`
+	Reason string     `json:"reason,omitempty" firestore:"reason,omitempty"`
+	At     time.Time  `json:"at" firestore:"at"`
+}
+
+// UpdateReservationStatusReq คือ body ของ PUT /reservations/:reservationId/status
+type UpdateReservationStatusReq struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// CurrentStatus คือสถานะปัจจุบัน (เอกสารเก่าที่ไม่มี status ถือว่า confirmed)
+func (r *Reservation) CurrentStatus() string {
+	s := strings.ToLower(strings.TrimSpace(r.Status))
+	if s == "" {
+		return ReservationConfirmed
+	}
+	return s
+}
+
+// IsTerminalReservationStatus บอกว่าสถานะนี้ไปต่อไม่ได้แล้ว
+func IsTerminalReservationStatus(s string) bool {
+	return AllowedReservationStatus[s] && len(reservationTransitions[s]) == 0
+}
+
+// ReservationTransitionError ถูกคืนเมื่อการเปลี่ยนสถานะไม่ถูกต้อง
+type ReservationTransitionError struct {
+	From  string
+	To    string
+	Actor OrderActor
+	// RoleDenied = true แปลว่าเส้นทางนี้มีอยู่ แต่ actor นี้ไม่มีสิทธิ์
+	RoleDenied bool
+}
+
+func (e *ReservationTransitionError) Error() string {
+	from := e.From
+	if from == reservationNew {
+		from = "(new)"
+	}
+	if e.RoleDenied {
+		return fmt.Sprintf("%s may not change reservation status %s -> %s", e.Actor, from, e.To)
+	}
+	if !AllowedReservationStatus[e.To] {
+		return fmt.Sprintf("unknown reservation status %q", e.To)
+	}
+	return fmt.Sprintf("illegal reservation status transition %s -> %s", from, e.To)
+}
+
+// CheckReservationTransition ตรวจว่า actor เปลี่ยนสถานะ from -> to ได้หรือไม่
+func CheckReservationTransition(from, to string, actor OrderActor) error {
+	actors, ok := reservationTransitions[from][to]
+	if !ok {
+		return &ReservationTransitionError{From: from, To: to, Actor: actor}
+	}
+	for _, a := range actors {
+		if a == actor {
+			return nil
+		}
+	}
+	return &ReservationTransitionError{From: from, To: to, Actor: actor, RoleDenied: true}
+}
+
+// CheckReservationCreate ตรวจสถานะเริ่มต้นของการจองใหม่
+func CheckReservationCreate(status string, actor OrderActor) error {
+	return CheckReservationTransition(reservationNew, status, actor)
+}
+
+// NextReservationStatuses คืนสถานะที่ actor ไปต่อได้จาก from (ใช้ตอบ error ให้ client)
+func NextReservationStatuses(from string, actor OrderActor) []string {
+	out := make([]string, 0)
+	for to, actors := range reservationTransitions[from] {
+		for _, a := range actors {
+			if a == actor {
+				out = append(out, to)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// CancelStatusFor คือสถานะยกเลิกของแต่ละฝั่ง (POST /reservations/:id/cancel)
+func CancelStatusFor(actor OrderActor) string {
+	if actor == ActorVendor {
+		return ReservationCancelledByShop
+	}
+	return ReservationCancelledByUser
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckReservationTransition(t *testing.T) {
+	tests := []struct {
+		name       string
+		from, to   string
+		actor      OrderActor
+		ok         bool
+		roleDenied bool
+	}{
+		{"customer books pending", reservationNew, ReservationPending, ActorCustomer, true, false},
+		{"auto confirm on create", reservationNew, ReservationConfirmed, ActorSystem, true, false},
+		{"customer cannot self-confirm", reservationNew, ReservationConfirmed, ActorCustomer, false, true},
+		{"vendor confirms", ReservationPending, ReservationConfirmed, ActorVendor, true, false},
+		{"vendor declines", ReservationPending, ReservationDeclined, ActorVendor, true, false},
+		{"customer cannot decline", ReservationPending, ReservationDeclined, ActorCustomer, false, true},
+		{"customer cancels confirmed", ReservationConfirmed, ReservationCancelledByUser, ActorCustomer, true, false},
+		{"vendor cannot cancel as user", ReservationConfirmed, ReservationCancelledByUser, ActorVendor, false, true},
+		{"vendor cannot cancel pending as shop", ReservationPending, ReservationCancelledByShop, ActorVendor, false, false},
+		{"system marks no-show", ReservationConfirmed, ReservationNoShow, ActorSystem, true, false},
+		{"system completes seated", ReservationSeated, ReservationCompleted, ActorSystem, true, false},
+		{"customer cannot complete", ReservationSeated, ReservationCompleted, ActorCustomer, false, true},
+		{"no seating from pending", ReservationPending, ReservationSeated, ActorVendor, false, false},
+		{"completed is terminal", ReservationCompleted, ReservationNoShow, ActorSystem, false, false},
+		{"unknown target", ReservationConfirmed, "lost", ActorVendor, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckReservationTransition(tt.from, tt.to, tt.actor)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("want ok, got %v", err)
+				}
+				return
+			}
+			var te *ReservationTransitionError
+			if !errors.As(err, &te) {
+				t.Fatalf("want *ReservationTransitionError, got %v", err)
+			}
+			if te.RoleDenied != tt.roleDenied {
+				t.Fatalf("RoleDenied = %v, want %v (%v)", te.RoleDenied, tt.roleDenied, err)
+			}
+		})
+	}
+}
+
+func TestReservationCurrentStatus(t *testing.T) {
+	tests := map[string]string{
+		"":                  ReservationConfirmed, // เอกสารเก่า
+		" Seated ":          ReservationSeated,
+		"no_show":           ReservationNoShow,
+		"CANCELLED_BY_USER": ReservationCancelledByUser,
+	}
+	for in, want := range tests {
+		r := &Reservation{Status: in}
+		if got := r.CurrentStatus(); got != want {
+			t.Errorf("CurrentStatus(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
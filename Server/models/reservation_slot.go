@@ -0,0 +1,151 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ค่า default / ขีดจำกัดของการตั้งค่าการจอง
+const (
+	DefaultSlotMinutes     = 30
+	DefaultBookingMinutes  = 90
+	DefaultMaxDaysAhead    = 30
+	maxReservationTables   = 100
+	maxTableSeats          = 50
+	maxBookingMinutes      = 12 * 60
+	maxReservationDaysSpan = 365
+	maxDepositPerPerson    = 10000
+)
+
+// ReservationTable คือโต๊ะหนึ่งตัวของร้าน (การจองหนึ่งครั้งใช้หนึ่งโต๊ะ)
+type ReservationTable struct {
+	ID    string `json:"id" firestore:"id"`
+	Name  string `json:"name,omitempty" firestore:"name,omitempty"`
+	Seats int    `json:"seats" firestore:"seats"`
+}
+
+// ReservationSettings คือการตั้งค่าการจองของร้าน (Shop.ReservationSettings = nil → จองแบบเดิมไม่ระบุเวลา)
+// ใช้ Tables หรือ SeatCapacity อย่างใดอย่างหนึ่ง; slot สร้างจากเวลาเปิดของร้าน (ไม่มีตาราง = ทั้งวัน)
+type ReservationSettings struct {
+	Tables       []ReservationTable `json:"tables,omitempty" firestore:"tables,omitempty"`
+	SeatCapacity int                `json:"seat_capacity,omitempty" firestore:"seat_capacity,omitempty"`
+	// SlotMinutes = ระยะห่างของเวลาเริ่มที่จองได้; BookingMinutes = เวลาที่การจองหนึ่งครั้งใช้โต๊ะ/ที่นั่ง
+	SlotMinutes    int `json:"slot_minutes" firestore:"slot_minutes"`
+	BookingMinutes int `json:"booking_minutes" firestore:"booking_minutes"`
+	// MaxPartySize = 0 → ไม่เกินโต๊ะใหญ่สุด / จำนวนที่นั่งทั้งหมด
+	MaxPartySize     int `json:"max_party_size,omitempty" firestore:"max_party_size,omitempty"`
+	MinNoticeMinutes int `json:"min_notice_minutes,omitempty" firestore:"min_notice_minutes,omitempty"`
+	MaxDaysAhead     int `json:"max_days_ahead" firestore:"max_days_ahead"`
+	// AutoConfirm = true → การจองที่ผ่านการตรวจที่ว่างเป็น confirmed ทันที (ไม่งั้นรอร้านยืนยัน)
+	AutoConfirm bool `json:"auto_confirm,omitempty" firestore:"auto_confirm,omitempty"`
+	// DepositPerPerson > 0 → ตัดมัดจำ (x จำนวนคน) จาก wallet ตอนจอง
+	// ลูกค้ายกเลิกก่อนเวลาจอง DepositRefundHours ชม. ได้คืน ยกเลิกช้ากว่านั้น / ไม่มา → เข้าบัญชีร้าน
+	DepositPerPerson   float64 `json:"deposit_per_person,omitempty" firestore:"deposit_per_person,omitempty"`
+	DepositRefundHours int     `json:"deposit_refund_hours,omitempty" firestore:"deposit_refund_hours,omitempty"`
+}
+
+// SetReservationSettingsReq คือ body ของ PUT /shop/:id/reservation-settings (settings = null → เลิกใช้ slot)
+type SetReservationSettingsReq struct {
+	Settings *ReservationSettings `json:"settings"`
+}
+
+// Validate ตรวจและเติมค่า default (id โต๊ะว่าง = t1, t2, ...)
+func (rs *ReservationSettings) Validate() error {
+	if len(rs.Tables) > 0 && rs.SeatCapacity > 0 {
+		return fmt.Errorf("use either tables or seat_capacity, not both")
+	}
+	if len(rs.Tables) == 0 && rs.SeatCapacity <= 0 {
+		return fmt.Errorf("tables or seat_capacity required")
+	}
+	if len(rs.Tables) > maxReservationTables {
+		return fmt.Errorf("at most %d tables", maxReservationTables)
+	}
+	seen := map[string]bool{}
+	for i := range rs.Tables {
+		t := &rs.Tables[i]
+		t.ID = strings.TrimSpace(t.ID)
+		if t.ID == "" {
+			t.ID = fmt.Sprintf("t%d", i+1)
+		}
+		if seen[t.ID] {
+			return fmt.Errorf("duplicate table id %q", t.ID)
+		}
+		seen[t.ID] = true
+		if t.Seats <= 0 || t.Seats > maxTableSeats {
+			return fmt.Errorf("table %s: seats must be 1-%d", t.ID, maxTableSeats)
+		}
+	}
+
+	if rs.SlotMinutes == 0 {
+		rs.SlotMinutes = DefaultSlotMinutes
+	}
+	if rs.BookingMinutes == 0 {
+		rs.BookingMinutes = max(DefaultBookingMinutes, rs.SlotMinutes)
+	}
+	if rs.MaxDaysAhead == 0 {
+		rs.MaxDaysAhead = DefaultMaxDaysAhead
+	}
+	switch {
+	case rs.SlotMinutes < 5 || rs.SlotMinutes > 24*60:
+		return fmt.Errorf("slot_minutes must be 5-%d", 24*60)
+	case rs.BookingMinutes < rs.SlotMinutes || rs.BookingMinutes > maxBookingMinutes:
+		return fmt.Errorf("booking_minutes must be between slot_minutes and %d", maxBookingMinutes)
+	case rs.MaxDaysAhead < 0 || rs.MaxDaysAhead > maxReservationDaysSpan:
+		return fmt.Errorf("max_days_ahead must be 1-%d", maxReservationDaysSpan)
+	case rs.MinNoticeMinutes < 0 || rs.MaxPartySize < 0:
+		return fmt.Errorf("min_notice_minutes and max_party_size must be >= 0")
+	case rs.DepositPerPerson < 0 || rs.DepositPerPerson > maxDepositPerPerson:
+		return fmt.Errorf("deposit_per_person must be 0-%d", maxDepositPerPerson)
+	case rs.DepositRefundHours < 0 || rs.DepositRefundHours > maxReservationDaysSpan*24:
+		return fmt.Errorf("deposit_refund_hours must be 0-%d", maxReservationDaysSpan*24)
+	}
+	return nil
+}
+
+// PartyLimit คือจำนวนคนสูงสุดต่อการจอง
+func (rs *ReservationSettings) PartyLimit() int {
+	limit := rs.SeatCapacity
+	for _, t := range rs.Tables {
+		limit = max(limit, t.Seats)
+	}
+	if rs.MaxPartySize > 0 && rs.MaxPartySize < limit {
+		return rs.MaxPartySize
+	}
+	return limit
+}
+
+// Clone คัดลอกแบบ deep (store ใน memory ไม่แชร์ slice กับผู้เรียก)
+func (rs *ReservationSettings) Clone() *ReservationSettings {
+	if rs == nil {
+		return nil
+	}
+	c := *rs
+	c.Tables = append([]ReservationTable(nil), rs.Tables...)
+	return &c
+}
+
+// ReservationSlot คือช่วงเวลาหนึ่งช่องใน GET /shops/:id/availability
+type ReservationSlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Time  string    `json:"time"` // HH:MM ตามเวลาท้องถิ่นของร้าน
+	// SeatsLeft (โหมดที่นั่ง) / TablesLeft (โหมดโต๊ะ: โต๊ะว่างที่นั่งพอสำหรับ people)
+	SeatsLeft  *int `json:"seats_left,omitempty"`
+	TablesLeft *int `json:"tables_left,omitempty"`
+	Bookable   bool `json:"bookable"`
+	// Reason เมื่อ Bookable = false: "full" | "too_soon" | "party_too_large"
+	Reason string `json:"reason,omitempty"`
+}
+
+// HoldsCapacity = การจองนี้ยังกันโต๊ะ/ที่นั่งอยู่ (รอยืนยัน / ยืนยันแล้ว / นั่งอยู่)
+func (r *Reservation) HoldsCapacity() bool {
+	if r.SlotStart == nil || r.SlotEnd == nil {
+		return false
+	}
+	switch r.CurrentStatus() {
+	case ReservationPending, ReservationConfirmed, ReservationSeated:
+		return true
+	}
+	return false
+}
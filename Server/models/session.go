@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Session คือ login หนึ่งครั้งบนหนึ่งอุปกรณ์ (sessions/{id})
+// refresh token จริงไม่ถูกเก็บ เก็บแค่ hash และหมุนใหม่ทุกครั้งที่ refresh
+type Session struct {
+	ID          string     `json:"id" firestore:"-"`
+	UserID      string     `json:"userId" firestore:"userId"`
+	Role        string     `json:"role" firestore:"role"`
+	RefreshHash string     `json:"-" firestore:"refreshHash"`
+	Device      string     `json:"device,omitempty" firestore:"device,omitempty"`
+	UserAgent   string     `json:"userAgent,omitempty" firestore:"userAgent,omitempty"`
+	IP          string     `json:"ip,omitempty" firestore:"ip,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt" firestore:"createdAt"`
+	LastUsedAt  time.Time  `json:"lastUsedAt" firestore:"lastUsedAt"`
+	ExpiresAt   time.Time  `json:"expiresAt" firestore:"expiresAt"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty" firestore:"revokedAt,omitempty"`
+}
+
+// Active บอกว่า session ยังใช้ได้ ณ เวลา now
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -30,19 +30,58 @@ type Shop struct {
 	Image       string `json:"image,omitempty" firestore:"image,omitempty"`
 
 	// ✅ pointer รองรับ null
-	PriceMin      *float64               `json:"price_min,omitempty" firestore:"price_min,omitempty"`
-	PriceMax      *float64               `json:"price_max,omitempty" firestore:"price_max,omitempty"`
-	MenuActiveCnt *int                   `json:"menu_active_count,omitempty" firestore:"menu_active_count,omitempty"`
-	Address       *latlng.LatLng         `json:"address,omitempty" firestore:"address,omitempty"`
-	VendorRef     *firestore.DocumentRef `json:"-" firestore:"vendor_id,omitempty"`
+	PriceMin      *float64       `json:"price_min,omitempty" firestore:"price_min,omitempty"`
+	PriceMax      *float64       `json:"price_max,omitempty" firestore:"price_max,omitempty"`
+	MenuActiveCnt *int           `json:"menu_active_count,omitempty" firestore:"menu_active_count,omitempty"`
+	Address       *latlng.LatLng `json:"address,omitempty" firestore:"address,omitempty"`
+	// Geohash ของ Address (ใช้ค้นร้านใกล้ ๆ) ต้องอัปเดตพร้อม address เสมอ ดู ShopLocationFields
+	Geohash string `json:"-" firestore:"geohash,omitempty"`
+	// OrderCount = จำนวนออเดอร์ที่ completed (ใช้เรียงตามความนิยม)
+	OrderCount int `json:"order_count" firestore:"order_count"`
+	// IndexVersion บอกว่าเอกสารมีฟิลด์ที่ใช้ query/เรียงครบแล้ว (ดู ShopIndexVersion)
+	IndexVersion int                    `json:"-" firestore:"index_v,omitempty"`
+	VendorRef    *firestore.DocumentRef `json:"-" firestore:"vendor_id,omitempty"`
 
 	// ✅ NEW: This field will be sent as a string in the JSON response
 	VendorID      string    `json:"vendor_id,omitempty" firestore:"-"`
 	OrderActive   bool      `json:"order_active" firestore:"order_active"`
 	ReserveActive bool      `json:"reserve_active" firestore:"reserve_active"`
+	QueueActive   bool      `json:"queue_active" firestore:"queue_active"`
 	Status        bool      `json:"status" firestore:"status"` // "open" | "closed"
 	CreatedAt     time.Time `json:"createdAt" firestore:"createdAt"`
 	UpdatedAt     time.Time `json:"updatedAt" firestore:"updatedAt"`
+
+	// Hours = nil คือไม่ใช้ตารางเวลา (เปิด/ปิดด้วย status อย่างเดียวเหมือนเดิม)
+	Hours *OpeningHours `json:"hours,omitempty" firestore:"hours,omitempty"`
+	// Schedule เขียนโดย scheduler เท่านั้น (service.ShopScheduler)
+	Schedule *ShopSchedule `json:"schedule,omitempty" firestore:"schedule,omitempty"`
+	// ReservationSettings = nil คือรับจองแบบไม่ระบุเวลา (แบบเดิม)
+	ReservationSettings *ReservationSettings `json:"reservation_settings,omitempty" firestore:"reservation_settings,omitempty"`
+
+	// soft delete: ร้านถูกซ่อนตั้งแต่ DeletedAt และกู้คืนได้จนถึง PurgeAfter (หลังจากนั้น purge job ลบถาวร)
+	DeletedAt  *time.Time `json:"deleted_at,omitempty" firestore:"deleted_at,omitempty"`
+	PurgeAfter *time.Time `json:"purge_after,omitempty" firestore:"purge_after,omitempty"`
+	DeletedBy  string     `json:"deleted_by,omitempty" firestore:"deleted_by,omitempty"`
+}
+
+// IsDeleted = ร้านถูก soft delete แล้ว (ยังไม่ purge)
+func (s *Shop) IsDeleted() bool { return s.DeletedAt != nil }
+
+// ShopIndexVersion: ร้านที่ index_v ต่ำกว่านี้จะถูกเติมฟิลด์ตอน start server (service.BackfillShopIndex)
+const ShopIndexVersion = 1
+
+// ShopLocationFields คือฟิลด์ที่ต้องเขียนเมื่อเปลี่ยนตำแหน่งร้าน (address + geohash)
+func ShopLocationFields(lat, lng float64) map[string]any {
+	return map[string]any{
+		"address": &latlng.LatLng{Latitude: lat, Longitude: lng},
+		"geohash": EncodeGeohash(lat, lng, GeohashPrecision),
+	}
+}
+
+// NearbyShop คือผลลัพธ์ของ GET /shops/nearby
+type NearbyShop struct {
+	Shop
+	DistanceKm float64 `json:"distance_km"`
 }
 
 type UpdateShopBody struct {
@@ -54,14 +93,16 @@ type UpdateShopBody struct {
 	Status        *bool `json:"status,omitempty"`
 	OrderActive   *bool `json:"order_active,omitempty"`
 	ReserveActive *bool `json:"reserve_active,omitempty"`
+	QueueActive   *bool `json:"queue_active,omitempty"`
 
 	Address  *GeoPoint `json:"address,omitempty"`
 	Location any       `json:"location,omitempty"` // legacy
 }
 
 const (
-	ColShops   = "shops"
-	SubColMenu = "menu"
+	ColShops             = "shops"
+	SubColMenu           = "menu"
+	SubColMenuCategories = "menu_categories"
 )
 
 type MenuItem struct {
@@ -75,6 +116,56 @@ type MenuItem struct {
 	CreatedAt   time.Time              `json:"createdAt" firestore:"createdAt"`
 	UpdatedAt   time.Time              `json:"updatedAt" firestore:"updatedAt"`
 	Extra       map[string]interface{} `json:"extra,omitempty" firestore:"extra,omitempty"`
+	// CategoryID ว่าง = ไม่มีหมวด; Position = ลำดับภายในหมวด (น้อยขึ้นก่อน)
+	CategoryID string `json:"category_id,omitempty" firestore:"category_id,omitempty"`
+	Position   int    `json:"position" firestore:"position"`
+	// ตัวเลือกที่ลูกค้าเลือกได้ (ขนาด, ความเผ็ด, ท็อปปิ้ง ...)
+	OptionGroups []OptionGroup `json:"option_groups,omitempty" firestore:"option_groups,omitempty"`
+	// สต็อก (nil = ไม่นับ) และ sold_out = สต็อกหมด (ระบบตั้งเอง แยกจาก active ที่ร้านเปิด/ปิดเอง)
+	Stock   *MenuStock `json:"stock,omitempty" firestore:"stock,omitempty"`
+	SoldOut bool       `json:"sold_out" firestore:"sold_out"`
+	// ช่วงเวลาที่ขาย (ว่าง = ขายตลอดเวลาที่ร้านเปิด)
+	Availability []AvailabilityWindow `json:"availability,omitempty" firestore:"availability,omitempty"`
+	// AvailableNow คำนวณตอนตอบ GET /shop/:id/menu ไม่ได้เก็บ
+	AvailableNow *bool `json:"available_now,omitempty" firestore:"-"`
+}
+
+// Available = ร้านเปิดขายและสต็อกยังไม่หมด
+func (m *MenuItem) Available() bool {
+	return m.Active && !m.SoldOut
+}
+
+// MenuCategory คือหมวดของเมนู (shops/{id}/menu_categories/{categoryId}) เช่น "เส้น", "เครื่องดื่ม"
+type MenuCategory struct {
+	ID        string    `json:"id" firestore:"-"`
+	ShopID    string    `json:"shop_id" firestore:"shop_id"`
+	Name      string    `json:"name" firestore:"name"`
+	Position  int       `json:"position" firestore:"position"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// MenuSection คือหมวดหนึ่งพร้อมเมนูที่เรียงแล้ว (response ของ GET /shop/:id/menu)
+type MenuSection struct {
+	MenuCategory
+	Items []MenuItem `json:"items"`
+}
+
+type MenuCategoryReq struct {
+	Name string `json:"name"`
+}
+
+// MenuOrderReq คือ body ของ PUT /shop/:id/menu/order (จัดลำดับทั้งเมนูในครั้งเดียว)
+//   - Categories: id หมวดตามลำดับใหม่ (ต้องครบทุกหมวด)
+//   - Items: เมนูตามลำดับใหม่ พร้อมหมวดที่อยู่ ("" = ไม่มีหมวด) เมนูที่ไม่ได้ส่งมาคงที่เดิม
+type MenuOrderReq struct {
+	Categories []string        `json:"categories"`
+	Items      []MenuOrderItem `json:"items"`
+}
+
+type MenuOrderItem struct {
+	ID         string `json:"id"`
+	CategoryID string `json:"category_id"`
 }
 
 type CreateMenuReq struct {
@@ -83,6 +174,10 @@ type CreateMenuReq struct {
 	Image       string   `json:"image"`
 	Price       *float64 `json:"price"`
 	Active      *bool    `json:"active"`
+	CategoryID  string   `json:"category_id"`
+
+	OptionGroups []OptionGroup        `json:"option_groups"`
+	Availability []AvailabilityWindow `json:"availability"`
 }
 
 type UpdateMenuReq struct {
@@ -91,4 +186,8 @@ type UpdateMenuReq struct {
 	Image       *string  `json:"image,omitempty"`
 	Price       *float64 `json:"price,omitempty"`
 	Active      *bool    `json:"active,omitempty"`
+	CategoryID  *string  `json:"category_id,omitempty"` // "" = เอาออกจากหมวด
+
+	OptionGroups *[]OptionGroup        `json:"option_groups,omitempty"` // [] = ลบตัวเลือกทั้งหมด
+	Availability *[]AvailabilityWindow `json:"availability,omitempty"`  // [] = ขายตลอด
 }
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// โหมดสต็อกของเมนู
+const (
+	StockDaily    = "daily"    // รีเซ็ตเป็น DailyQuantity ทุกวัน (ตามเวลาท้องถิ่นของร้าน)
+	StockAbsolute = "absolute" // นับลดไปเรื่อย ๆ จนกว่าร้านจะเติมเอง
+)
+
+const stockDayLayout = "2006-01-02"
+
+// MenuStock คือสต็อกของเมนู (nil = ไม่นับสต็อก)
+type MenuStock struct {
+	Mode          string `json:"mode" firestore:"mode"`
+	Quantity      int    `json:"quantity" firestore:"quantity"` // เหลือขายได้
+	DailyQuantity int    `json:"daily_quantity,omitempty" firestore:"daily_quantity,omitempty"`
+	LowThreshold  int    `json:"low_threshold,omitempty" firestore:"low_threshold,omitempty"` // เหลือ <= ค่านี้ = แจ้งเตือนร้าน (0 = ไม่แจ้ง)
+	Day           string `json:"day,omitempty" firestore:"day,omitempty"`                     // วันของยอด daily (YYYY-MM-DD)
+	LowAlerted    bool   `json:"low_alerted,omitempty" firestore:"low_alerted,omitempty"`     // แจ้งไปแล้ว (ล้างเมื่อเติม/รีเซ็ต)
+}
+
+// SetStockReq คือ body ของ PUT /shop/:id/menu/:menuId/stock
+// mode "off" = เลิกนับสต็อก; quantity คือยอดต่อวัน (daily) หรือยอดคงเหลือ (absolute)
+type SetStockReq struct {
+	Mode         string `json:"mode"`
+	Quantity     int    `json:"quantity"`
+	LowThreshold int    `json:"low_threshold"`
+}
+
+// StockDay คือวันที่ของเวลา t ตามเวลาท้องถิ่น loc
+func StockDay(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(stockDayLayout)
+}
+
+// Validate ตรวจค่าที่ร้านตั้ง
+func (r SetStockReq) Validate() error {
+	switch r.Mode {
+	case StockDaily, StockAbsolute, "off":
+	default:
+		return fmt.Errorf("mode must be %q, %q or \"off\"", StockDaily, StockAbsolute)
+	}
+	if r.Quantity < 0 || r.LowThreshold < 0 {
+		return fmt.Errorf("quantity and low_threshold must be >= 0")
+	}
+	return nil
+}
+
+// Current คือสต็อกที่ใช้ได้ในวัน day (daily ที่ยังไม่ได้รีเซ็ตของวันนี้ = ยอดเต็ม)
+func (s *MenuStock) Current(day string) MenuStock {
+	out := *s
+	if s.Mode == StockDaily && s.Day != day {
+		out.Quantity = s.DailyQuantity
+		out.Day = day
+		out.LowAlerted = false
+	}
+	return out
+}
+
+// IsLow = เหลือน้อยถึงเกณฑ์แจ้งเตือน (แต่ยังไม่หมด)
+func (s *MenuStock) IsLow() bool {
+	return s.LowThreshold > 0 && s.Quantity > 0 && s.Quantity <= s.LowThreshold
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// ประเภทรายการใน wallet ledger
+const (
+	WalletTopUp      = "topup"
+	WalletDebit      = "debit"  // ตัดเงินค่าออเดอร์
+	WalletRefund     = "refund" // คืนเงิน (เช่นออเดอร์ถูกยกเลิก)
+	WalletAdjustment = "adjustment"
+	WalletIncome     = "income" // เงินเข้าบัญชีร้าน (เช่นมัดจำของการจองที่ลูกค้าไม่มา)
+)
+
+// แหล่งที่มาของรายการ (RefType)
+const (
+	WalletRefOrder       = "order"
+	WalletRefTopUp       = "topup"
+	WalletRefSystem      = "system"
+	WalletRefOpening     = "opening" // ยอดยกมาจาก Cost เดิมก่อนมี ledger
+	WalletRefReservation = "reservation"
+)
+
+// WalletTxn คือรายการหนึ่งใน ledger (append-only) ที่ users/{userId}/wallet_transactions/{id}
+// (ledger ของร้านอยู่ที่ vendors/{vendorId}/wallet_transactions/{id})
+// Amount เป็นค่ามีเครื่องหมาย: เข้า = บวก, ออก = ลบ
+type WalletTxn struct {
+	ID           string    `json:"id" firestore:"-"`
+	UserID       string    `json:"userId" firestore:"userId"`
+	Type         string    `json:"type" firestore:"type"`
+	Amount       float64   `json:"amount" firestore:"amount"`
+	BalanceAfter float64   `json:"balanceAfter" firestore:"balanceAfter"`
+	RefType      string    `json:"refType" firestore:"refType"`
+	RefID        string    `json:"refId,omitempty" firestore:"refId,omitempty"`
+	Note         string    `json:"note,omitempty" firestore:"note,omitempty"`
+	CreatedAt    time.Time `json:"createdAt" firestore:"createdAt"`
+
+	// Account = role ของเจ้าของ ledger ("vendor" → vendors, อื่น ๆ → users) ใช้ตอน Append ไม่เก็บ
+	Account string `json:"-" firestore:"-"`
+}
@@ -4,47 +4,100 @@ import (
 	"github.com/PPEACH21/MoblieApp_MeebleProject/controllers"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/middlewares"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/service"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
 	"github.com/gofiber/fiber/v2"
 )
 
+// ทุก route ในนี้อยู่หลัง ProtectedAuth แล้ว
+// ownership: ร้าน = vendor_id ของ shops/{id}, ข้อมูลส่วนตัว = user_id ใน token
 func Routes(app *fiber.App) {
+	vendorOnly := middlewares.RequireRole(store.RoleVendor)
+	userOnly := middlewares.RequireRole(store.RoleUser)
+	ownShop := middlewares.RequireShopOwner("id")
+	ownShopByShopID := middlewares.RequireShopOwner("shopId")
+	liveShop := middlewares.RequireLiveShop() // หลัง ownShop: ร้านที่ถูกลบ (รอ purge) แก้ไม่ได้
+	idempotent := middlewares.Idempotent()    // header Idempotency-Key (ไม่บังคับ)
+
+	/* ---------- AUTH / SESSIONS ---------- */
+	app.Post("/auth/logout", controllers.Logout)
+	app.Get("/auth/sessions", controllers.ListSessions)
+	app.Delete("/auth/sessions/:id", controllers.RevokeSession)
+
 	app.Get("/profile", middlewares.Profile)
-	app.Put("/profile/:id", controllers.UpdateProfile)
+	app.Put("/profile/:id", middlewares.RequireSelf("id"), controllers.UpdateProfile)
 	app.Post("/sendotp", service.OTPvertify())
-	app.Put("/verifiedEmail/:id", controllers.VerifiedUser)
+	app.Put("/verifiedEmail/:id", middlewares.RequireSelf("id"), controllers.VerifiedUser)
 
 	/* ---------- SHOP ---------- */
-	app.Post("/shop/create", controllers.CreateShop)
+	app.Post("/shop/create", vendorOnly, controllers.CreateShop)
 	app.Get("/shops", controllers.GetAllShops)
+	app.Get("/shops/nearby", controllers.NearbyShops)
+	app.Get("/search", controllers.Search)
+	app.Get("/search/suggest", controllers.SearchSuggest)
 	app.Get("/shop/by-id/:id", controllers.GetShopByID)
 	app.Get("/shop/:id", controllers.GetShopByShopID)
-	app.Put("/shop/:id/update", controllers.UpdateShopBasic) // basic fields
-	app.Put("/shop/:id", controllers.UpdateShop)             // generic partial update
-	app.Delete("/shop/:id", controllers.DeleteShop)
+	app.Put("/shop/:id/update", ownShop, liveShop, controllers.UpdateShopBasic) // basic fields
+	app.Put("/shop/:id", ownShop, liveShop, controllers.UpdateShop)             // generic partial update
+	app.Delete("/shop/:id", ownShop, controllers.DeleteShop)                    // soft delete
+	app.Post("/shop/:id/restore", ownShop, controllers.RestoreShop)
+	app.Get("/shop/:id/hours", controllers.GetShopHours)
+	app.Put("/shop/:id/hours", ownShop, liveShop, controllers.SetShopHours)
 	app.Get("/shop/:shopId/name", controllers.GetShopNameById)
 	/* ---------- MENU ---------- */
-	app.Post("/shop/:id/menu", controllers.CreateMenuItem)
+	app.Post("/shop/:id/menu", ownShop, liveShop, controllers.CreateMenuItem)
 	app.Get("/shop/:id/menu", controllers.ListMenuItems)
-	app.Put("/shop/:id/menu/:menuId", controllers.UpdateMenuItem)
-	app.Delete("/shop/:id/menu/:menuId", controllers.DeleteMenuItem)
+	app.Put("/shop/:id/menu/order", ownShop, liveShop, controllers.ReorderMenu) // ต้องมาก่อน /menu/:menuId
+	app.Post("/shop/:id/menu/categories", ownShop, liveShop, controllers.CreateMenuCategory)
+	app.Get("/shop/:id/menu/categories", controllers.ListMenuCategories)
+	app.Put("/shop/:id/menu/categories/:categoryId", ownShop, liveShop, controllers.UpdateMenuCategory)
+	app.Delete("/shop/:id/menu/categories/:categoryId", ownShop, liveShop, controllers.DeleteMenuCategory)
+	app.Get("/shop/:id/menu/low-stock", ownShop, controllers.ListLowStock)
+	app.Put("/shop/:id/menu/:menuId", ownShop, liveShop, controllers.UpdateMenuItem)
+	app.Put("/shop/:id/menu/:menuId/stock", ownShop, liveShop, controllers.SetMenuStock)
+	app.Delete("/shop/:id/menu/:menuId", ownShop, liveShop, controllers.DeleteMenuItem)
 
 	/* ---------- ORDERS ---------- */
-	app.Post("/orders", controllers.CreateOrder)
+	app.Post("/orders", userOnly, idempotent, controllers.CreateOrder)
 	app.Get("/orders", controllers.ListAllOrders)
 	app.Get("/userOrders", controllers.ListUserOrders)
-	app.Get("/shop/:shopId/orders", controllers.ListOrdersByShop)
+	app.Get("/userOrders/stream", controllers.StreamUserOrders) // SSE
+	app.Get("/shop/:shopId/orders", ownShopByShopID, controllers.ListOrdersByShop)
+	app.Get("/shop/:shopId/orders/stream", ownShopByShopID, controllers.StreamShopOrders) // SSE
 	app.Get("/orders/:orderId", controllers.GetOrderByID)
-	app.Put("/orders/:orderId/status", controllers.UpdateOrderStatus)
-	app.Get("/shop/:shopId/history", controllers.ListHistoryByShop)
-	app.Get("/users/:userId/history", controllers.ListUserHistory)
-	app.Get("/:uid/history/:historyId", controllers.GetUserHistoryDetail)
+	app.Put("/orders/:orderId/status", controllers.UpdateOrderStatus) // ตรวจเจ้าของใน transaction
+	app.Post("/orders/:orderId/cancel", controllers.CancelOrder)
+	app.Get("/shop/:shopId/history", ownShopByShopID, controllers.ListHistoryByShop)
+	app.Get("/users/:userId/history", middlewares.RequireSelf("userId"), controllers.ListUserHistory)
+	app.Get("/:uid/history/:historyId", middlewares.RequireSelf("uid"), controllers.GetUserHistoryDetail)
 	/* ---------- RESERVATIONS ---------- */
-	app.Post("/shops/:id/reservations", controllers.CreateReservation)
-	app.Get("/shop/:id/reservations", controllers.ListReservationsByShop)
-	app.Get("/users/:userId/reservations", controllers.GetUserReservations)
+	app.Post("/shops/:id/reservations", userOnly, controllers.CreateReservation)
+	app.Get("/shops/:id/availability", controllers.GetShopAvailability)
+	app.Put("/shop/:id/reservation-settings", ownShop, liveShop, controllers.SetReservationSettings)
+	app.Get("/shop/:id/reservations", ownShop, controllers.ListReservationsByShop)
+	app.Get("/users/:userId/reservations", middlewares.RequireSelf("userId"), controllers.GetUserReservations)
+	app.Get("/reservations/:reservationId", controllers.GetReservation)
+	app.Put("/reservations/:reservationId/status", controllers.UpdateReservationStatus) // ตรวจสิทธิ์ใน transaction
+	app.Post("/reservations/:reservationId/cancel", controllers.CancelReservation)
+	app.Post("/shop/:id/calendar-feed", ownShop, liveShop, controllers.IssueShopCalendarFeed) // GET /shop/:id/reservations.ics อยู่ใน main.go
+	app.Delete("/shop/:id/calendar-feed", ownShop, controllers.RevokeShopCalendarFeed)
+	app.Post("/users/:userId/calendar-feed", middlewares.RequireSelf("userId"), controllers.IssueUserCalendarFeed)
+	app.Delete("/users/:userId/calendar-feed", middlewares.RequireSelf("userId"), controllers.RevokeUserCalendarFeed)
+	/* ---------- WALK-IN QUEUE ---------- */
+	app.Post("/shops/:id/queue", userOnly, controllers.TakeQueueTicket)
+	app.Get("/shops/:id/queue", controllers.GetQueueSummary)
+	app.Get("/shop/:id/queue", ownShop, controllers.ListShopQueue)
+	app.Post("/shop/:id/queue/next", ownShop, liveShop, controllers.CallNextQueueTicket)
+	app.Post("/shop/:id/queue/:ticketId/:action", ownShop, liveShop, controllers.MoveShopQueueTicket) // call | skip | seat
+	app.Get("/queue/tickets", userOnly, controllers.ListMyQueueTickets)
+	app.Get("/queue/tickets/:ticketId", userOnly, controllers.GetMyQueueTicket)
+	app.Post("/queue/tickets/:ticketId/leave", userOnly, controllers.LeaveQueue)
 	/* ---------- CART ---------- */
-	app.Get("/cart", controllers.GetCart)
-	app.Post("/cart/add", controllers.AddToCart)
-	app.Patch("/cart/qty", controllers.UpdateCartQty)
-	app.Post("/cart/checkout", controllers.CheckoutCartFromDB)
+	app.Get("/cart", userOnly, controllers.GetCart)
+	app.Post("/cart/add", userOnly, controllers.AddToCart)
+	app.Patch("/cart/qty", userOnly, controllers.UpdateCartQty)
+	app.Post("/cart/checkout", userOnly, idempotent, controllers.CheckoutCartFromDB)
+	/* ---------- WALLET ---------- */
+	app.Get("/wallet", userOnly, controllers.GetWallet)
+	app.Post("/wallet/topup", userOnly, controllers.TopUpWallet)
+	app.Get("/wallet/transactions", userOnly, controllers.ListWalletTransactions)
 }
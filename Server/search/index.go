@@ -0,0 +1,339 @@
+// Package search คือ full-text index ภายใน process ของร้านและเมนู
+// ไม่พึ่ง search service ภายนอก: สร้างใหม่จาก store ตอน start แล้วอัปเดตตามการแก้ไขร้าน/เมนู
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ชนิดของเอกสารใน index
+const (
+	KindShop = "shop"
+	KindMenu = "menu"
+)
+
+// น้ำหนักของแต่ละฟิลด์ และของแต่ละแบบการจับคู่คำ
+const (
+	weightName        = 3.0
+	weightDescription = 1.0
+
+	matchExact    = 1.0
+	matchPrefix   = 0.8
+	matchContains = 0.6
+	matchFuzzy    = 0.5
+
+	maxExpansions = 64 // จำนวนคำใน index สูงสุดที่ query หนึ่งคำขยายไปได้ (prefix/fuzzy)
+)
+
+// Doc คือเอกสารหนึ่งตัวใน index (ร้าน หรือ เมนูของร้าน)
+type Doc struct {
+	ID          string  `json:"-"`
+	Kind        string  `json:"kind"`
+	ShopID      string  `json:"shop_id"`
+	MenuID      string  `json:"menu_id,omitempty"`
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Image       string  `json:"image,omitempty"`
+	Price       float64 `json:"price,omitempty"`
+	Active      bool    `json:"active"`
+}
+
+func ShopDocID(shopID string) string         { return KindShop + ":" + shopID }
+func MenuDocID(shopID, menuID string) string { return KindMenu + ":" + shopID + "/" + menuID }
+
+// Hit คือผลลัพธ์หนึ่งรายการ
+type Hit struct {
+	Doc
+	Score float64 `json:"score"`
+}
+
+// Options ของ Search
+type Options struct {
+	Kind            string // "" = ทุกชนิด
+	Limit           int
+	IncludeInactive bool // รวมเมนูที่ปิดขายอยู่
+}
+
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]*Doc
+	postings map[string]map[string]float64 // term -> docID -> น้ำหนัก
+	docTerms map[string][]string           // docID -> terms (ไว้ลบ)
+
+	terms []string // term ทั้งหมดเรียงตามตัวอักษร (สร้างใหม่เมื่อ dirty)
+	dirty bool
+}
+
+func New() *Index {
+	return &Index{
+		docs:     map[string]*Doc{},
+		postings: map[string]map[string]float64{},
+		docTerms: map[string][]string{},
+	}
+}
+
+// Put เพิ่มหรือแทนที่เอกสาร
+func (ix *Index) Put(d Doc) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(d.ID)
+	weights := map[string]float64{}
+	for _, t := range indexTerms(d.Name) {
+		weights[t] = weightName
+	}
+	for _, t := range indexTerms(d.Description) {
+		if weights[t] < weightDescription {
+			weights[t] = weightDescription
+		}
+	}
+	terms := make([]string, 0, len(weights))
+	for t, w := range weights {
+		p := ix.postings[t]
+		if p == nil {
+			p = map[string]float64{}
+			ix.postings[t] = p
+			ix.dirty = true
+		}
+		p[d.ID] = w
+		terms = append(terms, t)
+	}
+	doc := d
+	ix.docs[d.ID] = &doc
+	ix.docTerms[d.ID] = terms
+}
+
+// Remove ลบเอกสาร (ไม่มีอยู่ก็ไม่เป็นไร)
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+// RemoveShop ลบร้านและเมนูทั้งหมดของร้าน
+func (ix *Index) RemoveShop(shopID string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for id, d := range ix.docs {
+		if d.ShopID == shopID {
+			ix.remove(id)
+		}
+	}
+}
+
+// Reset ล้าง index ทั้งหมด
+func (ix *Index) Reset() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.docs = map[string]*Doc{}
+	ix.postings = map[string]map[string]float64{}
+	ix.docTerms = map[string][]string{}
+	ix.terms = nil
+	ix.dirty = false
+}
+
+// Get คืนเอกสารตาม id
+func (ix *Index) Get(id string) (Doc, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	d, ok := ix.docs[id]
+	if !ok {
+		return Doc{}, false
+	}
+	return *d, true
+}
+
+// Len คือจำนวนเอกสาร
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// ต้องถือ write lock อยู่
+func (ix *Index) remove(id string) {
+	for _, t := range ix.docTerms[id] {
+		p := ix.postings[t]
+		delete(p, id)
+		if len(p) == 0 {
+			delete(ix.postings, t)
+			ix.dirty = true
+		}
+	}
+	delete(ix.docTerms, id)
+	delete(ix.docs, id)
+}
+
+// Search ค้นหาแบบ:
+//   - ทุกคำใน query ต้องเจอ (ถ้าไม่มีเอกสารไหนเจอครบ จะคืนเอกสารที่เจอมากที่สุด)
+//   - คำสุดท้ายจับแบบ prefix ได้ (autocomplete ระหว่างพิมพ์)
+//   - คำไทยจับแบบ contains ได้ (คำประสมที่ตัดคำไม่ตรงกัน)
+//   - พิมพ์ผิดได้ 1 ตัว (คำยาว 4–7 ตัวอักษร) หรือ 2 ตัว (8 ตัวขึ้นไป)
+func (ix *Index) Search(q string, opt Options) []Hit {
+	tokens := dedupe(Tokenize(q))
+	if len(tokens) == 0 {
+		return nil
+	}
+	ix.ensureSorted()
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	scores := map[string]float64{}
+	matched := map[string]int{}
+	for i, tok := range tokens {
+		best := map[string]float64{} // docID -> คะแนนดีที่สุดของ token นี้
+		for term, factor := range ix.expand(tok, i == len(tokens)-1) {
+			for id, w := range ix.postings[term] {
+				if s := w * factor; s > best[id] {
+					best[id] = s
+				}
+			}
+		}
+		for id, s := range best {
+			scores[id] += s
+			matched[id]++
+		}
+	}
+
+	need := 0
+	for _, n := range matched {
+		need = max(need, n)
+	}
+	hits := make([]Hit, 0)
+	for id, s := range scores {
+		d := ix.docs[id]
+		// เมนูที่ไม่มีร้านใน index (ร้านถูกลบ/ซ่อน) ไม่แสดง
+		if _, ok := ix.docs[ShopDocID(d.ShopID)]; d.Kind == KindMenu && !ok {
+			continue
+		}
+		if matched[id] < need || (opt.Kind != "" && d.Kind != opt.Kind) || (!opt.IncludeInactive && !d.Active) {
+			continue
+		}
+		hits = append(hits, Hit{Doc: *d, Score: s})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Kind != hits[j].Kind {
+			return hits[i].Kind == KindShop
+		}
+		return hits[i].Name < hits[j].Name
+	})
+	if opt.Limit > 0 && len(hits) > opt.Limit {
+		hits = hits[:opt.Limit]
+	}
+	return hits
+}
+
+// expand หาคำใน index ที่ query token นี้จับได้ พร้อมตัวคูณคะแนน; ต้องถือ read lock
+func (ix *Index) expand(tok string, last bool) map[string]float64 {
+	out := map[string]float64{}
+	add := func(term string, f float64) {
+		if f > out[term] {
+			out[term] = f
+		}
+	}
+	if _, ok := ix.postings[tok]; ok {
+		add(tok, matchExact)
+	}
+
+	n := utf8.RuneCountInString(tok)
+	thai := isThai([]rune(tok)[0])
+
+	// prefix: ช่วงใน ix.terms ที่ขึ้นต้นด้วย tok
+	if last || n >= 3 {
+		i := sort.SearchStrings(ix.terms, tok)
+		for k := 0; i < len(ix.terms) && strings.HasPrefix(ix.terms[i], tok) && k < maxExpansions; i, k = i+1, k+1 {
+			add(ix.terms[i], matchPrefix)
+		}
+	}
+
+	// contains (ไทยเท่านั้น) และ fuzzy
+	edits := 0
+	switch {
+	case n >= 8:
+		edits = 2
+	case n >= 4:
+		edits = 1
+	}
+	k := 0
+	for _, term := range ix.terms {
+		if k >= maxExpansions {
+			break
+		}
+		if _, ok := out[term]; ok {
+			continue
+		}
+		if thai && n >= 2 && strings.Contains(term, tok) {
+			add(term, matchContains)
+			k++
+			continue
+		}
+		if edits > 0 {
+			m := utf8.RuneCountInString(term)
+			if m-n <= edits && n-m <= edits && editDistance(tok, term, edits) <= edits {
+				add(term, matchFuzzy)
+				k++
+			}
+		}
+	}
+	return out
+}
+
+func (ix *Index) ensureSorted() {
+	ix.mu.RLock()
+	dirty := ix.dirty
+	ix.mu.RUnlock()
+	if !dirty {
+		return
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if !ix.dirty {
+		return
+	}
+	terms := make([]string, 0, len(ix.postings))
+	for t := range ix.postings {
+		terms = append(terms, t)
+	}
+	sort.Strings(terms)
+	ix.terms = terms
+	ix.dirty = false
+}
+
+// editDistance = Damerau–Levenshtein แบบ optimal string alignment บนหน่วย rune
+// (สลับตัวอักษรติดกันนับเป็น 1 ครั้ง) เกิน limit แล้วหยุดคืน limit+1
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,31 @@
+package search
+
+import "strings"
+
+// thaiWords คือพจนานุกรมสำหรับตัดคำ เน้นคำที่เจอบ่อยในชื่อร้าน/เมนูอาหาร
+// เพิ่มคำได้ตามต้องการ (คำที่ไม่มีในนี้ยังค้นเจอด้วย prefix / contains / fuzzy)
+const thaiWords = `
+ข้าว ข้าวผัด ข้าวมัน ข้าวมันไก่ ข้าวขาหมู ข้าวหมูแดง ข้าวหมูกรอบ ข้าวต้ม ข้าวเหนียว ข้าวสวย ข้าวกล้อง ข้าวไข่เจียว ข้าวราดแกง ข้าวหน้า ข้าวซอย ข้าวยำ ข้าวโพด
+ก๋วยเตี๋ยว ก๋วยจั๊บ บะหมี่ เส้นเล็ก เส้นใหญ่ เส้นหมี่ วุ้นเส้น มาม่า ขนมจีน ราดหน้า ผัดซีอิ๊ว ผัดไทย สุกี้ เย็นตาโฟ เกาเหลา
+ผัด ทอด ต้ม ย่าง ปิ้ง นึ่ง อบ ยำ แกง ตุ๋น เผา คั่ว ลวก ราด ผัดกะเพรา กะเพรา กระเพรา พริกแกง พริกเผา กระเทียม พริกไทย
+ต้มยำ ต้มข่า ต้มจืด ต้มแซ่บ แกงเขียวหวาน แกงเผ็ด แกงส้ม แกงป่า แกงจืด พะแนง มัสมั่น ฉู่ฉี่ ลาบ น้ำตก ส้มตำ ตำ ไส้กรอก แหนม
+หมู ไก่ เนื้อ วัว ปลา กุ้ง ปู หมึก ปลาหมึก หอย หอยนางรม เป็ด ไข่ ไข่ดาว ไข่เจียว ไข่ต้ม ไข่เค็ม เต้าหู้ ทะเล ซีฟู้ด
+หมูสับ หมูกรอบ หมูแดง หมูยอ หมูทอด หมูกระทะ หมูปิ้ง หมูย่าง หมูหัน หมูตุ๋น ขาหมู คอหมู สามชั้น ซี่โครง
+ไก่ทอด ไก่ย่าง ไก่ต้ม ไก่อบ ปีกไก่ น่องไก่ อกไก่ ปลาทอด ปลาเผา ปลานึ่ง ปลาดุก ปลานิล ปลากะพง ปลาแซลมอน กุ้งเผา กุ้งทอด กุ้งแช่น้ำปลา
+ผัก ผักบุ้ง คะน้า กะหล่ำ กะหล่ำปลี ถั่ว ถั่วงอก ถั่วฝักยาว เห็ด มะเขือ แตงกวา มะนาว พริก หอม หัวหอม ต้นหอม ผักชี โหระพา ใบกะเพรา ขิง ข่า ตะไคร้ มะพร้าว
+น้ำ น้ำแข็ง น้ำเปล่า น้ำอัดลม น้ำผลไม้ น้ำส้ม น้ำมะพร้าว น้ำมะนาว น้ำปลา น้ำจิ้ม น้ำซุป น้ำพริก น้ำตาล
+ชา ชาไทย ชาเขียว ชานม ชามะนาว กาแฟ กาแฟเย็น กาแฟร้อน โกโก้ นม นมสด นมชมพู โอเลี้ยง โซดา ปั่น เย็น ร้อน หวาน
+ขนม ขนมปัง ขนมหวาน เค้ก ไอศกรีม ไอติม บิงซู ทองหยอด ทองหยิบ ฝอยทอง บัวลอย ลอดช่อง กล้วย กล้วยทอด ข้าวเหนียวมะม่วง มะม่วง ทุเรียน สังขยา โรตี วาฟเฟิล ครัวซองต์ บราวนี่ คุกกี้ พุดดิ้ง เครป
+ผลไม้ แตงโม สับปะรด ส้ม องุ่น สตรอว์เบอร์รี แอปเปิ้ล ลำไย ลิ้นจี่ เงาะ มังคุด ฝรั่ง มะละกอ
+เผ็ด เค็ม เปรี้ยว จืด กรอบ นุ่ม แซ่บ อร่อย พิเศษ ธรรมดา จานเดียว ชุด ถ้วย จาน แก้ว
+ร้าน ร้านอาหาร ครัว คาเฟ่ บ้าน ป้า ลุง เจ๊ เฮีย แม่ พ่อ ตลาด สาขา โต๊ะ ตามสั่ง อาหาร อาหารตามสั่ง ของหวาน เครื่องดื่ม ทานเล่น จานหลัก ฟาสต์ฟู้ด
+พิซซ่า เบอร์เกอร์ แฮมเบอร์เกอร์ สปาเกตตี้ สเต๊ก ซูชิ ราเมน ชาบู ปิ้งย่าง บุฟเฟ่ต์ ติ่มซำ เกี๊ยว ซาลาเปา ขนมจีบ แซนด์วิช สลัด เฟรนช์ฟรายส์ นักเก็ต
+`
+
+var thaiDict = func() map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(thaiWords) {
+		m[w] = true
+	}
+	return m
+}()
@@ -0,0 +1,153 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ตัดคำภาษาไทยแบบ longest matching กับพจนานุกรม (thai_dict.go) บนหน่วย TCC
+// (Thai Character Cluster: พยัญชนะ + สระ/วรรณยุกต์ที่เกาะอยู่ — จุดที่ตัดคำได้เสมอ)
+// ส่วนที่ไม่อยู่ในพจนานุกรมถูกรวมเป็นคำเดียว และยังหาเจอได้ด้วย prefix / contains / fuzzy ใน index
+
+const maxWordClusters = 12 // คำในพจนานุกรมยาวไม่เกินนี้ (หน่วย TCC)
+
+func isThai(r rune) bool { return r >= 0x0E01 && r <= 0x0E4E }
+
+func isThaiLeadingVowel(r rune) bool { return r >= 0x0E40 && r <= 0x0E44 } // เ แ โ ใ ไ
+
+func isThaiFollowing(r rune) bool {
+	switch {
+	case r == 0x0E31, r >= 0x0E34 && r <= 0x0E3A, r >= 0x0E47 && r <= 0x0E4E: // สระบน/ล่าง วรรณยุกต์ การันต์
+		return true
+	case r == 0x0E30, r == 0x0E32, r == 0x0E33, r == 0x0E45: // ะ า ำ ๅ
+		return true
+	}
+	return false
+}
+
+// normalize: ตัวพิมพ์เล็ก, เลขไทย → อารบิก, ตัดเครื่องหมาย/ไม้ยมก/ไปยาลน้อยเป็นช่องว่าง
+func normalize(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= '๐' && r <= '๙':
+			b.WriteRune('0' + (r - '๐'))
+		case r == 'ๆ' || r == 'ฯ':
+			b.WriteByte(' ')
+		case isThai(r), unicode.IsLetter(r), unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// runs แยกข้อความเป็นช่วงภาษาไทย / ช่วงอื่น (ช่องว่างเป็นตัวแบ่ง)
+func runs(s string) (out []string, thai []bool) {
+	for _, field := range strings.Fields(normalize(s)) {
+		start := 0
+		rs := []rune(field)
+		for i := 1; i <= len(rs); i++ {
+			if i == len(rs) || isThai(rs[i]) != isThai(rs[start]) {
+				out = append(out, string(rs[start:i]))
+				thai = append(thai, isThai(rs[start]))
+				start = i
+			}
+		}
+	}
+	return out, thai
+}
+
+// clusters แบ่งข้อความไทยเป็น TCC
+func clusters(s string) []string {
+	rs := []rune(s)
+	out := make([]string, 0, len(rs))
+	for i := 0; i < len(rs); {
+		start := i
+		if isThaiLeadingVowel(rs[i]) && i+1 < len(rs) {
+			i++
+		}
+		i++
+		for i < len(rs) && isThaiFollowing(rs[i]) {
+			i++
+		}
+		out = append(out, string(rs[start:i]))
+	}
+	return out
+}
+
+// SegmentThai ตัดคำไทยหนึ่งช่วง (ไม่มีช่องว่าง)
+func SegmentThai(s string) []string {
+	cl := clusters(s)
+	var (
+		out     []string
+		unknown strings.Builder
+	)
+	flush := func() {
+		if unknown.Len() > 0 {
+			out = append(out, unknown.String())
+			unknown.Reset()
+		}
+	}
+	for i := 0; i < len(cl); {
+		best := 0
+		var w strings.Builder
+		for j := i; j < len(cl) && j-i < maxWordClusters; j++ {
+			w.WriteString(cl[j])
+			if thaiDict[w.String()] {
+				best = j - i + 1
+			}
+		}
+		if best == 0 {
+			unknown.WriteString(cl[i])
+			i++
+			continue
+		}
+		flush()
+		out = append(out, strings.Join(cl[i:i+best], ""))
+		i += best
+	}
+	flush()
+	return out
+}
+
+// Tokenize คืนคำจากข้อความ (ตามลำดับ ไม่ตัดคำซ้ำ)
+func Tokenize(s string) []string {
+	parts, thai := runs(s)
+	out := make([]string, 0, len(parts))
+	for i, p := range parts {
+		if thai[i] {
+			out = append(out, SegmentThai(p)...)
+		} else {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// indexTerms คือคำที่เก็บใน index: คำที่ตัดได้ + ช่วงภาษาไทยทั้งก้อน (ให้พิมพ์ต่อเนื่องแล้ว prefix เจอ)
+func indexTerms(s string) []string {
+	terms := Tokenize(s)
+	parts, thai := runs(s)
+	for i, p := range parts {
+		if thai[i] {
+			terms = append(terms, p)
+		}
+	}
+	return dedupe(terms)
+}
+
+func dedupe(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := in[:0]
+	for _, t := range in {
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
+}
@@ -0,0 +1,31 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"lowercase latin words", "Pad THAI", []string{"pad", "thai"}},
+		{"thai digits become arabic", "ร้าน ABC๑๒๓", []string{"ร้าน", "abc123"}},
+		{"longest dictionary match", "ข้าวผัดกะเพรา", []string{"ข้าวผัด", "กะเพรา"}},
+		{"several thai words", "กะเพราหมูสับไข่ดาว", []string{"กะเพรา", "หมูสับ", "ไข่ดาว"}},
+		{"thai then latin without space", "ข้าวมันไก่Hainan", []string{"ข้าวมันไก่", "hainan"}},
+		{"unknown thai kept together", "ข้าวฮฮฮผัด", []string{"ข้าว", "ฮฮฮ", "ผัด"}},
+		{"mai yamok and punctuation split", "เส้นเล็กๆ, ต้มยำ!", []string{"เส้นเล็ก", "ต้มยำ"}},
+		{"only punctuation", "  !! ", []string{}},
+		{"empty", "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.in); !slices.Equal(got, tt.want) {
+				t.Fatalf("Tokenize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+const (
+	IdempotencyTTL = 24 * time.Hour // เก็บ response ไว้ตอบซ้ำนานเท่านี้
+	// request แรกค้าง (process ตายกลางทาง) เกินนี้ถือว่า key ว่างแล้ว
+	idempotencyLockTTL = 2 * time.Minute
+	MaxIdempotencyKey  = 255
+)
+
+var (
+	ErrIdempotencyMismatch   = errors.New("Idempotency-Key was already used with a different request")
+	ErrIdempotencyInProgress = errors.New("a request with this Idempotency-Key is still in progress")
+)
+
+// IdempotencyRequestHash คือ hash ของ method + path + body
+// body ที่เป็น JSON ถูกจัดรูปใหม่ก่อน (ลำดับ key / ช่องว่างต่างกันถือว่าเป็น request เดียวกัน)
+func IdempotencyRequestHash(method, path string, body []byte) string {
+	if v := bytes.TrimSpace(body); len(v) > 0 {
+		var doc any
+		if json.Unmarshal(v, &doc) == nil {
+			if canon, err := json.Marshal(doc); err == nil {
+				body = canon
+			}
+		}
+	}
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// BeginIdempotent จอง key ให้ request นี้
+//   - คืน record (ไม่ nil) = เคยทำสำเร็จแล้ว ให้ตอบ response ที่เก็บไว้
+//   - คืน nil, nil = จองได้ ให้รัน handler แล้วเรียก CompleteIdempotent / ReleaseIdempotent
+func BeginIdempotent(ctx context.Context, userID, key, reqHash string) (*models.IdempotencyRecord, error) {
+	var replay *models.IdempotencyRecord
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		replay = nil
+		nowT := time.Now()
+		rec, err := tx.Idempotency().Get(ctx, userID, key)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		// record ที่หมดอายุแล้วถูกเขียนทับด้านล่าง (key ใช้ใหม่ได้)
+		if rec != nil && nowT.Before(rec.ExpiresAt) {
+			if rec.RequestHash != reqHash {
+				return ErrIdempotencyMismatch
+			}
+			if rec.Status == models.IdempotencyDone {
+				replay = rec
+				return nil
+			}
+			return ErrIdempotencyInProgress
+		}
+		return tx.Idempotency().Put(ctx, &models.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			RequestHash: reqHash,
+			Status:      models.IdempotencyInProgress,
+			CreatedAt:   nowT,
+			ExpiresAt:   nowT.Add(idempotencyLockTTL),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replay, nil
+}
+
+// CompleteIdempotent เก็บ response ของ request แรกไว้ตอบซ้ำ
+func CompleteIdempotent(ctx context.Context, userID, key, reqHash string, code int, contentType string, body []byte) error {
+	nowT := time.Now()
+	return config.DB.Idempotency().Put(ctx, &models.IdempotencyRecord{
+		UserID:       userID,
+		Key:          key,
+		RequestHash:  reqHash,
+		Status:       models.IdempotencyDone,
+		ResponseCode: code,
+		ResponseType: contentType,
+		ResponseBody: append([]byte(nil), body...),
+		CreatedAt:    nowT,
+		ExpiresAt:    nowT.Add(IdempotencyTTL),
+	})
+}
+
+// PurgeExpiredIdempotency ลบ record ที่หมดอายุแล้วทั้งหมด (ทีละ store.PurgeBatchSize) คืนจำนวนที่ลบ
+func PurgeExpiredIdempotency(ctx context.Context, now time.Time) (int, error) {
+	total := 0
+	for {
+		n, err := config.DB.Idempotency().DeleteExpired(ctx, now, store.PurgeBatchSize)
+		total += n
+		if err != nil || n < store.PurgeBatchSize {
+			return total, err
+		}
+	}
+}
+
+// StartIdempotencyPurger รัน PurgeExpiredIdempotency ทุก every จนกว่า ctx จะถูกยกเลิก
+func StartIdempotencyPurger(ctx context.Context, every time.Duration) {
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		if _, err := PurgeExpiredIdempotency(ctx, time.Now()); err != nil {
+			log.Println("idempotency purge:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// ReleaseIdempotent ปล่อย key (request แรกไม่สำเร็จ ให้ลองใหม่ด้วย key เดิมได้)
+func ReleaseIdempotent(ctx context.Context, userID, key string) error {
+	return config.DB.Idempotency().Delete(ctx, userID, key)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIdempotencyReplayAndConflict(t *testing.T) {
+	useMemoryStore(t)
+	ctx := context.Background()
+	hash := IdempotencyRequestHash("POST", "/orders", []byte(`{"a":1,"b":2}`))
+
+	// ลำดับ key / ช่องว่างต่างกันถือว่าเป็น request เดียวกัน
+	if h := IdempotencyRequestHash("POST", "/orders", []byte(` {"b":2, "a":1}`)); h != hash {
+		t.Fatalf("canonical JSON hash differs: %s vs %s", h, hash)
+	}
+	if h := IdempotencyRequestHash("POST", "/orders", []byte(`{"a":1,"b":3}`)); h == hash {
+		t.Fatal("different body must hash differently")
+	}
+
+	rec, err := BeginIdempotent(ctx, "u1", "k1", hash)
+	if err != nil || rec != nil {
+		t.Fatalf("first begin = %v, %v; want nil, nil", rec, err)
+	}
+	if _, err := BeginIdempotent(ctx, "u1", "k1", hash); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Fatalf("second begin while running = %v, want ErrIdempotencyInProgress", err)
+	}
+	if _, err := BeginIdempotent(ctx, "u1", "k1", "other"); !errors.Is(err, ErrIdempotencyMismatch) {
+		t.Fatalf("other body while running = %v, want ErrIdempotencyMismatch", err)
+	}
+	// key เดียวกันของ user อื่นไม่ชนกัน
+	if rec, err := BeginIdempotent(ctx, "u2", "k1", "other"); err != nil || rec != nil {
+		t.Fatalf("other user begin = %v, %v; want nil, nil", rec, err)
+	}
+
+	if err := CompleteIdempotent(ctx, "u1", "k1", hash, 201, "application/json", []byte(`{"id":"o1"}`)); err != nil {
+		t.Fatal(err)
+	}
+	rec, err = BeginIdempotent(ctx, "u1", "k1", hash)
+	if err != nil || rec == nil {
+		t.Fatalf("replay = %v, %v; want stored record", rec, err)
+	}
+	if rec.ResponseCode != 201 || rec.ResponseType != "application/json" || string(rec.ResponseBody) != `{"id":"o1"}` {
+		t.Fatalf("replay record = %d %s %s", rec.ResponseCode, rec.ResponseType, rec.ResponseBody)
+	}
+	if _, err := BeginIdempotent(ctx, "u1", "k1", "other"); !errors.Is(err, ErrIdempotencyMismatch) {
+		t.Fatalf("other body after done = %v, want ErrIdempotencyMismatch", err)
+	}
+}
@@ -2,13 +2,10 @@ package service
 
 import (
 	"fmt"
-	"os"
-	"time"
 
 	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,33 +15,15 @@ func Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
 
-	role:="user"
-	checkuser := true;
-	docs, err := config.User.Where("email", "==", user.Email).Limit(1).Documents(config.Ctx).Next()
+	// store หาใน users ก่อนแล้วค่อย vendors และบอก role มาให้
+	member, err := config.DB.Accounts().FindByEmail(config.Ctx, user.Email)
 	if err != nil {
-		docs, err = config.User.Where("username", "==", user.Username).Limit(1).Documents(config.Ctx).Next()
+		member, err = config.DB.Accounts().FindByUsername(config.Ctx, user.Username)
 		if err != nil {
-			checkuser=false
+			return c.Status(fiber.StatusNotFound).SendString("Email or Username Not Found")
 		}
 	}
-	
-	
-	if !checkuser{
-		docs, err = config.Vendor.Where("email", "==", user.Email).Limit(1).Documents(config.Ctx).Next()
-		if err != nil {
-			docs, err = config.Vendor.Where("username", "==", user.Username).Limit(1).Documents(config.Ctx).Next()
-			if err != nil {
-				return c.Status(fiber.StatusNotFound).SendString("Email or Username Not Found")
-			}
-		}
-		role="vendor"
-	}
-
-	
-	var member models.User
-	if err := docs.DataTo(&member); err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Error parsing user data")
-	}
+	role := member.Role
 
 	err = bcrypt.CompareHashAndPassword([]byte(member.Password), []byte(user.Password))
 	if err != nil {
@@ -52,28 +31,17 @@ func Login(c *fiber.Ctx) error {
 	}
 
 	fmt.Println("Login Valid Correct!")
-	claims := jwt.MapClaims{
-		"user_id":  docs.Ref.ID,
-		"username": member.Username,
-		"verified": member.Verified,
-		"role":     role,
-		"exp":      time.Now().Add(time.Minute * 60).Unix(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	secret := os.Getenv("JWT_SECRET")
-	t, err := token.SignedString([]byte(secret))
+	pair, err := IssueSession(config.Ctx, member, ClientInfoFrom(c))
 	if err != nil {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
-	return c.JSON(fiber.Map{
-		"user_id":  docs.Ref.ID,
+	return c.JSON(pair.Into(fiber.Map{
+		"user_id":  member.ID,
 		"email":    member.Email,
 		"username": member.Username,
 		"role":     role,
 		"verified": member.Verified,
-		"token":    t,
 		"message":  "login success",
-	})
+	}))
 }
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+// อ่านเมนูทุกร้านใหม่ทุกชั่วโมง (จับเมนูที่ถูกแก้จาก instance อื่น)
+const menuWindowFullInterval = time.Hour
+
+// CheckAvailability ตรวจว่าทุกรายการอยู่ในช่วงขาย ณ เวลา at (loc = timezone ของร้าน, ดู StockLocation)
+func CheckAvailability(priced *PricedItems, shopID string, loc *time.Location, at time.Time) error {
+	var problems []ItemProblem
+	seen := map[string]bool{}
+	for _, it := range priced.Items {
+		m := priced.Menus[it.ID]
+		if m == nil || seen[it.ID] || m.AvailableAt(at, loc) {
+			continue
+		}
+		seen[it.ID] = true
+		p := ItemProblem{MenuID: it.ID, Name: m.Name, Reason: ItemNotNow}
+		if next, open, ok := m.NextAvailabilityChange(at, loc); ok && open {
+			next = next.In(loc)
+			p.NextAvailable = &next
+		}
+		problems = append(problems, p)
+	}
+	if len(problems) > 0 {
+		return &ItemsUnavailableError{ShopID: shopID, Problems: problems}
+	}
+	return nil
+}
+
+// MarkAvailableNow เติม available_now ให้เมนู (สำหรับ response)
+func MarkAvailableNow(items []models.MenuItem, shop *models.Shop, at time.Time) {
+	loc := StockLocation(shop)
+	for i := range items {
+		ok := items[i].Available() && items[i].AvailableAt(at, loc)
+		items[i].AvailableNow = &ok
+	}
+}
+
+// menuWindows จำเวลาที่ช่วงขายของเมนูในแต่ละร้านจะเปลี่ยนครั้งถัดไป
+// ถึงเวลาแล้วคำนวณช่วงราคาของร้านใหม่ (price_min/max นับเฉพาะเมนูที่ขายอยู่ตอนนั้น)
+type menuWindows struct {
+	mu       sync.Mutex
+	due      map[string]time.Time
+	lastFull time.Time
+}
+
+var MenuWindows = &menuWindows{due: map[string]time.Time{}}
+
+// track ตั้งเวลาตรวจครั้งถัดไปของร้านจากเมนูทั้งหมด (ไม่มีช่วงขาย = ไม่ต้องตรวจ)
+func (mw *menuWindows) track(shopID string, items []models.MenuItem, loc *time.Location, now time.Time) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+	var next time.Time
+	for i := range items {
+		if at, _, ok := items[i].NextAvailabilityChange(now, loc); ok && (next.IsZero() || at.Before(next)) {
+			next = at
+		}
+	}
+	if next.IsZero() {
+		delete(mw.due, shopID)
+		return
+	}
+	mw.due[shopID] = next
+}
+
+// Run คำนวณช่วงราคาใหม่ของร้านที่ช่วงขายเปลี่ยน คืนจำนวนร้านที่อัปเดต
+func (mw *menuWindows) Run(ctx context.Context, now time.Time) (int, error) {
+	mw.mu.Lock()
+	var ids []string
+	full := now.Sub(mw.lastFull) >= menuWindowFullInterval
+	if full {
+		mw.lastFull = now
+	} else {
+		for id, at := range mw.due {
+			if !at.After(now) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	mw.mu.Unlock()
+
+	if full {
+		shops, err := config.DB.Shops().List(ctx)
+		if err != nil {
+			return 0, err
+		}
+		for i := range shops {
+			if shops[i].IsDeleted() {
+				continue
+			}
+			items, err := config.DB.Menus().List(ctx, shops[i].ID)
+			if err != nil {
+				return 0, err
+			}
+			loc := StockLocation(&shops[i])
+			mw.track(shops[i].ID, items, loc, now)
+			for j := range items {
+				if len(items[j].Availability) > 0 {
+					ids = append(ids, shops[i].ID) // ช่วงขายอาจเปลี่ยนระหว่างที่ไม่ได้ตรวจ
+					break
+				}
+			}
+		}
+	}
+
+	n := 0
+	for _, id := range ids {
+		// คำนวณช่วงราคาแล้ว track ร้านใหม่ในตัว
+		if err := updateShopPriceRangeAt(ctx, id, now); err != nil {
+			log.Println("menu windows: price range", id, err)
+			mw.mu.Lock()
+			delete(mw.due, id)
+			mw.mu.Unlock()
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
+
+// StartMenuWindows รัน MenuWindows ทุก every จนกว่า ctx จะถูกยกเลิก
+func StartMenuWindows(ctx context.Context, every time.Duration) {
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		if _, err := MenuWindows.Run(ctx, time.Now()); err != nil {
+			log.Println("menu windows:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
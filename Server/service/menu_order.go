@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// MenuOrderError = body ของการจัดลำดับ/ย้ายหมวดไม่ถูกต้อง (ตอบ 400)
+type MenuOrderError struct{ Msg string }
+
+func (e *MenuOrderError) Error() string { return e.Msg }
+
+func menuOrderErr(format string, a ...any) error {
+	return &MenuOrderError{Msg: fmt.Sprintf(format, a...)}
+}
+
+// sortMenuItems เรียงตาม position แล้วตามเวลาที่สร้าง (เมนูเก่าที่ยังไม่มี position = 0 ทั้งหมด)
+func sortMenuItems(items []models.MenuItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Position != items[j].Position {
+			return items[i].Position < items[j].Position
+		}
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+}
+
+// GroupMenu จัดเมนูเป็นหมวดตามลำดับ
+// เมนูที่ไม่มีหมวด (หรือหมวดถูกลบไปแล้ว) อยู่ใน uncategorized; flat = ทุกเมนูเรียงตามที่แสดง (หมวดก่อน แล้วไม่มีหมวด)
+func GroupMenu(cats []models.MenuCategory, items []models.MenuItem) (sections []models.MenuSection, uncategorized, flat []models.MenuItem) {
+	sorted := append([]models.MenuItem(nil), items...)
+	sortMenuItems(sorted)
+
+	index := make(map[string]int, len(cats))
+	sections = make([]models.MenuSection, 0, len(cats))
+	for i, c := range cats {
+		index[c.ID] = i
+		sections = append(sections, models.MenuSection{MenuCategory: c, Items: []models.MenuItem{}})
+	}
+	uncategorized = []models.MenuItem{}
+	for _, m := range sorted {
+		if i, ok := index[m.CategoryID]; ok && m.CategoryID != "" {
+			sections[i].Items = append(sections[i].Items, m)
+		} else {
+			uncategorized = append(uncategorized, m)
+		}
+	}
+	flat = make([]models.MenuItem, 0, len(items))
+	for _, s := range sections {
+		flat = append(flat, s.Items...)
+	}
+	flat = append(flat, uncategorized...)
+	return sections, uncategorized, flat
+}
+
+// NextMenuPosition คือ position ท้ายสุดของหมวด categoryID
+func NextMenuPosition(items []models.MenuItem, categoryID string) int {
+	next := 0
+	for _, m := range items {
+		if m.CategoryID == categoryID && m.Position >= next {
+			next = m.Position + 1
+		}
+	}
+	return next
+}
+
+// ReorderMenu จัดลำดับหมวดและเมนู (และย้ายหมวดของเมนู) ใน transaction เดียว
+// หมวดที่มีเมนูถูกส่งมา: เมนูที่ส่งมาเรียงก่อนตามลำดับ แล้วต่อด้วยเมนูเดิมของหมวดที่ไม่ได้ส่งมา
+func ReorderMenu(ctx context.Context, shopID string, req models.MenuOrderReq) error {
+	return config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		cats, err := tx.MenuCategories().List(ctx, shopID)
+		if err != nil {
+			return err
+		}
+		items, err := tx.Menus().List(ctx, shopID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+
+		// ---- ตรวจก่อนเขียน ----
+		catSet := make(map[string]bool, len(cats))
+		for _, c := range cats {
+			catSet[c.ID] = true
+		}
+		if len(req.Categories) > 0 {
+			if len(req.Categories) != len(cats) {
+				return menuOrderErr("categories must list all %d categories", len(cats))
+			}
+			seen := map[string]bool{}
+			for _, id := range req.Categories {
+				if !catSet[id] || seen[id] {
+					return menuOrderErr("unknown or duplicate category %q", id)
+				}
+				seen[id] = true
+			}
+		}
+		byID := make(map[string]models.MenuItem, len(items))
+		for _, m := range items {
+			byID[m.ID] = m
+		}
+		moved := map[string]bool{}
+		touched := map[string]bool{} // หมวดที่ลำดับต้องคำนวณใหม่
+		for _, it := range req.Items {
+			m, ok := byID[it.ID]
+			if !ok || moved[it.ID] {
+				return menuOrderErr("unknown or duplicate menu item %q", it.ID)
+			}
+			if it.CategoryID != "" && !catSet[it.CategoryID] {
+				return menuOrderErr("unknown category %q", it.CategoryID)
+			}
+			moved[it.ID] = true
+			touched[it.CategoryID] = true
+			touched[m.CategoryID] = true
+		}
+
+		// ---- เขียน ----
+		for i, id := range req.Categories {
+			if err := tx.MenuCategories().Update(ctx, shopID, id, map[string]any{"position": i, "updatedAt": now}); err != nil {
+				return err
+			}
+		}
+
+		sortMenuItems(items)
+		for cat := range touched {
+			var order []string
+			for _, it := range req.Items {
+				if it.CategoryID == cat {
+					order = append(order, it.ID)
+				}
+			}
+			for _, m := range items {
+				if m.CategoryID == cat && !moved[m.ID] {
+					order = append(order, m.ID)
+				}
+			}
+			for pos, id := range order {
+				m := byID[id]
+				if m.Position == pos && m.CategoryID == cat {
+					continue
+				}
+				if err := tx.Menus().Update(ctx, shopID, id, map[string]any{
+					"category_id": cat,
+					"position":    pos,
+					"updatedAt":   now,
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteMenuCategory ลบหมวด เมนูในหมวดย้ายไปต่อท้าย "ไม่มีหมวด" (ไม่ลบเมนู)
+func DeleteMenuCategory(ctx context.Context, shopID, categoryID string) (int, error) {
+	moved := 0
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		moved = 0
+		if _, err := tx.MenuCategories().Get(ctx, shopID, categoryID); err != nil {
+			return err
+		}
+		items, err := tx.Menus().List(ctx, shopID)
+		if err != nil {
+			return err
+		}
+		sortMenuItems(items)
+		next := NextMenuPosition(items, "")
+		now := time.Now()
+		for _, m := range items {
+			if m.CategoryID != categoryID {
+				continue
+			}
+			if err := tx.Menus().Update(ctx, shopID, m.ID, map[string]any{
+				"category_id": "",
+				"position":    next,
+				"updatedAt":   now,
+			}); err != nil {
+				return err
+			}
+			next++
+			moved++
+		}
+		return tx.MenuCategories().Delete(ctx, shopID, categoryID)
+	})
+	return moved, err
+}
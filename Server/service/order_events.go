@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+// ชนิดของ event ที่ส่งให้หน้าจอออเดอร์ (vendor OrderShop / user UserOrder)
+const (
+	OrderEventCreated       = "order.created"
+	OrderEventStatusChanged = "order.status_changed"
+	// แจ้งเตือนสต็อก (ส่งเฉพาะ stream ของร้าน)
+	StockEventLow     = "menu.stock_low"
+	StockEventSoldOut = "menu.sold_out"
+	// คิว walk-in: updated = บัตรเปลี่ยน (ร้าน + เจ้าของบัตร), position = ลำดับคิวขยับ (เจ้าของบัตรที่ยังรอ)
+	QueueEventUpdated  = "queue.updated"
+	QueueEventPosition = "queue.position"
+)
+
+const (
+	orderEventBacklog = 100              // เก็บย้อนหลังต่อ topic ไว้ให้ resume
+	orderEventMaxAge  = 15 * time.Minute // เก่ากว่านี้ไม่เก็บไว้ resume
+	orderEventBuffer  = 32               // buffer ต่อ subscriber; เต็ม = ตัดการเชื่อมต่อ ให้ client ต่อใหม่แล้ว resume
+)
+
+// OrderEvent คือข้อมูลหนึ่งรายการใน stream
+// ID เรียงเพิ่มขึ้นทั้ง process ใช้เป็น SSE id / Last-Event-ID
+type OrderEvent struct {
+	ID         uint64              `json:"id"`
+	Type       string              `json:"type"`
+	OrderID    string              `json:"orderId"`
+	ShopID     string              `json:"shopId"`
+	UserID     string              `json:"userId"`
+	Status     string              `json:"status"`
+	PrevStatus string              `json:"prevStatus,omitempty"`
+	At         time.Time           `json:"at"`
+	Order      *models.Order       `json:"order,omitempty"`
+	Stock      *StockChange        `json:"stock,omitempty"`
+	Queue      *models.QueueTicket `json:"queue,omitempty"`
+}
+
+// ShopTopic / UserTopic คือช่องที่ subscribe ได้
+func ShopTopic(shopID string) string { return "shop:" + shopID }
+func UserTopic(userID string) string { return "user:" + userID }
+
+// OrderHub คือ pub/sub ภายใน process (ไม่แชร์ข้าม instance)
+// ถ้า restart เลข ID จะเริ่มใหม่ → client ที่ส่ง Last-Event-ID เก่ามาจะได้ resync
+type OrderHub struct {
+	mu     sync.Mutex
+	seq    uint64
+	topics map[string]*hubTopic
+	// floor = trimmed สูงสุดของ topic ที่ถูกลบไปแล้ว (topic ใหม่ resume ก่อนหน้านี้ไม่ได้)
+	floor uint64
+}
+
+type hubTopic struct {
+	backlog []OrderEvent
+	// trimmed = ID ล่าสุดที่ถูกตัดทิ้งจาก backlog (resume ก่อนหน้านี้ไม่ได้แล้ว)
+	trimmed uint64
+	subs    map[*OrderSub]struct{}
+}
+
+// OrderSub คือ subscriber หนึ่งตัว อ่าน event จาก C จนกว่า C จะถูกปิด
+type OrderSub struct {
+	C <-chan OrderEvent
+	// Backlog คือ event ที่พลาดไปหลัง Last-Event-ID (ส่งก่อนอ่าน C)
+	Backlog []OrderEvent
+	// Resync = resume ไม่ได้ครบ client ควรโหลดรายการใหม่ทั้งหมด
+	Resync bool
+
+	ch    chan OrderEvent
+	hub   *OrderHub
+	topic string
+}
+
+// OrderEvents คือ hub ที่ handler ทุกตัวใช้
+var OrderEvents = NewOrderHub()
+
+func NewOrderHub() *OrderHub {
+	return &OrderHub{topics: map[string]*hubTopic{}}
+}
+
+// PublishOrder ส่ง event ของออเดอร์ไปยังร้านและลูกค้า
+func (h *OrderHub) PublishOrder(typ string, ord *models.Order, prevStatus string) {
+	snap := *ord // event เก็บ snapshot ไม่แชร์ pointer กับผู้เรียก
+	e := OrderEvent{
+		Type:       typ,
+		OrderID:    ord.ID,
+		ShopID:     ord.ShopID,
+		UserID:     ord.CustomerID,
+		Status:     ord.Status,
+		PrevStatus: prevStatus,
+		At:         time.Now(),
+		Order:      &snap,
+	}
+	if e.UserID == "" {
+		e.UserID = ord.UserID
+	}
+	topics := []string{ShopTopic(ord.ShopID), UserTopic(e.UserID)}
+	if ord.UserID != "" && ord.UserID != e.UserID {
+		topics = append(topics, UserTopic(ord.UserID))
+	}
+	h.Publish(e, topics...)
+}
+
+// PublishStock ส่งแจ้งเตือนสต็อกเหลือน้อย/หมดไปยังร้าน
+func (h *OrderHub) PublishStock(shopID string, ch *StockChange) {
+	snap := *ch
+	typ := StockEventLow
+	if ch.SoldOut {
+		typ = StockEventSoldOut
+	}
+	h.Publish(OrderEvent{Type: typ, ShopID: shopID, At: time.Now(), Stock: &snap}, ShopTopic(shopID))
+}
+
+// PublishQueue ส่งบัตรที่เปลี่ยนไปยังร้านและเจ้าของบัตร
+// waiting = บัตรที่ยังรอหลังเปลี่ยน (มี Position แล้ว) ส่งลำดับใหม่ให้เจ้าของแต่ละใบ; nil = คิวไม่ขยับ
+func (h *OrderHub) PublishQueue(t *models.QueueTicket, waiting []models.QueueTicket) {
+	snap := *t
+	now := time.Now()
+	h.Publish(OrderEvent{Type: QueueEventUpdated, ShopID: t.ShopID, UserID: t.UserID, Status: t.Status, At: now, Queue: &snap},
+		ShopTopic(t.ShopID), UserTopic(t.UserID))
+	for i := range waiting {
+		w := waiting[i]
+		if w.ID == t.ID {
+			continue
+		}
+		h.Publish(OrderEvent{Type: QueueEventPosition, ShopID: w.ShopID, UserID: w.UserID, Status: w.Status, At: now, Queue: &w},
+			UserTopic(w.UserID))
+	}
+}
+
+// Publish ตั้ง ID ให้ e แล้วส่งเข้าทุก topic (ID เดียวกัน)
+func (h *OrderHub) Publish(e OrderEvent, topics ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	e.ID = h.seq
+	for _, name := range topics {
+		t := h.topic(name)
+		t.backlog = append(t.backlog, e)
+		t.trim(e.At)
+		for s := range t.subs {
+			select {
+			case s.ch <- e:
+			default:
+				// อ่านไม่ทัน: ปิดทิ้ง client จะต่อใหม่พร้อม Last-Event-ID
+				delete(t.subs, s)
+				close(s.ch)
+			}
+		}
+	}
+}
+
+// Subscribe เริ่มฟัง topic; lastID > 0 = resume ต่อจาก event นั้น
+func (h *OrderHub) Subscribe(topic string, lastID uint64) *OrderSub {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan OrderEvent, orderEventBuffer)
+	s := &OrderSub{C: ch, ch: ch, hub: h, topic: topic}
+
+	t := h.topic(topic)
+	t.trim(time.Now())
+	if lastID > 0 {
+		if lastID > h.seq || lastID < t.trimmed {
+			s.Resync = true
+		}
+		for _, e := range t.backlog {
+			if e.ID > lastID {
+				s.Backlog = append(s.Backlog, e)
+			}
+		}
+	}
+	t.subs[s] = struct{}{}
+	return s
+}
+
+// Close เลิกฟัง (เรียกซ้ำได้)
+func (s *OrderSub) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t, ok := h.topics[s.topic]
+	if !ok {
+		return
+	}
+	if _, ok := t.subs[s]; ok {
+		delete(t.subs, s)
+		close(s.ch)
+	}
+	t.trim(time.Now())
+	h.dropIfIdle(s.topic, t)
+}
+
+// Sweep ตัด backlog ที่หมดอายุของทุก topic แล้วลบ topic ที่ไม่มีคนฟังและไม่มีอะไรให้ resume
+// (topic ที่มีแต่ publish เช่นร้าน/ลูกค้าที่ไม่ได้เปิด stream จะไม่ค้างอยู่ตลอดไป)
+func (h *OrderHub) Sweep(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for name, t := range h.topics {
+		t.trim(now)
+		h.dropIfIdle(name, t)
+	}
+}
+
+// StartOrderEventSweeper รัน Sweep ของ OrderEvents ทุก every จนกว่า ctx จะถูกยกเลิก
+func StartOrderEventSweeper(ctx context.Context, every time.Duration) {
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-tick.C:
+			OrderEvents.Sweep(now)
+		}
+	}
+}
+
+// dropIfIdle ลบ topic ที่ไม่มีคนฟังและ backlog ว่าง; ต้องถือ lock อยู่
+func (h *OrderHub) dropIfIdle(name string, t *hubTopic) {
+	if len(t.subs) > 0 || len(t.backlog) > 0 {
+		return
+	}
+	if t.trimmed > h.floor {
+		h.floor = t.trimmed
+	}
+	delete(h.topics, name)
+}
+
+// topic คืน (หรือสร้าง) topic; ต้องถือ lock อยู่
+func (h *OrderHub) topic(name string) *hubTopic {
+	t, ok := h.topics[name]
+	if !ok {
+		t = &hubTopic{trimmed: h.floor, subs: map[*OrderSub]struct{}{}}
+		h.topics[name] = t
+	}
+	return t
+}
+
+// trim ตัด backlog ที่เกินจำนวนหรือเก่าเกิน
+func (t *hubTopic) trim(now time.Time) {
+	cut := 0
+	if n := len(t.backlog) - orderEventBacklog; n > 0 {
+		cut = n
+	}
+	for cut < len(t.backlog) && now.Sub(t.backlog[cut].At) > orderEventMaxAge {
+		cut++
+	}
+	if cut == 0 {
+		return
+	}
+	t.trimmed = t.backlog[cut-1].ID
+	t.backlog = append([]OrderEvent(nil), t.backlog[cut:]...)
+}
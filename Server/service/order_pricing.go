@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// เหตุผลที่รายการสั่งซื้อใช้ไม่ได้
+const (
+	ItemNotFound  = "not_found"
+	ItemInactive  = "inactive"
+	ItemWrongShop = "wrong_shop"
+	ItemBadQty    = "bad_qty"
+	ItemBadOption = "bad_options"
+	ItemSoldOut   = "sold_out"
+	ItemLowStock  = "insufficient_stock"
+	ItemNotNow    = "not_available_now"
+)
+
+// ItemProblem คือรายการที่ resolve กับเมนูจริงไม่ผ่าน
+type ItemProblem struct {
+	MenuID string `json:"menuId"`
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail,omitempty"`
+	// มีเมื่อ Reason = not_available_now: เวลาที่เริ่มขายรอบถัดไป
+	NextAvailable *time.Time `json:"next_available,omitempty"`
+}
+
+// PriceChange คือรายการที่ราคาฝั่ง client ไม่ตรงกับราคาในเมนู
+type PriceChange struct {
+	MenuID      string  `json:"menuId"`
+	Name        string  `json:"name"`
+	QuotedPrice float64 `json:"quoted_price"`
+	Price       float64 `json:"price"`
+}
+
+// ItemsUnavailableError: มีรายการที่หาย/ปิดขาย/มาจากร้านอื่น
+type ItemsUnavailableError struct {
+	ShopID   string
+	Problems []ItemProblem
+}
+
+func (e *ItemsUnavailableError) Error() string {
+	parts := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		parts = append(parts, p.MenuID+":"+p.Reason)
+	}
+	return "items unavailable: " + strings.Join(parts, ", ")
+}
+
+// PriceChangedError: ราคาเปลี่ยน และลูกค้ายังไม่ได้ยอมรับ
+type PriceChangedError struct {
+	Changes []PriceChange
+	Total   float64
+}
+
+func (e *PriceChangedError) Error() string {
+	return fmt.Sprintf("prices changed for %d item(s), new total %.2f", len(e.Changes), e.Total)
+}
+
+// PricedItems คือผลลัพธ์ที่ใช้ราคาจากเมนูจริงแล้ว
+type PricedItems struct {
+	Items   []models.OrderItem
+	Total   float64
+	Changes []PriceChange
+	Menus   map[string]*models.MenuItem // เมนูที่อ่านมาแล้ว (menuId) ใช้ต่อในการตัดสต็อก
+}
+
+// PriceOrderItems resolve ทุกรายการกับ shops/{shopID}/menu/{menuId}
+// ใช้ราคา/ชื่อ/รูปจากเมนู ไม่เชื่อค่าที่ client ส่งมา
+//   - itemShops (ถ้ามี) คือ shopId ที่ติดมากับแต่ละรายการ เช่นจาก cart ใช้จับของร้านอื่น
+//   - ราคาไม่ตรงและ acceptChanges=false → *PriceChangedError
+//
+// ทุก read ผ่าน repos เพื่อให้เรียกใน transaction ได้ (อ่านก่อนเขียน)
+func PriceOrderItems(ctx context.Context, repos store.Repos, shopID string, items []models.OrderItem, itemShops []string, acceptChanges bool) (*PricedItems, error) {
+	out := &PricedItems{Items: make([]models.OrderItem, 0, len(items)), Changes: []PriceChange{}, Menus: map[string]*models.MenuItem{}}
+	var problems []ItemProblem
+
+	for i, it := range items {
+		if it.Qty <= 0 {
+			problems = append(problems, ItemProblem{MenuID: it.ID, Name: it.Name, Reason: ItemBadQty})
+			continue
+		}
+		if i < len(itemShops) && itemShops[i] != "" && itemShops[i] != shopID {
+			problems = append(problems, ItemProblem{MenuID: it.ID, Name: it.Name, Reason: ItemWrongShop})
+			continue
+		}
+
+		menu, err := repos.Menus().Get(ctx, shopID, it.ID)
+		if errors.Is(err, store.ErrNotFound) {
+			problems = append(problems, ItemProblem{MenuID: it.ID, Name: it.Name, Reason: ItemNotFound})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if menu.ShopID != "" && menu.ShopID != shopID {
+			problems = append(problems, ItemProblem{MenuID: it.ID, Name: menu.Name, Reason: ItemWrongShop})
+			continue
+		}
+		if !menu.Active {
+			problems = append(problems, ItemProblem{MenuID: it.ID, Name: menu.Name, Reason: ItemInactive})
+			continue
+		}
+
+		opts, err := ResolveOptions(menu, it.Options)
+		if err != nil {
+			problems = append(problems, ItemProblem{MenuID: it.ID, Name: menu.Name, Reason: ItemBadOption, Detail: err.Error()})
+			continue
+		}
+		unit := menu.Price + models.OptionsTotal(opts)
+		if unit < 0 {
+			problems = append(problems, ItemProblem{MenuID: it.ID, Name: menu.Name, Reason: ItemBadOption, Detail: "price with options is negative"})
+			continue
+		}
+
+		// ราคา 0 = client ไม่ได้ส่งราคามา ไม่นับว่าเปลี่ยน
+		if it.Price > 0 && !samePrice(it.Price, unit) {
+			out.Changes = append(out.Changes, PriceChange{
+				MenuID:      it.ID,
+				Name:        menu.Name,
+				QuotedPrice: it.Price,
+				Price:       unit,
+			})
+		}
+
+		line := it
+		line.Name = menu.Name
+		line.Price = unit
+		line.Options = opts
+		line.BasePrice = 0
+		if len(opts) > 0 {
+			line.BasePrice = menu.Price
+		}
+		line.Extras = nil // ไม่เก็บข้อมูลที่ไม่ได้ตรวจ ใช้ Options แทน
+		if menu.Image != "" {
+			line.Image = menu.Image
+		}
+		if menu.Description != "" {
+			line.Description = menu.Description
+		}
+		out.Items = append(out.Items, line)
+		out.Total += unit * float64(it.Qty)
+		out.Menus[it.ID] = menu
+	}
+
+	if len(problems) > 0 {
+		return nil, &ItemsUnavailableError{ShopID: shopID, Problems: problems}
+	}
+	if len(out.Changes) > 0 && !acceptChanges {
+		return nil, &PriceChangedError{Changes: out.Changes, Total: out.Total}
+	}
+	return out, nil
+}
+
+// ResolveOptions ตรวจตัวเลือกที่ลูกค้าเลือกกับ option_groups ของเมนู (กลุ่ม/ตัวเลือกต้องมีจริง, ไม่ซ้ำ, จำนวนอยู่ใน min/max)
+// คืนตัวเลือกพร้อมชื่อและ price_delta จากเมนู เรียงตามลำดับในเมนู
+func ResolveOptions(menu *models.MenuItem, sel []models.SelectedOption) ([]models.SelectedOption, error) {
+	picked := map[string]bool{}
+	for _, s := range sel {
+		if !hasOption(menu, s) {
+			return nil, fmt.Errorf("unknown option %s/%s", s.GroupID, s.OptionID)
+		}
+		key := s.GroupID + "\x00" + s.OptionID
+		if picked[key] {
+			return nil, fmt.Errorf("option %s/%s selected twice", s.GroupID, s.OptionID)
+		}
+		picked[key] = true
+	}
+
+	out := make([]models.SelectedOption, 0, len(sel))
+	for _, g := range menu.OptionGroups {
+		n := 0
+		for _, o := range g.Options {
+			if !picked[g.ID+"\x00"+o.ID] {
+				continue
+			}
+			out = append(out, models.SelectedOption{
+				GroupID:    g.ID,
+				OptionID:   o.ID,
+				GroupName:  g.Name,
+				Name:       o.Name,
+				PriceDelta: o.PriceDelta,
+			})
+			n++
+		}
+		if n < g.Min {
+			return nil, fmt.Errorf("%s: choose at least %d", g.Name, g.Min)
+		}
+		if g.Max > 0 && n > g.Max {
+			return nil, fmt.Errorf("%s: choose at most %d", g.Name, g.Max)
+		}
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+func hasOption(menu *models.MenuItem, s models.SelectedOption) bool {
+	for _, g := range menu.OptionGroups {
+		if g.ID != s.GroupID {
+			continue
+		}
+		for _, o := range g.Options {
+			if o.ID == s.OptionID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// samePrice เทียบราคาระดับสตางค์ กัน float ปัดเศษ
+func samePrice(a, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+func TestResolveOptions(t *testing.T) {
+	menu := &models.MenuItem{
+		ID: "m1",
+		OptionGroups: []models.OptionGroup{
+			{ID: "size", Name: "Size", Min: 1, Max: 1, Options: []models.MenuOption{
+				{ID: "M", Name: "Medium"},
+				{ID: "L", Name: "Large", PriceDelta: 10},
+			}},
+			{ID: "top", Name: "Toppings", Max: 2, Options: []models.MenuOption{
+				{ID: "egg", Name: "Fried egg", PriceDelta: 10},
+				{ID: "pork", Name: "Extra pork", PriceDelta: 20},
+				{ID: "rice", Name: "Extra rice", PriceDelta: 5},
+			}},
+		},
+	}
+	noGroups := &models.MenuItem{ID: "m2"}
+	sel := func(pairs ...string) []models.SelectedOption {
+		var out []models.SelectedOption
+		for i := 0; i+1 < len(pairs); i += 2 {
+			out = append(out, models.SelectedOption{GroupID: pairs[i], OptionID: pairs[i+1]})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		menu    *models.MenuItem
+		sel     []models.SelectedOption
+		want    []string // group/option ตามลำดับที่คืน
+		wantErr bool
+	}{
+		{"required only", menu, sel("size", "L"), []string{"size/L"}, false},
+		{"menu order, not request order", menu, sel("top", "pork", "top", "egg", "size", "M"), []string{"size/M", "top/egg", "top/pork"}, false},
+		{"missing required group", menu, sel("top", "egg"), nil, true},
+		{"too many in group", menu, sel("size", "M", "size", "L"), nil, true},
+		{"over max toppings", menu, sel("size", "M", "top", "egg", "top", "pork", "top", "rice"), nil, true},
+		{"duplicate option", menu, sel("size", "M", "top", "egg", "top", "egg"), nil, true},
+		{"unknown option", menu, sel("size", "XL"), nil, true},
+		{"unknown group", menu, sel("size", "M", "sauce", "chili"), nil, true},
+		{"no groups, no options", noGroups, nil, nil, false},
+		{"no groups, option sent", noGroups, sel("size", "M"), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveOptions(tt.menu, tt.sel)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("got %+v, want nil", got)
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %v", got, tt.want)
+			}
+			for i, o := range got {
+				if o.GroupID+"/"+o.OptionID != tt.want[i] {
+					t.Fatalf("option %d = %s/%s, want %s", i, o.GroupID, o.OptionID, tt.want[i])
+				}
+			}
+		})
+	}
+
+	// ชื่อและราคาเติมจากเมนู ไม่เชื่อค่าที่ client ส่งมา
+	got, err := ResolveOptions(menu, []models.SelectedOption{{GroupID: "size", OptionID: "L", Name: "Free", PriceDelta: -100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Name != "Large" || got[0].GroupName != "Size" || got[0].PriceDelta != 10 {
+		t.Fatalf("resolved option = %+v", got[0])
+	}
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+const (
+	otpLength      = 6
+	otpTTL         = 5 * time.Minute
+	otpMaxAttempts = 5 // ใส่ผิดครบแล้วล็อก ต้องขอรหัสใหม่
+
+	otpResendGap   = 60 * time.Second // ขอรหัสใหม่ได้ไม่ถี่กว่านี้ (ต่อ email + purpose)
+	otpSendWindow  = time.Hour
+	otpMaxPerEmail = 5  // ต่อ email + purpose ใน 1 ชั่วโมง
+	otpMaxPerIP    = 20 // ต่อ IP ใน 1 ชั่วโมง (ทุก email รวมกัน)
+
+	otpTicketTTL = 10 * time.Minute
+)
+
+var (
+	ErrOTPNotFound = errors.New("otp not found")
+	ErrOTPExpired  = errors.New("otp expired")
+	ErrOTPLocked   = errors.New("too many attempts, request a new code")
+
+	ErrInvalidTicket = errors.New("invalid or expired ticket")
+)
+
+// OTPRateLimitError: ขอรหัสถี่เกินไป
+type OTPRateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *OTPRateLimitError) Error() string {
+	return fmt.Sprintf("too many OTP requests, retry after %ds", int(e.RetryAfter.Seconds()+0.5))
+}
+
+// OTPInvalidError: รหัสผิด พร้อมจำนวนครั้งที่เหลือ
+type OTPInvalidError struct {
+	AttemptsLeft int
+}
+
+func (e *OTPInvalidError) Error() string {
+	return fmt.Sprintf("invalid otp, %d attempt(s) left", e.AttemptsLeft)
+}
+
+// NormalizeEmail ใช้เป็น key ของ OTP ให้ตรงกันทุกครั้ง
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IssueOTP สร้างรหัสใหม่ (แทนตัวเก่า) แล้วคืนรหัสจริงไว้ส่งทางอีเมลเท่านั้น ห้ามส่งกลับใน response
+func IssueOTP(ctx context.Context, purpose, email, ip string) (string, error) {
+	if !models.IsOTPPurpose(purpose) {
+		return "", fmt.Errorf("unknown otp purpose %q", purpose)
+	}
+	email = NormalizeEmail(email)
+	if wait := otpIPLimiter.wait(ip, time.Now()); wait > 0 {
+		return "", &OTPRateLimitError{RetryAfter: wait}
+	}
+
+	code, err := generateNumericOTP(otpLength)
+	if err != nil {
+		return "", err
+	}
+
+	err = config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		nowT := time.Now()
+		rec := &models.OTPRecord{Email: email, Purpose: purpose, WindowStart: nowT}
+
+		prev, err := tx.OTPs().Get(ctx, purpose, email)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		if prev != nil {
+			if gap := prev.CreatedAt.Add(otpResendGap).Sub(nowT); gap > 0 {
+				return &OTPRateLimitError{RetryAfter: gap}
+			}
+			if nowT.Before(prev.WindowStart.Add(otpSendWindow)) {
+				if prev.SendCount >= otpMaxPerEmail {
+					return &OTPRateLimitError{RetryAfter: prev.WindowStart.Add(otpSendWindow).Sub(nowT)}
+				}
+				rec.WindowStart = prev.WindowStart
+				rec.SendCount = prev.SendCount
+			}
+		}
+
+		rec.CodeHash = hashOTP(purpose, email, code)
+		rec.CreatedAt = nowT
+		rec.ExpireAt = nowT.Add(otpTTL)
+		rec.SendCount++
+		return tx.OTPs().Put(ctx, rec)
+	})
+	if err != nil {
+		return "", err
+	}
+	otpIPLimiter.add(ip, time.Now())
+	return code, nil
+}
+
+// VerifyOTP ตรวจรหัส: ถูก → ล้าง hash ทันที (ใช้ได้ครั้งเดียว), ผิด → นับครั้ง และล็อกเมื่อครบ limit
+// record ไม่ถูกลบ ตัวนับการขอรหัสซ้ำ (resend gap / ต่อชั่วโมง) จึงยังมีผล
+func VerifyOTP(ctx context.Context, purpose, email, code string) error {
+	if !models.IsOTPPurpose(purpose) {
+		return fmt.Errorf("unknown otp purpose %q", purpose)
+	}
+	email = NormalizeEmail(email)
+	code = strings.TrimSpace(code)
+
+	// ผลลัพธ์ถูกเก็บนอก tx เพราะการนับครั้งที่ผิดต้อง commit ด้วย (คืน error จาก tx = rollback)
+	var result error
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		result = nil
+		rec, err := tx.OTPs().Get(ctx, purpose, email)
+		if errors.Is(err, store.ErrNotFound) {
+			result = ErrOTPNotFound
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// รหัสถูกใช้ไปแล้ว (เหลือแค่ตัวนับ)
+		if rec.CodeHash == "" {
+			result = ErrOTPNotFound
+			return nil
+		}
+		if rec.Locked {
+			result = ErrOTPLocked
+			return nil
+		}
+		if time.Now().After(rec.ExpireAt) {
+			result = ErrOTPExpired
+			return nil
+		}
+
+		if code == "" || !hmac.Equal([]byte(rec.CodeHash), []byte(hashOTP(purpose, email, code))) {
+			rec.Attempts++
+			if rec.Attempts >= otpMaxAttempts {
+				rec.Locked = true
+				result = ErrOTPLocked
+			} else {
+				result = &OTPInvalidError{AttemptsLeft: otpMaxAttempts - rec.Attempts}
+			}
+			return tx.OTPs().Put(ctx, rec)
+		}
+
+		rec.CodeHash = ""
+		return tx.OTPs().Put(ctx, rec)
+	})
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+// IssueOTPTicket ออก ticket หลัง VerifyOTP ผ่าน (แทน ticket เก่าของ email + purpose เดียวกัน)
+// ticket จริงคืนให้ client ครั้งเดียว ฝั่งเราเก็บแค่ hash
+func IssueOTPTicket(ctx context.Context, purpose, email string) (string, error) {
+	if !models.IsOTPPurpose(purpose) {
+		return "", fmt.Errorf("unknown otp purpose %q", purpose)
+	}
+	email = NormalizeEmail(email)
+	ticket, _, err := newRefreshSecret()
+	if err != nil {
+		return "", err
+	}
+	key := models.OTPTicketPurpose(purpose)
+	nowT := time.Now()
+	err = config.DB.OTPs().Put(ctx, &models.OTPRecord{
+		Email:     email,
+		Purpose:   key,
+		CodeHash:  hashOTP(key, email, ticket),
+		CreatedAt: nowT,
+		ExpireAt:  nowT.Add(otpTicketTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// CheckOTPTicket ตรวจ ticket ภายใน tx (อ่านอย่างเดียว)
+// ผู้เรียกต้องลบด้วย ConsumeOTPTicket ใน tx เดียวกันเพื่อให้ใช้ได้ครั้งเดียว
+func CheckOTPTicket(ctx context.Context, tx store.Repos, purpose, email, ticket string) error {
+	email = NormalizeEmail(email)
+	key := models.OTPTicketPurpose(purpose)
+	rec, err := tx.OTPs().Get(ctx, key, email)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrInvalidTicket
+	}
+	if err != nil {
+		return err
+	}
+	if ticket == "" || time.Now().After(rec.ExpireAt) ||
+		!hmac.Equal([]byte(rec.CodeHash), []byte(hashOTP(key, email, ticket))) {
+		return ErrInvalidTicket
+	}
+	return nil
+}
+
+// ConsumeOTPTicket ลบ ticket (เรียกหลัง CheckOTPTicket ผ่าน)
+func ConsumeOTPTicket(ctx context.Context, tx store.Repos, purpose, email string) error {
+	return tx.OTPs().Delete(ctx, models.OTPTicketPurpose(purpose), NormalizeEmail(email))
+}
+
+// hashOTP = HMAC(secret, purpose|email|code) — รหัส 6 หลักถ้า hash เปล่า ๆ เดาย้อนได้ทันที
+func hashOTP(purpose, email, code string) string {
+	key := os.Getenv("OTP_SECRET")
+	if key == "" {
+		key = os.Getenv("JWT_SECRET")
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(purpose + "|" + email + "|" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+/* ---------------- per-IP limiter (in-process) ---------------- */
+
+type ipLimiter struct {
+	mu    sync.Mutex
+	sends map[string][]time.Time
+}
+
+var otpIPLimiter = &ipLimiter{sends: map[string][]time.Time{}}
+
+// recent คืนเวลาที่ส่งภายใน window (และล้างของเก่าทิ้ง) ต้องถือ lock อยู่
+func (l *ipLimiter) recent(ip string, now time.Time) []time.Time {
+	list := l.sends[ip]
+	i := 0
+	for i < len(list) && now.Sub(list[i]) >= otpSendWindow {
+		i++
+	}
+	list = list[i:]
+	if len(list) == 0 {
+		delete(l.sends, ip)
+	} else {
+		l.sends[ip] = list
+	}
+	return list
+}
+
+// wait คืนเวลาที่ต้องรอ (0 = ส่งได้)
+func (l *ipLimiter) wait(ip string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	list := l.recent(ip, now)
+	if len(list) < otpMaxPerIP {
+		return 0
+	}
+	return list[0].Add(otpSendWindow).Sub(now)
+}
+
+func (l *ipLimiter) add(ip string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sends[ip] = append(l.recent(ip, now), now)
+}
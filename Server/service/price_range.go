@@ -2,70 +2,25 @@ package service
 
 import (
 	"context"
-	"errors"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
 )
 
-func asFloat(v any) (float64, bool) {
-	switch x := v.(type) {
-	case float64:
-		return x, true
-	case int64:
-		return float64(x), true
-	case int:
-		return float64(x), true
-	default:
-		return 0, false
-	}
-}
-
-func getMinMaxWithIndex(ctx context.Context, shopId string) (min *float64, max *float64, count int, err error) {
-	col := config.Client.Collection(models.ColShops).Doc(shopId).Collection(models.SubColMenu)
-
-	minSnap, err := col.Where("active", "==", true).OrderBy("price", firestore.Asc).Limit(1).Documents(ctx).GetAll()
-	if err != nil {
-		return nil, nil, 0, err
-	}
-	maxSnap, err := col.Where("active", "==", true).OrderBy("price", firestore.Desc).Limit(1).Documents(ctx).GetAll()
-	if err != nil {
-		return nil, nil, 0, err
-	}
-	allSnap, err := col.Where("active", "==", true).Documents(ctx).GetAll()
+// getMinMax สแกนเมนูที่ขายได้ ณ เวลา now (active, ยังไม่ sold out, อยู่ในช่วงขาย) ของร้าน แล้วคืนราคาต่ำสุด/สูงสุด และจำนวนเมนู
+func getMinMax(ctx context.Context, shopId string, loc *time.Location, now time.Time) (min *float64, max *float64, count int, err error) {
+	items, err := config.DB.Menus().List(ctx, shopId)
 	if err != nil {
 		return nil, nil, 0, err
 	}
-	count = len(allSnap)
-
-	if len(minSnap) == 0 || len(maxSnap) == 0 {
-		return nil, nil, count, nil
-	}
-	minV, ok1 := asFloat(minSnap[0].Data()["price"])
-	maxV, ok2 := asFloat(maxSnap[0].Data()["price"])
-	if !ok1 || !ok2 {
-		return nil, nil, 0, errors.New("invalid price type")
-	}
-	return &minV, &maxV, count, nil
-}
-
-func getMinMaxByScan(ctx context.Context, shopId string) (min *float64, max *float64, count int, err error) {
-	col := config.Client.Collection(models.ColShops).Doc(shopId).Collection(models.SubColMenu)
-	snaps, err := col.Where("active", "==", true).Documents(ctx).GetAll()
-	if err != nil {
-		return nil, nil, 0, err
-	}
-	count = len(snaps)
-	if count == 0 {
-		return nil, nil, 0, nil
-	}
-	for _, s := range snaps {
-		p, ok := asFloat(s.Data()["price"])
-		if !ok {
+	MenuWindows.track(shopId, items, loc, now)
+	for _, it := range items {
+		if !it.Available() || !it.AvailableAt(now, loc) {
 			continue
 		}
+		count++
+		p := it.Price
 		if min == nil || p < *min {
 			pp := p
 			min = &pp
@@ -78,32 +33,32 @@ func getMinMaxByScan(ctx context.Context, shopId string) (min *float64, max *flo
 	return
 }
 
+// UpdateShopPriceRange คำนวณช่วงราคาจากเมนูที่ขายได้ตอนนี้ (ช่วงขายเปลี่ยน → MenuWindows เรียกใหม่ให้)
 func UpdateShopPriceRange(ctx context.Context, shopId string) error {
-	min, max, count, err := getMinMaxWithIndex(ctx, shopId)
+	return updateShopPriceRangeAt(ctx, shopId, time.Now())
+}
+
+func updateShopPriceRangeAt(ctx context.Context, shopId string, now time.Time) error {
+	var shop *models.Shop
+	if s, err := config.DB.Shops().Get(ctx, shopId); err == nil {
+		shop = s
+	}
+	min, max, count, err := getMinMax(ctx, shopId, StockLocation(shop), now)
 	if err != nil {
-		// fallback หากไม่มี index ฯลฯ
-		min, max, count, err = getMinMaxByScan(ctx, shopId)
-		if err != nil {
-			return err
-		}
+		return err
 	}
 
-	updates := []firestore.Update{
-		{Path: "updatedAt", Value: time.Now()},
-		{Path: "menu_active_count", Value: count},
+	updates := map[string]any{
+		"updatedAt":         time.Now(),
+		"menu_active_count": count,
 	}
 	if min == nil || max == nil {
-		updates = append(updates,
-			firestore.Update{Path: "price_min", Value: nil},
-			firestore.Update{Path: "price_max", Value: nil},
-		)
+		updates["price_min"] = nil
+		updates["price_max"] = nil
 	} else {
-		updates = append(updates,
-			firestore.Update{Path: "price_min", Value: *min},
-			firestore.Update{Path: "price_max", Value: *max},
-		)
+		updates["price_min"] = *min
+		updates["price_max"] = *max
 	}
 
-	_, err = config.Client.Collection(models.ColShops).Doc(shopId).Update(ctx, updates)
-	return err
+	return config.DB.Shops().Update(ctx, shopId, updates)
 }
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// code ของ QueueError
+const (
+	QueueAlreadyInCode = "ALREADY_IN_QUEUE"
+	QueueEmptyCode     = "QUEUE_EMPTY"
+	QueueIllegalCode   = "ILLEGAL_TRANSITION"
+)
+
+// QueueError = ทำรายการกับคิวไม่ได้ (Conflict = true → 409, ไม่งั้น 400)
+type QueueError struct {
+	Code     string
+	Message  string
+	Conflict bool
+	Ticket   *models.QueueTicket // ALREADY_IN_QUEUE: บัตรที่ถืออยู่
+}
+
+func (e *QueueError) Error() string { return e.Message }
+
+// withPositions ตั้ง Position ให้บัตรที่รออยู่ (active เรียงตามเวลารับบัตรแล้ว) คืนเฉพาะบัตรที่รอ
+func withPositions(active []models.QueueTicket) []models.QueueTicket {
+	waiting := make([]models.QueueTicket, 0, len(active))
+	for i := range active {
+		active[i].Position = 0
+		if active[i].Status == models.QueueWaiting {
+			active[i].Position = len(waiting) + 1
+			waiting = append(waiting, active[i])
+		}
+	}
+	return waiting
+}
+
+// fillPosition ตั้ง Position ของ t จากบัตรที่ยังอยู่ในคิวของร้าน
+func fillPosition(t *models.QueueTicket, active []models.QueueTicket) {
+	t.Position = 0
+	for _, w := range withPositions(active) {
+		if w.ID == t.ID {
+			t.Position = w.Position
+		}
+	}
+}
+
+// ShopQueue คืนบัตรที่ยังอยู่ในคิวของร้าน (called ก่อน แล้วตามด้วย waiting ตามลำดับ)
+func ShopQueue(ctx context.Context, shopID string) ([]models.QueueTicket, error) {
+	active, err := config.DB.Queue().ListActive(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+	waiting := withPositions(active)
+	out := make([]models.QueueTicket, 0, len(active))
+	for _, t := range active {
+		if t.Status == models.QueueCalled {
+			out = append(out, t)
+		}
+	}
+	return append(out, waiting...), nil
+}
+
+// QueueTicketWithPosition อ่านบัตรพร้อมลำดับปัจจุบัน
+func QueueTicketWithPosition(ctx context.Context, t *models.QueueTicket) error {
+	if !t.IsActive() {
+		t.Position = 0
+		return nil
+	}
+	active, err := config.DB.Queue().ListActive(ctx, t.ShopID)
+	if err != nil {
+		return err
+	}
+	fillPosition(t, active)
+	return nil
+}
+
+// TakeQueueTicket ออกบัตรคิวใหม่ (ร้านต้องเปิดและเปิดรับคิวอยู่) เลขคิวเรียงต่อกันในแต่ละวัน
+// user ถือบัตรที่ยังไม่จบได้ร้านละหนึ่งใบ
+func TakeQueueTicket(ctx context.Context, shopID, userID string, req models.TakeQueueTicketReq, now time.Time) (*models.QueueTicket, error) {
+	var out models.QueueTicket
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		s, err := tx.Shops().Get(ctx, shopID)
+		if err != nil {
+			return err
+		}
+		if err := CheckShopOpen(s, ShopServiceQueue, now); err != nil {
+			return err
+		}
+		active, err := tx.Queue().ListActive(ctx, shopID)
+		if err != nil {
+			return err
+		}
+		for i := range active {
+			if active[i].UserID == userID {
+				fillPosition(&active[i], active)
+				return &QueueError{Code: QueueAlreadyInCode, Message: "you already have a ticket in this queue", Conflict: true, Ticket: &active[i]}
+			}
+		}
+
+		day := models.StockDay(now, StockLocation(s))
+		n, err := tx.Queue().NextNumber(ctx, shopID, day)
+		if err != nil {
+			return err
+		}
+		out = models.QueueTicket{
+			ShopID:    shopID,
+			UserID:    userID,
+			Number:    n,
+			Day:       day,
+			People:    req.People,
+			Note:      req.Note,
+			Status:    models.QueueWaiting,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := tx.Queue().Create(ctx, &out); err != nil {
+			return err
+		}
+		out.Position = len(withPositions(active)) + 1
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	OrderEvents.PublishQueue(&out, nil) // บัตรใหม่ต่อท้าย คิวคนอื่นไม่ขยับ
+	return &out, nil
+}
+
+// MoveQueueTicket เปลี่ยนสถานะบัตร
+//   - ร้าน (ActorVendor): shopID = ร้านของผู้เรียก บัตรต้องเป็นของร้านนี้
+//   - ลูกค้า (ActorCustomer): บัตรต้องเป็นของ callerID
+//
+// บัตรที่ไม่ใช่ของผู้เรียกถือว่าไม่พบ (store.ErrNotFound)
+func MoveQueueTicket(ctx context.Context, shopID, ticketID, to string, actor models.OrderActor, callerID string, now time.Time) (*models.QueueTicket, error) {
+	var (
+		out  models.QueueTicket
+		prev string
+	)
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		t, err := tx.Queue().Get(ctx, ticketID)
+		if err != nil {
+			return err
+		}
+		if (actor == models.ActorVendor && t.ShopID != shopID) || (actor == models.ActorCustomer && t.UserID != callerID) {
+			return store.ErrNotFound
+		}
+		prev = t.Status
+		if err := moveTicket(ctx, tx, t, to, actor, now); err != nil {
+			return err
+		}
+		out = *t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	publishQueueMove(ctx, &out, prev == models.QueueWaiting)
+	return &out, nil
+}
+
+// CallNextQueueTicket เรียกบัตรที่รอนานที่สุดของร้าน
+func CallNextQueueTicket(ctx context.Context, shopID string, now time.Time) (*models.QueueTicket, error) {
+	var out models.QueueTicket
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		active, err := tx.Queue().ListActive(ctx, shopID)
+		if err != nil {
+			return err
+		}
+		waiting := withPositions(active)
+		if len(waiting) == 0 {
+			return &QueueError{Code: QueueEmptyCode, Message: "no one is waiting", Conflict: true}
+		}
+		t := waiting[0]
+		if err := moveTicket(ctx, tx, &t, models.QueueCalled, models.ActorVendor, now); err != nil {
+			return err
+		}
+		out = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	publishQueueMove(ctx, &out, true)
+	return &out, nil
+}
+
+// moveTicket ตรวจเส้นทางแล้วเขียนสถานะใหม่ (ต้องอ่านอย่างอื่นใน transaction ครบแล้ว)
+func moveTicket(ctx context.Context, tx store.Repos, t *models.QueueTicket, to string, actor models.OrderActor, now time.Time) error {
+	if !models.CanMoveQueueTicket(t.Status, to, actor) {
+		return &QueueError{Code: QueueIllegalCode, Message: fmt.Sprintf("%s may not move ticket %s -> %s", actor, t.Status, to), Conflict: true}
+	}
+	fields := map[string]any{"status": to, "updatedAt": now}
+	if to == models.QueueCalled {
+		fields["called_at"] = now
+		t.CalledAt = &now
+	}
+	if err := tx.Queue().Update(ctx, t.ID, fields); err != nil {
+		return err
+	}
+	t.Status, t.UpdatedAt, t.Position = to, now, 0
+	return nil
+}
+
+// publishQueueMove แจ้งบัตรที่เปลี่ยน และถ้าบัตรออกจากกลุ่มที่รอ (คิวขยับ) แจ้งลำดับใหม่ของคนที่ยังรอด้วย
+func publishQueueMove(ctx context.Context, t *models.QueueTicket, moved bool) {
+	var active []models.QueueTicket
+	var err error
+	if moved {
+		active, err = config.DB.Queue().ListActive(ctx, t.ShopID)
+	}
+	if !moved || err != nil {
+		OrderEvents.PublishQueue(t, nil)
+		return
+	}
+	OrderEvents.PublishQueue(t, withPositions(active))
+}
+
+/* ---------------- expiry ---------------- */
+
+// queueExpirer จำวันล่าสุดที่ปิดบัตรค้างของแต่ละร้าน (แบบเดียวกับ stockResetter)
+type queueExpirer struct {
+	mu   sync.Mutex
+	days map[string]string
+}
+
+var QueueExpirer = &queueExpirer{days: map[string]string{}}
+
+// Run ปิดบัตรที่ค้างอยู่ในคิวจากวันก่อน ๆ (-> skipped) ของร้านที่ขึ้นวันใหม่ (ตามเวลาท้องถิ่นของร้าน)
+// คืนจำนวนบัตรที่ปิด
+func (qe *queueExpirer) Run(ctx context.Context, now time.Time) (int, error) {
+	qe.mu.Lock()
+	defer qe.mu.Unlock()
+
+	shops, err := config.DB.Shops().List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := range shops {
+		s := &shops[i]
+		if s.IsDeleted() {
+			continue
+		}
+		day := models.StockDay(now, StockLocation(s))
+		if qe.days[s.ID] == day {
+			continue
+		}
+		closed, err := expireQueueTickets(ctx, s.ID, day, now)
+		if err != nil {
+			return n, err
+		}
+		qe.days[s.ID] = day
+		n += closed
+	}
+	return n, nil
+}
+
+// expireQueueTickets ปิดบัตรของร้านที่ออกก่อนวัน day แล้วแจ้งเจ้าของบัตร + ลำดับใหม่ของคนที่ยังรอ
+func expireQueueTickets(ctx context.Context, shopID, day string, now time.Time) (int, error) {
+	var closed []models.QueueTicket
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		active, err := tx.Queue().ListActive(ctx, shopID)
+		if err != nil {
+			return err
+		}
+		closed = closed[:0]
+		for i := range active {
+			t := active[i]
+			if t.Day >= day {
+				continue
+			}
+			if err := moveTicket(ctx, tx, &t, models.QueueSkipped, models.ActorSystem, now); err != nil {
+				return err
+			}
+			closed = append(closed, t)
+		}
+		return nil
+	})
+	if err != nil || len(closed) == 0 {
+		return 0, err
+	}
+	for i := range closed[:len(closed)-1] {
+		OrderEvents.PublishQueue(&closed[i], nil)
+	}
+	publishQueueMove(ctx, &closed[len(closed)-1], true)
+	return len(closed), nil
+}
+
+// StartQueueExpirer ปิดบัตรคิวค้างข้ามวันทุก every จนกว่า ctx จะถูกยกเลิก
+func StartQueueExpirer(ctx context.Context, every time.Duration) {
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		if n, err := QueueExpirer.Run(ctx, time.Now()); err != nil {
+			log.Println("queue expiry:", err)
+		} else if n > 0 {
+			log.Printf("queue expiry: %d tickets skipped", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
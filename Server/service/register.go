@@ -1,44 +1,38 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"log"
 	"time"
+	"unicode"
 
-	"cloud.google.com/go/firestore"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
 	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// เครดิตเริ่มต้นของ user ใหม่
+const signupCredit = 10000
+
 func CreateUser(c *fiber.Ctx) error {
 	user := new(models.User)
-	if err := c.BodyParser(user) ; err!=nil{
-        return  c.Status(fiber.StatusBadRequest).SendString(err.Error())
-	}
-
-	if(user.Email=="" || user.Password=="" || user.Username ==""){
-		return  c.Status(fiber.StatusBadRequest).SendString("Please fill in all required fields.")
+	if err := c.BodyParser(user); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
 
-	_,err := config.User.Where("email","==",user.Email).Limit(1).Documents(config.Ctx).Next()
-	if err == nil{
-		return c.Status(fiber.StatusBadRequest).SendString("email has already")
+	if user.Email == "" || user.Password == "" || user.Username == "" {
+		return c.Status(fiber.StatusBadRequest).SendString("Please fill in all required fields.")
 	}
 
-	_,err = config.User.Where("username","==",user.Username).Limit(1).Documents(config.Ctx).Next()
-	if err == nil{
-		return c.Status(fiber.StatusBadRequest).SendString("Username have been Already")
-	}
-	_,err = config.Vendor.Where("email","==",user.Email).Limit(1).Documents(config.Ctx).Next()
-	if err == nil{
+	// ตรวจซ้ำทั้ง users และ vendors
+	if _, err := config.DB.Accounts().FindByEmail(config.Ctx, user.Email); err == nil {
 		return c.Status(fiber.StatusBadRequest).SendString("email has already")
 	}
-
-	_,err = config.Vendor.Where("username","==",user.Username).Limit(1).Documents(config.Ctx).Next()
-	if err == nil{
+	if _, err := config.DB.Accounts().FindByUsername(config.Ctx, user.Username); err == nil {
 		return c.Status(fiber.StatusBadRequest).SendString("Username have been Already")
 	}
 
@@ -46,112 +40,145 @@ func CreateUser(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString("Error hashing password")
 	}
-	user.Password = string(hashedPassword)
-	
-	var docRef *firestore.DocumentRef
-
-	if(user.Role=="vendor"){
-		docRef, _, err = config.Vendor.Add(config.Ctx,map[string]interface{}{
-			"email":     user.Email,
-			"firstname": "",
-			"lastname": "",
-			"username": 	user.Username,
-			"password":   user.Password,
-			"verified": false,
-			"Cost": 0,
-			"createdat": firestore.ServerTimestamp,
-		})
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("Error saving user")
+
+	account := models.User{
+		Email:    user.Email,
+		Username: user.Username,
+		Password: string(hashedPassword),
+		Role:     user.Role,
+		Verified: false,
+	}
+	if account.Role != store.RoleVendor {
+		// user ใหม่ได้เครดิตเริ่มต้น และเปิด ledger ไปพร้อมกัน
+		account.Cost = signupCredit
+		account.WalletOpened = true
+	}
+	err = config.DB.RunTransaction(config.Ctx, func(ctx context.Context, tx store.Repos) error {
+		if err := tx.Accounts().Create(ctx, &account); err != nil {
+			return err
 		}
-	}else{
-		docRef, _, err = config.User.Add(config.Ctx,map[string]interface{}{
-			"email":     user.Email,
-			"firstname": "",
-			"lastname": "",
-			"username": 	user.Username,
-			"password":   user.Password,
-			"verified": false,
-			"Cost": 10000,
-			"createdat": firestore.ServerTimestamp,
-		})
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("Error saving user")
+		if !account.WalletOpened {
+			return nil
 		}
-	}
-
-	_, err = docRef.Update(config.Ctx, []firestore.Update{
-		{Path: "id", Value: docRef.ID},
+		return tx.Wallet().Append(ctx, &models.WalletTxn{
+			UserID:       account.ID,
+			Type:         models.WalletAdjustment,
+			Amount:       signupCredit,
+			BalanceAfter: signupCredit,
+			RefType:      models.WalletRefSystem,
+			Note:         "signup credit",
+			CreatedAt:    time.Now(),
+		})
 	})
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString("Failed to update ID field")
-	}
-	
-	claims := jwt.MapClaims{
-		"user_id":  docRef.ID,
-		"username": user.Username,
-		"verified": false,
-		"role":     user.Role,
-		"exp":      time.Now().Add(time.Minute * 60).Unix(),
+		return c.Status(fiber.StatusInternalServerError).SendString("Error saving user")
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	secret := os.Getenv("JWT_SECRET")
-	t, err := token.SignedString([]byte(secret))
+	pair, err := IssueSession(config.Ctx, &account, ClientInfoFrom(c))
 	if err != nil {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
-	return c.JSON(fiber.Map{
-		"user_id":	docRef.ID,
-		"verified":  false,
-		"role": 	user.Role,
-		"token"	:	t,
-		"message" : "Create success",
-	})
+	return c.JSON(pair.Into(fiber.Map{
+		"user_id":  account.ID,
+		"verified": false,
+		"role":     account.Role,
+		"message":  "Create success",
+	}))
 }
 
-func ChangePassword(c *fiber.Ctx)error{
-	user := new(models.User)
-	if err := c.BodyParser(user); err != nil {
-    return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-        "error": "Invalid request body",
-    })
+// นโยบายรหัสผ่าน: 8–72 ตัวอักษร (bcrypt ใช้ได้แค่ 72 byte) มีทั้งตัวอักษรและตัวเลข
+const (
+	passwordMinLen = 8
+	passwordMaxLen = 72
+)
+
+// ValidatePassword คืนข้อความที่บอก user ได้ตรง ๆ ถ้ารหัสผ่านไม่ผ่านนโยบาย
+func ValidatePassword(pw string) error {
+	if len(pw) < passwordMinLen {
+		return fmt.Errorf("password must be at least %d characters", passwordMinLen)
+	}
+	if len(pw) > passwordMaxLen {
+		return fmt.Errorf("password must be at most %d bytes", passwordMaxLen)
+	}
+	var letter, digit bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsLetter(r):
+			letter = true
+		}
 	}
-	
-	data, err := config.User.Where("email", "==", user.Email).Documents(config.Ctx).GetAll()
-	if err != nil {
-		return c.Status(fiber.StatusBadGateway).SendString("Get data error")
+	if !letter || !digit {
+		return errors.New("password must contain both letters and digits")
 	}
-	
-	if len(data) == 0 {
-		data, err = config.Vendor.Where("email", "==", user.Email).Documents(config.Ctx).GetAll()
-		if err != nil {
-			return c.Status(fiber.StatusBadGateway).SendString("Get vendor data error")
-		}
+	return nil
+}
+
+// ChangePassword ตั้งรหัสผ่านใหม่ด้วย reset_ticket ที่ได้จาก /checkotp
+// ticket ใช้ได้ครั้งเดียว และทุก session เดิมของบัญชีจะถูก revoke
+func ChangePassword(c *fiber.Ctx) error {
+	req := new(models.ChangePasswordReq)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
 	}
-	
-	if len(data) == 0 {
-		return c.Status(fiber.StatusNotFound).SendString("not data")
+	if req.Email == "" || req.ResetTicket == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email and reset_ticket are required"})
 	}
-	
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err := ValidatePassword(req.Password); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString("Error hashing password")
 	}
-	
-	docRef := data[0].Ref
-	_, err = docRef.Update(config.Ctx, []firestore.Update{
-		{Path: "password", Value: string(hashedPassword)},
+
+	var account *models.User
+	err = config.DB.RunTransaction(config.Ctx, func(ctx context.Context, tx store.Repos) error {
+		// ---------- READS ----------
+		if err := CheckOTPTicket(ctx, tx, models.OTPResetPassword, req.Email, req.ResetTicket); err != nil {
+			return err
+		}
+		acct, err := tx.Accounts().FindByEmail(ctx, req.Email)
+		if err != nil {
+			return err
+		}
+		account = acct
+
+		// ---------- WRITES ----------
+		if err := ConsumeOTPTicket(ctx, tx, models.OTPResetPassword, req.Email); err != nil {
+			return err
+		}
+		return tx.Accounts().Update(ctx, acct.Role, acct.ID, map[string]any{
+			"password":  string(hashedPassword),
+			"updatedAt": time.Now(),
+		})
 	})
 	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidTicket):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired reset ticket"})
+		case errors.Is(err, store.ErrNotFound):
+			return c.Status(fiber.StatusNotFound).SendString("not data")
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Error updating password",
 		})
 	}
-	
+
+	revoked, err := RevokeUserSessions(config.Ctx, account.ID, "")
+	if err != nil {
+		// รหัสผ่านเปลี่ยนแล้ว แต่ revoke ไม่ครบ — log ไว้ ไม่ fail ทั้ง request
+		log.Println("revoke sessions after password reset:", err)
+	}
+
 	fmt.Println("updating password Complete")
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Password changed successfully",
+		"message":          "Password changed successfully",
+		"sessions_revoked": revoked,
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// code ของ ReservationError
+const (
+	ReserveSlotsOffCode   = "SLOTS_NOT_CONFIGURED"
+	ReserveSlotNeededCode = "SLOT_REQUIRED"
+	ReserveBadSlotCode    = "INVALID_SLOT"
+	ReserveTooFarCode     = "TOO_FAR_AHEAD"
+	ReserveTooSoonCode    = "TOO_SOON"
+	ReservePartyCode      = "PARTY_TOO_LARGE"
+	ReserveFullCode       = "SLOT_FULL"
+)
+
+// เหตุผลที่ slot จองไม่ได้ (ReservationSlot.Reason)
+const (
+	slotFull       = "full"
+	slotTooSoon    = "too_soon"
+	slotPartyLarge = "party_too_large"
+)
+
+const reservationDayLayout = "2006-01-02"
+
+// ReservationError = จองช่วงเวลานั้นไม่ได้ (Conflict = true → 409, ไม่งั้น 400)
+type ReservationError struct {
+	Code     string
+	Message  string
+	Conflict bool
+}
+
+func (e *ReservationError) Error() string { return e.Message }
+
+func reservationErr(code string, conflict bool, format string, a ...any) error {
+	return &ReservationError{Code: code, Message: fmt.Sprintf(format, a...), Conflict: conflict}
+}
+
+// CheckShopReservable ตรวจว่าร้านรับจองล่วงหน้าได้ (ไม่สนว่าตอนนี้ร้านเปิดอยู่หรือไม่)
+// ร้านที่ scheduler ปิดอยู่ใช้ค่า reserve_active ที่จำไว้ก่อนปิด
+func CheckShopReservable(s *models.Shop) error {
+	if s.IsDeleted() {
+		return &ShopClosedError{Code: ShopDeletedCode, Message: "shop not found"}
+	}
+	enabled := s.ReserveActive
+	if s.Schedule != nil && s.Schedule.State == models.ScheduleClosed {
+		enabled = enabled || s.Schedule.ReserveActive
+	}
+	if !enabled {
+		return &ShopClosedError{Code: ShopReserveDisabledCode, Message: "shop is not accepting reservations"}
+	}
+	return nil
+}
+
+// parseReservationDay แปลง YYYY-MM-DD เป็นเที่ยงคืนของวันนั้นตามเวลาท้องถิ่นของร้าน
+func parseReservationDay(date string, loc *time.Location) (time.Time, error) {
+	d, err := time.ParseInLocation(reservationDayLayout, strings.TrimSpace(date), loc)
+	if err != nil {
+		return time.Time{}, reservationErr(ReserveBadSlotCode, false, "date must be YYYY-MM-DD")
+	}
+	return d, nil
+}
+
+// slotStarts คืนเวลาเริ่มทุก slot ที่เริ่มในวัน day (ต้องจบก่อนร้านปิดช่วงนั้น; ร้านไม่มีตารางเวลา = ทั้งวัน)
+func slotStarts(s *models.Shop, rs *models.ReservationSettings, day time.Time) []time.Time {
+	next := day.AddDate(0, 0, 1)
+	ivs := []models.Interval{{Start: day, End: next}}
+	if s.Hours != nil {
+		// ช่วงเปิดข้ามเที่ยงคืนของเมื่อวานมี slot หลังเที่ยงคืนของวันนี้ด้วย
+		ivs = s.Hours.Intervals(day.AddDate(0, 0, -1), day)
+	}
+	step := time.Duration(rs.SlotMinutes) * time.Minute
+	length := time.Duration(rs.BookingMinutes) * time.Minute
+	// ค่าที่ไม่ผ่าน validate (เช่นเอกสารเก่า) ทำให้ loop ไม่จบ
+	if step <= 0 || length <= 0 {
+		return nil
+	}
+	var out []time.Time
+	for _, iv := range ivs {
+		for t := iv.Start; !t.Add(length).After(iv.End); t = t.Add(step) {
+			if !t.Before(day) && t.Before(next) {
+				out = append(out, t.In(day.Location()))
+			}
+		}
+	}
+	return out
+}
+
+// aroundDays คือ dayKey ที่การจองอาจซ้อนกับ slot ของวัน day (การจองยาวไม่เกิน 12 ชม.)
+func aroundDays(day time.Time) []string {
+	return []string{
+		day.AddDate(0, 0, -1).Format(reservationDayLayout),
+		day.Format(reservationDayLayout),
+		day.AddDate(0, 0, 1).Format(reservationDayLayout),
+	}
+}
+
+// evaluateSlot คำนวณที่ว่างของ slot ที่เริ่ม start สำหรับ people คน
+// คืน slot และโต๊ะที่จะได้ (โหมดโต๊ะ: โต๊ะว่างที่เล็กที่สุดที่นั่งพอ)
+func evaluateSlot(rs *models.ReservationSettings, resvs []models.Reservation, start time.Time, people int, now time.Time) (models.ReservationSlot, string) {
+	end := start.Add(time.Duration(rs.BookingMinutes) * time.Minute)
+	slot := models.ReservationSlot{Start: start, End: end, Time: start.Format("15:04")}
+
+	var overlapping []models.Reservation
+	for _, r := range resvs {
+		if r.HoldsCapacity() && r.SlotStart.Before(end) && start.Before(*r.SlotEnd) {
+			overlapping = append(overlapping, r)
+		}
+	}
+
+	full := false
+	table := ""
+	if len(rs.Tables) > 0 {
+		busy := map[string]bool{}
+		for _, r := range overlapping {
+			busy[r.TableID] = true
+		}
+		free, best := 0, 0
+		for _, t := range rs.Tables {
+			if busy[t.ID] || t.Seats < people {
+				continue
+			}
+			free++
+			if table == "" || t.Seats < best {
+				table, best = t.ID, t.Seats
+			}
+		}
+		slot.TablesLeft = &free
+		full = free == 0
+	} else {
+		// ที่นั่งที่ใช้สูงสุดในช่วงนี้ (เปลี่ยนได้เฉพาะตอนมีการจองเริ่ม)
+		peak := 0
+		for _, p := range overlapping {
+			at := start
+			if p.SlotStart.After(at) {
+				at = *p.SlotStart
+			}
+			used := 0
+			for _, r := range overlapping {
+				if !at.Before(*r.SlotStart) && at.Before(*r.SlotEnd) {
+					used += r.People
+				}
+			}
+			peak = max(peak, used)
+		}
+		left := max(rs.SeatCapacity-peak, 0)
+		slot.SeatsLeft = &left
+		full = left < people
+	}
+
+	switch {
+	case people > rs.PartyLimit():
+		slot.Reason = slotPartyLarge
+	case start.Before(now.Add(time.Duration(rs.MinNoticeMinutes) * time.Minute)):
+		slot.Reason = slotTooSoon
+	case full:
+		slot.Reason = slotFull
+	default:
+		slot.Bookable = true
+	}
+	return slot, table
+}
+
+// ReservationAvailability คืนทุก slot ของวันที่ date (YYYY-MM-DD ตามเวลาท้องถิ่นของร้าน) สำหรับ people คน
+// วันที่ผ่านมาแล้วหรือเกิน max_days_ahead คืน slot ว่าง
+func ReservationAvailability(ctx context.Context, s *models.Shop, date string, people int, now time.Time) ([]models.ReservationSlot, error) {
+	rs := s.ReservationSettings
+	if rs == nil {
+		return nil, reservationErr(ReserveSlotsOffCode, true, "shop does not take timed reservations")
+	}
+	loc := StockLocation(s)
+	day, err := parseReservationDay(date, loc)
+	if err != nil {
+		return nil, err
+	}
+	today, _ := parseReservationDay(models.StockDay(now, loc), loc)
+	out := []models.ReservationSlot{}
+	if day.Before(today) || day.After(today.AddDate(0, 0, rs.MaxDaysAhead)) {
+		return out, nil
+	}
+	starts := slotStarts(s, rs, day)
+	if len(starts) == 0 {
+		return out, nil
+	}
+	resvs, err := config.DB.Reservations().ListByShopDays(ctx, s.ID, aroundDays(day))
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range starts {
+		slot, _ := evaluateSlot(rs, resvs, st, people, now)
+		out = append(out, slot)
+	}
+	return out, nil
+}
+
+// CreateReservation จองโต๊ะ/ที่นั่งใน transaction เดียว: อ่านร้านและการจองรอบ ๆ slot → ตรวจที่ว่าง → lock วัน → เขียน
+// ร้านที่ไม่ได้ตั้งค่า slot จองแบบเดิม (ร้านต้องเปิดอยู่ตอนนี้) แต่ตั้ง DayKey ให้
+// การจองใหม่เป็น pending (รอร้านยืนยัน) หรือ confirmed ถ้าร้านตั้ง auto_confirm
+// ร้านที่ตั้ง deposit_per_person ตัดมัดจำจาก wallet ของ user พร้อมกัน (ไม่พอ → *InsufficientFundsError)
+func CreateReservation(ctx context.Context, shopID string, req models.CreateReservationReq, now time.Time) (*models.Reservation, error) {
+	var out *models.Reservation
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		s, err := tx.Shops().Get(ctx, shopID)
+		if err != nil {
+			return err
+		}
+		loc := StockLocation(s)
+		rs := s.ReservationSettings
+		// รอร้านยืนยัน เว้นแต่ร้านตั้ง auto_confirm ไว้
+		status, actor := models.ReservationPending, models.ActorCustomer
+		if rs != nil && rs.AutoConfirm {
+			status, actor = models.ReservationConfirmed, models.ActorSystem
+		}
+		if err := models.CheckReservationCreate(status, actor); err != nil {
+			return err
+		}
+		resv := models.Reservation{
+			ShopID:    shopID,
+			UserID:    req.UserID,
+			Phone:     req.Phone,
+			People:    req.People,
+			Note:      req.Note,
+			Status:    status,
+			CreatedAt: now,
+			UpdatedAt: now,
+			DayKey:    models.StockDay(now, loc),
+			History:   []models.ReservationStatusChange{{To: status, By: actor, ByID: req.UserID, At: now}},
+		}
+
+		if rs == nil {
+			if err := CheckShopOpen(s, ShopServiceReserve, now); err != nil {
+				return err
+			}
+			if err := tx.Reservations().Create(ctx, &resv); err != nil {
+				return err
+			}
+			out = &resv
+			return nil
+		}
+
+		if err := CheckShopReservable(s); err != nil {
+			return err
+		}
+		if req.Date == "" || req.Time == "" {
+			return reservationErr(ReserveSlotNeededCode, false, "date and time required")
+		}
+		day, err := parseReservationDay(req.Date, loc)
+		if err != nil {
+			return err
+		}
+		start, err := time.ParseInLocation(reservationDayLayout+" 15:04", day.Format(reservationDayLayout)+" "+strings.TrimSpace(req.Time), loc)
+		if err != nil {
+			return reservationErr(ReserveBadSlotCode, false, "time must be HH:MM")
+		}
+		today, _ := parseReservationDay(models.StockDay(now, loc), loc)
+		if day.After(today.AddDate(0, 0, rs.MaxDaysAhead)) {
+			return reservationErr(ReserveTooFarCode, false, "reservations open at most %d days ahead", rs.MaxDaysAhead)
+		}
+		valid := false
+		for _, st := range slotStarts(s, rs, day) {
+			if st.Equal(start) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return reservationErr(ReserveBadSlotCode, false, "%s %s is not a bookable slot", req.Date, req.Time)
+		}
+
+		resvs, err := tx.Reservations().ListByShopDays(ctx, shopID, aroundDays(day))
+		if err != nil {
+			return err
+		}
+		slot, table := evaluateSlot(rs, resvs, start, req.People, now)
+		switch slot.Reason {
+		case slotPartyLarge:
+			return reservationErr(ReservePartyCode, false, "party size must be at most %d", rs.PartyLimit())
+		case slotTooSoon:
+			return reservationErr(ReserveTooSoonCode, true, "slot must be booked at least %d minutes ahead", rs.MinNoticeMinutes)
+		case slotFull:
+			return reservationErr(ReserveFullCode, true, "slot %s is fully booked", req.Time)
+		}
+
+		// lock ทุกวันที่การจองนี้ครอบ: การจองสองรายการที่ซ้อนกันต้องครอบวันเดียวกันอย่างน้อยหนึ่งวัน
+		days := []string{models.StockDay(slot.Start, loc)}
+		if last := models.StockDay(slot.End.Add(-time.Minute), loc); last != days[0] {
+			days = append(days, last)
+		}
+		var payer *models.User
+		deposit := roundMoney(rs.DepositPerPerson * float64(req.People))
+		if deposit > 0 {
+			if payer, err = tx.Accounts().GetAs(ctx, store.RoleUser, req.UserID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Reservations().LockDays(ctx, shopID, days); err != nil {
+			return err
+		}
+
+		resv.DayKey = days[0]
+		resv.SlotStart, resv.SlotEnd = &slot.Start, &slot.End
+		resv.TableID = table
+		if deposit > 0 {
+			refundBy := slot.Start.Add(-time.Duration(rs.DepositRefundHours) * time.Hour)
+			resv.Deposit, resv.DepositStatus, resv.DepositRefundBy = deposit, models.DepositHeld, &refundBy
+		}
+		if err := tx.Reservations().Create(ctx, &resv); err != nil {
+			return err
+		}
+		if payer != nil {
+			// ตัดมัดจำใน transaction เดียวกับการจอง (เงินไม่พอ → ไม่เกิดการจอง)
+			if _, err := ApplyWalletEntry(ctx, tx, payer, WalletEntry{
+				Type:    models.WalletDebit,
+				Amount:  deposit,
+				RefType: models.WalletRefReservation,
+				RefID:   resv.ID,
+				Note:    "reservation deposit " + s.ShopName,
+			}); err != nil {
+				return err
+			}
+		}
+		out = &resv
+		return nil
+	})
+	return out, err
+}
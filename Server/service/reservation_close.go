@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+const (
+	// ปิดการจองอัตโนมัติเมื่อเลยเวลาสิ้นสุด slot ไปเท่านี้ (ให้ร้านปิดเองก่อนได้)
+	reservationCloseGrace = time.Hour
+	reservationCloseBatch = 100
+)
+
+// autoCloseStatus คือสถานะที่ระบบปิดให้เมื่อเลยเวลา: ยืนยันแล้วแต่ไม่มา -> no_show, นั่งอยู่ -> completed
+var autoCloseStatus = map[string]string{
+	models.ReservationConfirmed: models.ReservationNoShow,
+	models.ReservationSeated:    models.ReservationCompleted,
+}
+
+// CloseOverdueReservations ปิดการจองที่เลย slot_end + reservationCloseGrace แล้วแต่ร้านยังไม่ปิด
+// (ActorSystem; no_show ยึดมัดจำให้ร้านตาม DepositOutcome) คืนจำนวนที่ปิด
+func CloseOverdueReservations(ctx context.Context, now time.Time) (int, error) {
+	due, err := config.DB.Reservations().ListOverdue(ctx, now.Add(-reservationCloseGrace), reservationCloseBatch)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, r := range due {
+		closed := false
+		err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+			resv, err := tx.Reservations().Get(ctx, r.ID)
+			if err != nil {
+				return err
+			}
+			// ร้านอาจเปลี่ยนสถานะไปแล้วระหว่างนี้
+			cur := resv.CurrentStatus()
+			to, ok := autoCloseStatus[cur]
+			if !ok {
+				return nil
+			}
+			if err := models.CheckReservationTransition(cur, to, models.ActorSystem); err != nil {
+				return err
+			}
+			if _, err := SettleReservationDeposit(ctx, tx, resv, to, now); err != nil {
+				return err
+			}
+			resv.Status = to
+			resv.UpdatedAt = now
+			resv.History = append(resv.History, models.ReservationStatusChange{
+				From: cur, To: to, By: models.ActorSystem, Reason: "slot ended", At: now,
+			})
+			closed = true
+			return tx.Reservations().Save(ctx, resv)
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		if closed {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// StartReservationCloser รัน CloseOverdueReservations ทุก every จนกว่า ctx จะถูกยกเลิก
+func StartReservationCloser(ctx context.Context, every time.Duration) {
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		if n, err := CloseOverdueReservations(ctx, time.Now()); err != nil {
+			log.Println("reservation close:", err)
+		} else if n > 0 {
+			log.Printf("reservation close: %d reservations", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// SettleReservationDeposit จัดการมัดจำเมื่อการจองเปลี่ยนเป็น to (ดู Reservation.DepositOutcome)
+//   - คืน: refund เข้า wallet ลูกค้า
+//   - ร้านได้: income เข้า wallet ของ vendor เจ้าของร้าน
+//
+// ไม่มีอะไรต้องทำ → nil, nil; ตั้ง resv.DepositStatus แต่ไม่ได้เขียนการจอง (ผู้เรียก Save เอง)
+// ต้องเรียกภายใน transaction หลังอ่านอย่างอื่นครบแล้ว (ฟังก์ชันนี้อ่านบัญชีก่อนแล้วค่อยเขียน)
+func SettleReservationDeposit(ctx context.Context, tx store.Repos, resv *models.Reservation, to string, at time.Time) (*models.WalletTxn, error) {
+	outcome := resv.DepositOutcome(to, at)
+	if outcome == "" {
+		return nil, nil
+	}
+
+	var (
+		acct *models.User
+		err  error
+	)
+	entry := WalletEntry{Amount: resv.Deposit, RefType: models.WalletRefReservation, RefID: resv.ID}
+	if outcome == models.DepositRefunded {
+		acct, err = tx.Accounts().GetAs(ctx, store.RoleUser, resv.UserID)
+		entry.Type = models.WalletRefund
+		entry.Note = "reservation deposit refund (" + to + ")"
+	} else {
+		var s *models.Shop
+		if s, err = tx.Shops().Get(ctx, resv.ShopID); err == nil {
+			acct, err = tx.Accounts().GetAs(ctx, store.RoleVendor, s.VendorID)
+		}
+		entry.Type = models.WalletIncome
+		entry.Note = "reservation deposit kept (" + to + ")"
+	}
+	if err != nil {
+		return nil, fmt.Errorf("deposit %s: %w", outcome, err)
+	}
+
+	txn, err := ApplyWalletEntry(ctx, tx, acct, entry)
+	if err != nil {
+		return nil, err
+	}
+	resv.DepositStatus = outcome
+	return txn, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// feed แสดงการจองย้อนหลังไม่เกินกี่วัน (การจองล่วงหน้าแสดงทั้งหมด)
+const CalendarFeedPastDays = 90
+
+const (
+	icsProdID    = "-//Meeble//Reservations//TH"
+	icsUIDDomain = "meeble"
+	icsLineLimit = 75 // octets ต่อบรรทัด (RFC 5545 3.1)
+)
+
+// ErrCalendarFeedToken = ไม่มี feed นี้ / token ไม่ตรง (ถูกออกใหม่หรือถูกปิดไปแล้ว)
+var ErrCalendarFeedToken = errors.New("invalid or revoked calendar feed token")
+
+// IssueCalendarFeed ออก token ใหม่ให้ feed (token เดิมใช้ไม่ได้ทันที) คืน token จริงครั้งเดียว ไม่เก็บ
+func IssueCalendarFeed(ctx context.Context, kind, ownerID string, now time.Time) (string, error) {
+	secret, hash, err := newRefreshSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := config.DB.CalendarFeeds().Put(ctx, &models.CalendarFeed{
+		Kind:      kind,
+		OwnerID:   ownerID,
+		TokenHash: hash,
+		CreatedAt: now,
+	}); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// CheckCalendarFeed ตรวจ token ของ feed
+func CheckCalendarFeed(ctx context.Context, kind, ownerID, token string) error {
+	if token == "" {
+		return ErrCalendarFeedToken
+	}
+	f, err := config.DB.CalendarFeeds().Get(ctx, kind, ownerID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrCalendarFeedToken
+	}
+	if err != nil {
+		return err
+	}
+	if !sameHash(f.TokenHash, hashSecret(token)) {
+		return ErrCalendarFeedToken
+	}
+	return nil
+}
+
+// ReservationCalendar สร้าง iCalendar (RFC 5545) จากการจอง
+//   - UID คงที่ต่อการจอง; ทุกครั้งที่สถานะเปลี่ยน SEQUENCE เพิ่ม (= จำนวนครั้งใน history)
+//     แอปปฏิทินจึงอัปเดต event เดิมแทนการสร้างใหม่
+//   - การจองที่ยกเลิก / ถูกปฏิเสธ / ไม่มา ยังอยู่ใน feed เป็น STATUS:CANCELLED
+//
+// kind = CalendarFeedShop → SUMMARY เป็นรายละเอียดลูกค้า, CalendarFeedUser → ชื่อร้าน
+func ReservationCalendar(ctx context.Context, kind, name string, resvs []models.Reservation, now time.Time) ([]byte, error) {
+	since := now.AddDate(0, 0, -CalendarFeedPastDays).Format(reservationDayLayout)
+	list := make([]models.Reservation, 0, len(resvs))
+	for _, r := range resvs {
+		if r.DayKey >= since {
+			list = append(list, r)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].DayKey != list[j].DayKey {
+			return list[i].DayKey < list[j].DayKey
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	var b strings.Builder
+	w := func(name, value string) { writeICSLine(&b, name+":"+value) }
+	w("BEGIN", "VCALENDAR")
+	w("VERSION", "2.0")
+	w("PRODID", icsProdID)
+	w("CALSCALE", "GREGORIAN")
+	w("METHOD", "PUBLISH")
+	w("X-WR-CALNAME", icsText(name))
+	w("REFRESH-INTERVAL;VALUE=DURATION", "PT15M")
+
+	shops := map[string]*models.Shop{}
+	for i := range list {
+		r := &list[i]
+		shopName := ""
+		if kind == models.CalendarFeedUser {
+			s, ok := shops[r.ShopID]
+			if !ok {
+				var err error
+				if s, err = config.DB.Shops().Get(ctx, r.ShopID); err != nil && !errors.Is(err, store.ErrNotFound) {
+					return nil, err
+				}
+				shops[r.ShopID] = s
+			}
+			if s != nil {
+				shopName = s.ShopName
+			}
+		}
+		writeReservationEvent(&b, kind, shopName, r)
+	}
+	w("END", "VCALENDAR")
+	return []byte(b.String()), nil
+}
+
+// writeReservationEvent เขียน VEVENT ของการจองหนึ่งรายการ
+func writeReservationEvent(b *strings.Builder, kind, shopName string, r *models.Reservation) {
+	w := func(name, value string) { writeICSLine(b, name+":"+value) }
+	status := r.CurrentStatus()
+	updated := r.UpdatedAt
+	if updated.IsZero() {
+		updated = r.CreatedAt
+	}
+
+	w("BEGIN", "VEVENT")
+	w("UID", fmt.Sprintf("reservation-%s@%s", r.ID, icsUIDDomain))
+	w("DTSTAMP", icsTime(updated))
+	if !r.CreatedAt.IsZero() {
+		w("CREATED", icsTime(r.CreatedAt))
+	}
+	w("LAST-MODIFIED", icsTime(updated))
+	w("SEQUENCE", fmt.Sprint(max(len(r.History)-1, 0)))
+	if r.SlotStart != nil && r.SlotEnd != nil {
+		w("DTSTART", icsTime(*r.SlotStart))
+		w("DTEND", icsTime(*r.SlotEnd))
+	} else if day, err := time.Parse(reservationDayLayout, r.DayKey); err == nil {
+		// การจองแบบเดิมไม่มีเวลา → event ทั้งวัน
+		w("DTSTART;VALUE=DATE", day.Format("20060102"))
+		w("DTEND;VALUE=DATE", day.AddDate(0, 0, 1).Format("20060102"))
+	}
+	w("STATUS", icsStatus(status))
+	w("TRANSP", "OPAQUE")
+
+	summary := fmt.Sprintf("Reservation: %d people", r.People)
+	if kind == models.CalendarFeedUser {
+		summary = "Reservation at " + shopName
+		if shopName == "" {
+			summary = "Reservation"
+		}
+	}
+	if status != models.ReservationConfirmed && status != models.ReservationSeated && status != models.ReservationCompleted {
+		summary += " [" + status + "]"
+	}
+	w("SUMMARY", icsText(summary))
+
+	desc := []string{"Status: " + status, fmt.Sprintf("People: %d", r.People)}
+	if r.TableID != "" {
+		desc = append(desc, "Table: "+r.TableID)
+	}
+	if kind == models.CalendarFeedShop && r.Phone != "" {
+		desc = append(desc, "Phone: "+r.Phone)
+	}
+	if r.Note != "" {
+		desc = append(desc, "Note: "+r.Note)
+	}
+	if r.CancelReason != "" {
+		desc = append(desc, "Reason: "+r.CancelReason)
+	}
+	if r.Deposit > 0 {
+		desc = append(desc, fmt.Sprintf("Deposit: %.2f (%s)", r.Deposit, r.DepositStatus))
+	}
+	w("DESCRIPTION", icsText(strings.Join(desc, "\n")))
+	w("END", "VEVENT")
+}
+
+// icsStatus แปลงสถานะการจองเป็น STATUS ของ VEVENT
+func icsStatus(status string) string {
+	switch status {
+	case models.ReservationPending:
+		return "TENTATIVE"
+	case models.ReservationConfirmed, models.ReservationSeated, models.ReservationCompleted:
+		return "CONFIRMED"
+	}
+	return "CANCELLED"
+}
+
+// icsTime คือ DATE-TIME แบบ UTC (…Z)
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icsText escape ค่า TEXT (RFC 5545 3.3.11)
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+func icsText(s string) string { return icsTextEscaper.Replace(s) }
+
+// writeICSLine เขียนหนึ่ง content line พร้อม fold ทุก 75 octets (ไม่ตัดกลางตัวอักษร UTF-8) จบด้วย CRLF
+func writeICSLine(b *strings.Builder, line string) {
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icsLineLimit - 1 // บรรทัดต่อขึ้นต้นด้วยช่องว่างหนึ่งตัว
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
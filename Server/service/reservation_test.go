@@ -0,0 +1,95 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+func TestEvaluateSlot(t *testing.T) {
+	bkk, err := time.LoadLocation("Asia/Bangkok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(hh, mm int) time.Time { return time.Date(2026, 10, 19, hh, mm, 0, 0, bkk) }
+	resv := func(status string, from, to time.Time, people int, table string) models.Reservation {
+		return models.Reservation{Status: status, SlotStart: &from, SlotEnd: &to, People: people, TableID: table}
+	}
+
+	tables := &models.ReservationSettings{
+		Tables:           []models.ReservationTable{{ID: "t1", Seats: 2}, {ID: "t2", Seats: 4}, {ID: "t3", Seats: 6}},
+		SlotMinutes:      30,
+		BookingMinutes:   90,
+		MinNoticeMinutes: 60,
+	}
+	seats := &models.ReservationSettings{SeatCapacity: 10, SlotMinutes: 30, BookingMinutes: 90}
+
+	// การจองรอบ ๆ slot 18:00-19:30
+	tableResvs := []models.Reservation{
+		resv(models.ReservationConfirmed, at(17, 0), at(18, 30), 4, "t2"),
+		resv(models.ReservationConfirmed, at(19, 30), at(21, 0), 6, "t3"),       // เริ่มตอน slot จบพอดี ไม่ซ้อน
+		resv(models.ReservationCancelledByUser, at(18, 0), at(19, 30), 2, "t1"), // ยกเลิกแล้วไม่กันโต๊ะ
+	}
+	allBusy := append([]models.Reservation{
+		resv(models.ReservationSeated, at(18, 0), at(19, 30), 5, "t3"),
+	}, tableResvs...)
+	seatResvs := []models.Reservation{
+		resv(models.ReservationConfirmed, at(17, 0), at(18, 30), 4, ""),
+		resv(models.ReservationPending, at(18, 30), at(20, 0), 5, ""),
+		resv(models.ReservationNoShow, at(18, 0), at(19, 30), 8, ""),
+		{Status: models.ReservationConfirmed, People: 9}, // การจองแบบไม่มีเวลา ไม่นับ
+	}
+	stacked := append([]models.Reservation{
+		resv(models.ReservationConfirmed, at(18, 15), at(19, 45), 3, ""),
+	}, seatResvs...)
+
+	early := at(12, 0)
+	tests := []struct {
+		name   string
+		rs     *models.ReservationSettings
+		resvs  []models.Reservation
+		people int
+		now    time.Time
+		reason string
+		table  string
+		left   int
+	}{
+		{"tables: smallest free table", tables, tableResvs, 2, early, "", "t1", 2},
+		{"tables: busy table skipped", tables, tableResvs, 4, early, "", "t3", 1},
+		{"tables: no table big enough free", tables, allBusy, 5, early, slotFull, "", 0},
+		{"tables: party larger than any table", tables, nil, 7, early, slotPartyLarge, "", 0},
+		{"tables: inside min notice", tables, nil, 2, at(17, 30), slotTooSoon, "t1", 3},
+		{"seats: peak of overlapping bookings", seats, seatResvs, 5, early, "", "", 5},
+		{"seats: not enough left", seats, seatResvs, 6, early, slotFull, "", 5},
+		{"seats: overlaps stack", seats, stacked, 2, early, "", "", 2},
+		{"seats: stacked full", seats, stacked, 3, early, slotFull, "", 2},
+		{"seats: empty", seats, nil, 10, early, "", "", 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slot, table := evaluateSlot(tt.rs, tt.resvs, at(18, 0), tt.people, tt.now)
+			if slot.Reason != tt.reason || slot.Bookable != (tt.reason == "") {
+				t.Fatalf("reason = %q bookable = %v, want %q", slot.Reason, slot.Bookable, tt.reason)
+			}
+			if table != tt.table {
+				t.Fatalf("table = %q, want %q", table, tt.table)
+			}
+			if !slot.End.Equal(at(19, 30)) || slot.Time != "18:00" {
+				t.Fatalf("slot = %s %s-%s", slot.Time, slot.Start, slot.End)
+			}
+			left := slot.SeatsLeft
+			if len(tt.rs.Tables) > 0 {
+				left = slot.TablesLeft
+				if slot.SeatsLeft != nil {
+					t.Fatal("tables mode must not report seats_left")
+				}
+			} else if slot.TablesLeft != nil {
+				t.Fatal("seats mode must not report tables_left")
+			}
+			if left == nil || *left != tt.left {
+				t.Fatalf("left = %v, want %d", left, tt.left)
+			}
+		})
+	}
+}
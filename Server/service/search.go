@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/search"
+)
+
+// SearchIndex คือ index ร้าน + เมนูของ process นี้
+// สร้างจาก store ตอน start (RebuildSearchIndex) แล้ว handler ที่แก้ร้าน/เมนูเรียก Index*/Unindex* ตาม
+// ถ้ารันหลาย instance แต่ละตัวเห็นเฉพาะการแก้ไขที่ผ่านตัวเอง จนกว่าจะ rebuild
+var SearchIndex = search.New()
+
+func shopDoc(s *models.Shop) search.Doc {
+	return search.Doc{
+		ID:          search.ShopDocID(s.ID),
+		Kind:        search.KindShop,
+		ShopID:      s.ID,
+		Name:        s.ShopName,
+		Description: s.Description,
+		Image:       s.Image,
+		Active:      true,
+	}
+}
+
+func menuDoc(m *models.MenuItem) search.Doc {
+	return search.Doc{
+		ID:          search.MenuDocID(m.ShopID, m.ID),
+		Kind:        search.KindMenu,
+		ShopID:      m.ShopID,
+		MenuID:      m.ID,
+		Name:        m.Name,
+		Description: m.Description,
+		Image:       m.Image,
+		Price:       m.Price,
+		Active:      m.Available(),
+	}
+}
+
+// RebuildSearchIndex อ่านร้านและเมนูทั้งหมดจาก store แล้วสร้าง index ใหม่
+func RebuildSearchIndex(ctx context.Context) (int, error) {
+	shops, err := config.DB.Shops().List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	docs := make([]search.Doc, 0, len(shops))
+	for i := range shops {
+		if shops[i].IsDeleted() {
+			continue
+		}
+		docs = append(docs, shopDoc(&shops[i]))
+		items, err := config.DB.Menus().List(ctx, shops[i].ID)
+		if err != nil {
+			return 0, err
+		}
+		for j := range items {
+			docs = append(docs, menuDoc(&items[j]))
+		}
+	}
+	SearchIndex.Reset()
+	for _, d := range docs {
+		SearchIndex.Put(d)
+	}
+	return len(docs), nil
+}
+
+// IndexShop เพิ่ม/แทนที่ร้านใน index (ร้านที่ถูก soft delete จะถูกเอาออกพร้อมเมนู)
+func IndexShop(s *models.Shop) {
+	if s.IsDeleted() {
+		UnindexShop(s.ID)
+		return
+	}
+	SearchIndex.Put(shopDoc(s))
+}
+
+// ReindexShop อ่านร้านจาก store ใหม่แล้วอัปเดต index (ใช้หลัง partial update)
+func ReindexShop(ctx context.Context, shopID string) {
+	s, err := config.DB.Shops().Get(ctx, shopID)
+	if err != nil {
+		log.Println("search: reindex shop", shopID, err)
+		return
+	}
+	IndexShop(s)
+}
+
+func UnindexShop(shopID string) { SearchIndex.RemoveShop(shopID) }
+
+// IndexShopWithMenus ใส่ร้านและเมนูทั้งหมดของร้านกลับเข้า index (เช่น หลังกู้คืนร้าน)
+func IndexShopWithMenus(ctx context.Context, s *models.Shop) error {
+	items, err := config.DB.Menus().List(ctx, s.ID)
+	if err != nil {
+		return err
+	}
+	IndexShop(s)
+	for i := range items {
+		IndexMenuItem(&items[i])
+	}
+	return nil
+}
+
+func IndexMenuItem(m *models.MenuItem) { SearchIndex.Put(menuDoc(m)) }
+
+// ReindexMenuItem อ่านเมนูจาก store ใหม่แล้วอัปเดต index
+func ReindexMenuItem(ctx context.Context, shopID, menuID string) {
+	m, err := config.DB.Menus().Get(ctx, shopID, menuID)
+	if err != nil {
+		log.Println("search: reindex menu", shopID, menuID, err)
+		return
+	}
+	IndexMenuItem(m)
+}
+
+func UnindexMenuItem(shopID, menuID string) {
+	SearchIndex.Remove(search.MenuDocID(shopID, menuID))
+}
@@ -2,9 +2,12 @@ package service
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/big"
+	"strconv"
 	"time"
 
 	"gopkg.in/gomail.v2"
@@ -34,39 +37,30 @@ func generateNumericOTP(n int) (string, error) {
 	return otp, nil
 }
 
+func OTPvertify() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body models.OTP_Verify
 
-func OTPvertify()fiber.Handler {
-	return func(c *fiber.Ctx)error{
-	var body models.OTP_Verify
-	
-	if err := c.BodyParser(&body); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
-	}
-
-	otp, err := generateNumericOTP(6)
-	if err != nil {
-		log.Println("generate OTP error:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Could not generate OTP",
-		})
-	}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+		if body.Email == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("Email is required")
+		}
 
-	expireAt := time.Now().Add(5 * time.Minute)
-	_,err = config.OTP.Doc(body.Email).Set(config.Ctx,map[string]interface{}{
-		"email":     body.Email,
-		"otp":       otp,
-		"createdAt": time.Now(),
-		"expireAt":  expireAt,
-	})
+		otp, err := IssueOTP(config.Ctx, models.OTPVerifyEmail, body.Email, c.IP())
+		if err != nil {
+			return otpError(c, err)
+		}
 
-	m := Mailer{}
-	message := gomail.NewMessage()
-	message.SetHeader("To", body.Email)
-	message.SetHeader("Subject", "OTP for E-mail Address Verification on MEEBLE!")
+		m := Mailer{}
+		message := gomail.NewMessage()
+		message.SetHeader("To", body.Email)
+		message.SetHeader("Subject", "OTP for E-mail Address Verification on MEEBLE!")
 
-	message.SetBody("text/html", fmt.Sprintf(`
+		message.SetBody("text/html", fmt.Sprintf(`
 	<div style="font-family: Arial, sans-serif; color:#333;">
 		<p>ถึงคุณ,%s</p>
 		<p>นี่คือรหัสยืนยันตัวตน (OTP) ของคุณ:</p>
@@ -74,93 +68,58 @@ func OTPvertify()fiber.Handler {
 		<p>รหัสนี้มีอายุการใช้งาน <b>5 นาที</b> กรุณาใช้เพื่อทำการยืนยันตัวตนภายในเวลาที่กำหนด</p>
 		<br>
 		<p>ขอบคุณที่ใช้บริการ Meeble 🙏</p>
-	</div>`,body.Username,otp))
-	m.Send(message)
-
-	_,err = config.OTP.Doc(body.Email).Set(config.Ctx,map[string]interface{}{
-		"email":     body.Email,
-		"otp":       otp,
-		"createdAt": time.Now(),
-		"expireAt":  expireAt,
-	})
-	if err != nil {
-		log.Println("Error saving OTP:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to save OTP",
-	})
-}
-	return c.JSON(fiber.Map{
+	</div>`, body.Username, otp))
+		m.Send(message)
+
+		// ❗ ไม่ส่งรหัสกลับใน response
+		return c.JSON(fiber.Map{
 			"status":  "success",
 			"message": "OTP email sent",
-			"otp":     otp,
+			"purpose": models.OTPVerifyEmail,
 			"to":      body.Email,
-	})
+		})
 	}
 }
 
-func CheckEmail(c *fiber.Ctx)error{
+func CheckEmail(c *fiber.Ctx) error {
 	user := new(models.User)
-	if err := c.BodyParser(user) ; err!=nil{
-        return  c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	if err := c.BodyParser(user); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
 
-	_,err := config.User.Where("email", "==", user.Email).Limit(1).Documents(config.Ctx).Next()
-	if err != nil{
-		_,err := config.Vendor.Where("email", "==", user.Email).Limit(1).Documents(config.Ctx).Next()
-			if err != nil{
-				return c.Status(fiber.StatusNotFound).SendString("Email or Username Not Found")
-		}
-    }
+	if _, err := config.DB.Accounts().FindByEmail(config.Ctx, user.Email); err != nil {
+		return c.Status(fiber.StatusNotFound).SendString("Email or Username Not Found")
+	}
 	return c.JSON(fiber.Map{
-			"status":  "success",
-			"message": "CheckEmail Success",
+		"status":  "success",
+		"message": "CheckEmail Success",
 	})
 }
 
-func OTPrepassword()fiber.Handler{
-	return func(c *fiber.Ctx)error{
-	var body models.OTP_Verify
-		
-	if err := c.BodyParser(&body); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
-	}
-	fmt.Print(body.Email)
-	if body.Email == "" {
-		return c.Status(fiber.StatusBadRequest).SendString("Email is required");
-	}
-
-	_,err := config.User.Where("email", "==", body.Email).Limit(1).Documents(config.Ctx).Next()
-	if err != nil {
-		_,err := config.Vendor.Where("email", "==", body.Email).Limit(1).Documents(config.Ctx).Next()
-			if err != nil {
-			return c.Status(fiber.StatusNotFound).SendString("Email or Username Not Found")
-    	}
-    }
+func OTPrepassword() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body models.OTP_Verify
 
-	otp, err := generateNumericOTP(6)
-	if err != nil {
-		log.Println("generate OTP error:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Could not generate OTP",
-		})
-	}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+		if body.Email == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("Email is required")
+		}
 
-	expireAt := time.Now().Add(5 * time.Minute)
-	_,err = config.OTP.Doc(body.Email).Set(config.Ctx,map[string]interface{}{
-		"email":     body.Email,
-		"otp":       otp,
-		"createdAt": time.Now(),
-		"expireAt":  expireAt,
-	})
+		otp, err := IssueOTP(config.Ctx, models.OTPResetPassword, body.Email, c.IP())
+		if err != nil {
+			return otpError(c, err)
+		}
 
-	m := Mailer{}
-	message := gomail.NewMessage()
-	message.SetHeader("To", body.Email)
-	message.SetHeader("Subject", "OTP for ChangePassword on MEEBLE!")
+		m := Mailer{}
+		message := gomail.NewMessage()
+		message.SetHeader("To", body.Email)
+		message.SetHeader("Subject", "OTP for ChangePassword on MEEBLE!")
 
-	message.SetBody("text/html", fmt.Sprintf(`
+		message.SetBody("text/html", fmt.Sprintf(`
 	<div style="font-family: Arial, sans-serif; color:#333;">
 		<p>ถึงคุณ,%s</p>
 		<p>นี่คือรหัสยืนยันตัวตน (OTP) ของคุณ:</p>
@@ -168,55 +127,78 @@ func OTPrepassword()fiber.Handler{
 		<p>รหัสนี้มีอายุการใช้งาน <b>5 นาที</b> กรุณาใช้เพื่อทำการยืนยันตัวตนภายในเวลาที่กำหนด</p>
 		<br>
 		<p>ขอบคุณที่ใช้บริการ Meeble 🙏</p>
-	</div>`,body.Email,otp))
-	m.Send(message)
-
-	_,err = config.OTP.Doc(body.Email).Set(config.Ctx,map[string]interface{}{
-		"email":     body.Email,
-		"otp":       otp,
-		"createdAt": time.Now(),
-		"expireAt":  expireAt,
-	})
-		if err != nil {
-			log.Println("Error saving OTP:", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to save OTP",
-		})
-	}
+	</div>`, body.Email, otp))
+		m.Send(message)
 
-	return c.JSON(fiber.Map{
-		"status":  "success",
-		"message": "OTP email sent",
-		"otp":     otp,
-		"to":      body.Email,
-	})
+		// ❗ ไม่ส่งรหัสกลับใน response
+		return c.JSON(fiber.Map{
+			"status":  "success",
+			"message": "OTP email sent",
+			"purpose": models.OTPResetPassword,
+			"to":      body.Email,
+		})
 	}
 }
 
-func MathOTP(c *fiber.Ctx)error{
+// MathOTP ตรวจรหัส  { email, otp, purpose }  (purpose ว่าง = verify_email)
+// รหัสถูกใช้ได้ครั้งเดียว ใส่ผิดครบ limit แล้วต้องขอรหัสใหม่
+func MathOTP(c *fiber.Ctx) error {
 	otp := new(models.OTP_Verify)
-	if err := c.BodyParser(otp) ; err!=nil{
-        return  c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	if err := c.BodyParser(otp); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
-	
-	doc,err := config.OTP.Doc(otp.Email).Get(config.Ctx)
-	if err != nil{
-		return c.Status(fiber.StatusNotFound).SendString("Email or Username Not Found")
-    }
-
-	data := doc.Data()
-	savedOTP := data["otp"].(string)
-	expireAt := data["expireAt"].(time.Time)
-
-	if time.Now().After(expireAt) {
-		return c.Status(fiber.StatusBadRequest).SendString("OTP expired")
+	purpose := otp.Purpose
+	if purpose == "" {
+		purpose = models.OTPVerifyEmail
 	}
-	if otp.OTP != savedOTP {
-		return c.Status(fiber.StatusBadRequest).SendString("Invalid OTP")
+	if !models.IsOTPPurpose(purpose) {
+		return c.Status(fiber.StatusBadRequest).SendString("Unknown OTP purpose")
 	}
 
-	return c.JSON(fiber.Map{
+	if err := VerifyOTP(config.Ctx, purpose, otp.Email, otp.OTP); err != nil {
+		return otpError(c, err)
+	}
+
+	resp := fiber.Map{
 		"status":  "success",
 		"message": "OTP Check Success",
-	})
-}
\ No newline at end of file
+		"purpose": purpose,
+	}
+	// ticket ใช้ยืนยันว่าผ่าน OTP แล้ว: reset_ticket → PUT /changepassword, verify_ticket → PUT /verifiedEmail/:id
+	ticket, err := IssueOTPTicket(config.Ctx, purpose, otp.Email)
+	if err != nil {
+		log.Println("issue otp ticket:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "OTP service error"})
+	}
+	if purpose == models.OTPResetPassword {
+		resp["reset_ticket"] = ticket
+	} else {
+		resp["verify_ticket"] = ticket
+	}
+	resp["ticket_expires_in"] = int64(otpTicketTTL / time.Second)
+	return c.JSON(resp)
+}
+
+// otpError แปลง error จาก IssueOTP / VerifyOTP เป็น response
+func otpError(c *fiber.Ctx, err error) error {
+	var rl *OTPRateLimitError
+	var inv *OTPInvalidError
+	switch {
+	case errors.As(err, &rl):
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(rl.RetryAfter.Seconds()))))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": rl.Error()})
+	case errors.As(err, &inv):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":         "Invalid OTP",
+			"attempts_left": inv.AttemptsLeft,
+		})
+	case errors.Is(err, ErrOTPLocked):
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrOTPExpired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "OTP expired"})
+	case errors.Is(err, ErrOTPNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "OTP not found, please request a new code"})
+	}
+	log.Println("otp error:", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "OTP service error"})
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+const defaultShopRestoreDays = 30
+
+var (
+	ErrShopDeleted        = errors.New("shop is already deleted")
+	ErrShopNotDeleted     = errors.New("shop is not deleted")
+	ErrShopRestoreExpired = errors.New("restore window has passed")
+)
+
+// ActiveOrdersError = ยังมีออเดอร์ที่ไม่จบ ลบร้านไม่ได้
+type ActiveOrdersError struct {
+	OrderIDs []string
+}
+
+func (e *ActiveOrdersError) Error() string {
+	return "shop has " + strconv.Itoa(len(e.OrderIDs)) + " active orders"
+}
+
+// ActiveReservationsError = ยังมีการจองที่กันที่นั่งหรือถือมัดจำอยู่ ลบร้านไม่ได้
+// (ร้านต้องปิดการจองเองก่อน เช่น cancelled_by_shop ซึ่งคืนมัดจำให้ลูกค้า)
+type ActiveReservationsError struct {
+	ReservationIDs []string
+}
+
+func (e *ActiveReservationsError) Error() string {
+	return "shop has " + strconv.Itoa(len(e.ReservationIDs)) + " active reservations"
+}
+
+// ShopRestoreWindow คือระยะที่กู้คืนร้านได้หลัง soft delete (env SHOP_RESTORE_DAYS, ค่าเริ่มต้น 30 วัน)
+func ShopRestoreWindow() time.Duration {
+	days := defaultShopRestoreDays
+	if v, err := strconv.Atoi(os.Getenv("SHOP_RESTORE_DAYS")); err == nil && v >= 0 {
+		days = v
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func writeAudit(ctx context.Context, tx store.Repos, action, shopID, actorID string, details map[string]any) error {
+	return tx.Audit().Create(ctx, &models.AuditRecord{
+		Action:     action,
+		TargetType: "shop",
+		TargetID:   shopID,
+		ActorID:    actorID,
+		Details:    details,
+		CreatedAt:  time.Now(),
+	})
+}
+
+// SoftDeleteShop ซ่อนร้าน ปิดรับออเดอร์/จอง/คิว (บัตรคิวที่ค้างถูก skipped) และตั้งเวลา purge
+// ไม่ให้ลบถ้ายังมีออเดอร์ที่ยังไม่จบ (*ActiveOrdersError) หรือการจองที่ยังค้าง (*ActiveReservationsError)
+func SoftDeleteShop(ctx context.Context, shopID, actorID string) (*models.Shop, error) {
+	var (
+		out    *models.Shop
+		closed []models.QueueTicket
+	)
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		s, err := tx.Shops().Get(ctx, shopID)
+		if err != nil {
+			return err
+		}
+		if s.IsDeleted() {
+			return ErrShopDeleted
+		}
+		orders, err := tx.Orders().List(ctx, store.OrderFilter{ShopID: shopID})
+		if err != nil {
+			return err
+		}
+		var active []string
+		for _, o := range orders {
+			if !models.IsTerminalOrderStatus(o.Status) {
+				active = append(active, o.ID)
+			}
+		}
+		if len(active) > 0 {
+			return &ActiveOrdersError{OrderIDs: active}
+		}
+		resvs, err := tx.Reservations().ListByShop(ctx, shopID)
+		if err != nil {
+			return err
+		}
+		var held []string
+		for i := range resvs {
+			if r := &resvs[i]; r.HoldsCapacity() || (r.Deposit > 0 && r.DepositStatus == models.DepositHeld) {
+				held = append(held, r.ID)
+			}
+		}
+		if len(held) > 0 {
+			return &ActiveReservationsError{ReservationIDs: held}
+		}
+		tickets, err := tx.Queue().ListActive(ctx, shopID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		purgeAt := now.Add(ShopRestoreWindow())
+		if err := tx.Shops().Update(ctx, shopID, map[string]any{
+			"deleted_at":     now,
+			"purge_after":    purgeAt,
+			"deleted_by":     actorID,
+			"status":         false,
+			"order_active":   false,
+			"reserve_active": false,
+			"queue_active":   false,
+			"updatedAt":      now,
+		}); err != nil {
+			return err
+		}
+		// บัตรคิวที่ยังรออยู่ปิดให้หมด (ลูกค้าไม่ค้างคิวของร้านที่ไม่มีแล้ว)
+		closed = closed[:0]
+		for i := range tickets {
+			t := tickets[i]
+			if err := moveTicket(ctx, tx, &t, models.QueueSkipped, models.ActorSystem, now); err != nil {
+				return err
+			}
+			closed = append(closed, t)
+		}
+		if err := writeAudit(ctx, tx, models.AuditShopDelete, shopID, actorID, map[string]any{
+			"shop_name":   s.ShopName,
+			"purge_after": purgeAt,
+		}); err != nil {
+			return err
+		}
+		s.DeletedAt, s.PurgeAfter, s.DeletedBy = &now, &purgeAt, actorID
+		s.Status, s.OrderActive, s.ReserveActive, s.QueueActive = false, false, false, false
+		out = s
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	UnindexShop(shopID)
+	ShopScheduler.Track(out, time.Now())
+	for i := range closed {
+		OrderEvents.PublishQueue(&closed[i], nil)
+	}
+	return out, nil
+}
+
+// RestoreShop กู้ร้านที่ถูก soft delete (ก่อน purge_after) ร้านยังปิดอยู่ ให้เจ้าของเปิดเอง
+// ร้านที่มีตารางเวลาจะถูก scheduler เปิด/ปิดตามตารางอีกครั้ง
+func RestoreShop(ctx context.Context, shopID, actorID string) (*models.Shop, error) {
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		s, err := tx.Shops().Get(ctx, shopID)
+		if err != nil {
+			return err
+		}
+		if !s.IsDeleted() {
+			return ErrShopNotDeleted
+		}
+		now := time.Now()
+		if s.PurgeAfter != nil && !now.Before(*s.PurgeAfter) {
+			return ErrShopRestoreExpired
+		}
+		if err := tx.Shops().Update(ctx, shopID, map[string]any{
+			"deleted_at":  nil,
+			"purge_after": nil,
+			"deleted_by":  nil,
+			"schedule":    nil,
+			"updatedAt":   now,
+		}); err != nil {
+			return err
+		}
+		return writeAudit(ctx, tx, models.AuditShopRestore, shopID, actorID, map[string]any{
+			"deleted_at": s.DeletedAt,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ApplyShopSchedule(ctx, shopID, time.Now()); err != nil {
+		log.Println("restore shop: schedule", shopID, err)
+	}
+	s, err := config.DB.Shops().Get(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+	if err := IndexShopWithMenus(ctx, s); err != nil {
+		log.Println("restore shop: search index", shopID, err)
+	}
+	ShopScheduler.Track(s, time.Now())
+	return s, nil
+}
+
+// PurgeShop ลบร้านถาวรพร้อมข้อมูลที่ผูกกับร้าน (ทีละ store.PurgeBatchSize) แล้วบันทึก audit
+// ลบตัวร้านเป็นอย่างสุดท้าย: ถ้าล้มกลางทาง รอบถัดไปจะทำต่อจากที่ค้าง
+// history ฝั่ง user และ wallet ledger ถูกเก็บไว้ (เป็นประวัติการซื้อของลูกค้า)
+func PurgeShop(ctx context.Context, s *models.Shop) (map[string]int, error) {
+	counts := map[string]int{}
+	steps := []struct {
+		name string
+		fn   func(ctx context.Context, shopID string, limit int) (int, error)
+	}{
+		{"menu", config.DB.Menus().DeleteByShop},
+		{"menu_categories", config.DB.MenuCategories().DeleteByShop},
+		{"orders", config.DB.Orders().DeleteByShop},
+		{"history", config.DB.History().DeleteByShop},
+		{"reservations", config.DB.Reservations().DeleteByShop},
+		{"queue", config.DB.Queue().DeleteByShop},
+		{"carts", config.DB.Carts().ReleaseByShop},
+	}
+	for _, st := range steps {
+		for {
+			n, err := st.fn(ctx, s.ID, store.PurgeBatchSize)
+			if err != nil {
+				return counts, err
+			}
+			counts[st.name] += n
+			if n < store.PurgeBatchSize {
+				break
+			}
+		}
+	}
+
+	details := map[string]any{"shop_name": s.ShopName, "vendor_id": s.VendorID, "deleted_at": s.DeletedAt, "deleted_by": s.DeletedBy}
+	for k, v := range counts {
+		details[k] = v
+	}
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		if err := tx.Shops().Delete(ctx, s.ID); err != nil {
+			return err
+		}
+		if err := tx.CalendarFeeds().Delete(ctx, models.CalendarFeedShop, s.ID); err != nil {
+			return err
+		}
+		return writeAudit(ctx, tx, models.AuditShopPurge, s.ID, "", details)
+	})
+	if err != nil {
+		return counts, err
+	}
+	UnindexShop(s.ID)
+	return counts, nil
+}
+
+// PurgeDeletedShops purge ทุกร้านที่ครบกำหนดแล้ว คืนจำนวนร้านที่ purge สำเร็จ
+func PurgeDeletedShops(ctx context.Context, now time.Time) (int, error) {
+	shops, err := config.DB.Shops().ListPurgeable(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := range shops {
+		counts, err := PurgeShop(ctx, &shops[i])
+		if err != nil {
+			return n, err
+		}
+		log.Printf("shop purge: %s %v", shops[i].ID, counts)
+		n++
+	}
+	return n, nil
+}
+
+// StartShopPurger รัน purge job ทุก every จนกว่า ctx จะถูกยกเลิก
+func StartShopPurger(ctx context.Context, every time.Duration) {
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		if _, err := PurgeDeletedShops(ctx, time.Now()); err != nil {
+			log.Println("shop purge:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
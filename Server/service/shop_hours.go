@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// บริการของร้านที่ตรวจด้วย CheckShopOpen
+const (
+	ShopServiceOrder   = "order"
+	ShopServiceReserve = "reserve"
+	ShopServiceQueue   = "queue"
+)
+
+// code ของ ShopClosedError (client ใช้เลือกข้อความ)
+const (
+	ShopDeletedCode          = "SHOP_DELETED"
+	ShopClosedCode           = "SHOP_CLOSED"
+	ShopOutsideHoursCode     = "OUTSIDE_HOURS"
+	ShopOrdersDisabledCode   = "ORDERS_DISABLED"
+	ShopReserveDisabledCode  = "RESERVATIONS_DISABLED"
+	ShopQueueDisabledCode    = "QUEUE_DISABLED"
+	shopScheduleFullInterval = 15 * time.Minute // อ่านร้านทั้งหมดใหม่ (จับร้านที่ถูกแก้จาก instance อื่น)
+)
+
+// ShopClosedError = ร้านไม่รับออเดอร์/การจองในตอนนี้
+type ShopClosedError struct {
+	Code     string
+	Message  string
+	NextOpen *time.Time
+}
+
+func (e *ShopClosedError) Error() string { return e.Message }
+
+// CheckShopOpen ตรวจว่าร้านรับ service (ShopServiceOrder / ShopServiceReserve) ณ เวลา at ได้หรือไม่
+// ร้านที่มีตารางเวลาต้องอยู่ในช่วงเปิดด้วย แม้ status / flag จะค้างเป็น true อยู่ก็ตาม
+func CheckShopOpen(s *models.Shop, service string, at time.Time) error {
+	if s.IsDeleted() {
+		return &ShopClosedError{Code: ShopDeletedCode, Message: "shop not found"}
+	}
+	if s.Hours != nil && !s.Hours.IsOpen(at) {
+		e := &ShopClosedError{Code: ShopOutsideHoursCode, Message: "shop is outside opening hours"}
+		if next, open, ok := s.Hours.NextChange(at); ok && open {
+			e.NextOpen = &next
+		}
+		return e
+	}
+	if !s.Status {
+		return &ShopClosedError{Code: ShopClosedCode, Message: "shop is closed"}
+	}
+	switch service {
+	case ShopServiceOrder:
+		if !s.OrderActive {
+			return &ShopClosedError{Code: ShopOrdersDisabledCode, Message: "shop is not accepting orders"}
+		}
+	case ShopServiceReserve:
+		if !s.ReserveActive {
+			return &ShopClosedError{Code: ShopReserveDisabledCode, Message: "shop is not accepting reservations"}
+		}
+	case ShopServiceQueue:
+		if !s.QueueActive {
+			return &ShopClosedError{Code: ShopQueueDisabledCode, Message: "shop is not taking queue tickets"}
+		}
+	}
+	return nil
+}
+
+// ApplyShopSchedule เปิด/ปิดร้านตามตารางเวลา ณ now (เฉพาะตอนที่ตารางเปลี่ยนสถานะ)
+//   - ปิด: status/order_active/reserve_active/queue_active = false และจำค่า flag เดิมไว้ใน schedule
+//   - เปิด: status = true และคืน flag ที่จำไว้ (หรือค่าที่ร้านเปิดเองระหว่างปิด)
+//
+// คืนร้านหลังอัปเดต (nil ถ้าไม่มีการเปลี่ยนแปลง)
+func ApplyShopSchedule(ctx context.Context, shopID string, now time.Time) (*models.Shop, error) {
+	var out *models.Shop
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		out = nil
+		s, err := tx.Shops().Get(ctx, shopID)
+		if err != nil {
+			return err
+		}
+		if s.Hours == nil || s.IsDeleted() {
+			return nil
+		}
+		want := models.ScheduleClosed
+		if s.Hours.IsOpen(now) {
+			want = models.ScheduleOpen
+		}
+		if s.Schedule != nil && s.Schedule.State == want {
+			return nil
+		}
+
+		// แก้ s ในหน่วยความจำตามที่เขียน (Firestore ห้ามอ่านหลังเขียนใน transaction)
+		sched := models.ShopSchedule{State: want, At: now}
+		if want == models.ScheduleClosed {
+			sched.OrderActive, sched.ReserveActive, sched.QueueActive = s.OrderActive, s.ReserveActive, s.QueueActive
+			s.Status, s.OrderActive, s.ReserveActive, s.QueueActive = false, false, false, false
+		} else {
+			s.Status = true
+			if prev := s.Schedule; prev != nil && prev.State == models.ScheduleClosed {
+				s.OrderActive = prev.OrderActive || s.OrderActive
+				s.ReserveActive = prev.ReserveActive || s.ReserveActive
+				s.QueueActive = prev.QueueActive || s.QueueActive
+			}
+		}
+		s.Schedule, s.UpdatedAt = &sched, now
+		if err := tx.Shops().Update(ctx, shopID, map[string]any{
+			"status":         s.Status,
+			"order_active":   s.OrderActive,
+			"reserve_active": s.ReserveActive,
+			"queue_active":   s.QueueActive,
+			"schedule":       sched,
+			"updatedAt":      now,
+		}); err != nil {
+			return err
+		}
+		out = s
+		return nil
+	})
+	return out, err
+}
+
+// shopScheduler จำเวลาที่แต่ละร้านต้องถูกตรวจครั้งถัดไป จะได้ไม่ต้องอ่านทุกร้านทุกนาที
+type shopScheduler struct {
+	mu       sync.Mutex
+	due      map[string]time.Time
+	lastFull time.Time
+}
+
+var ShopScheduler = &shopScheduler{due: map[string]time.Time{}}
+
+// Track ให้ scheduler ตรวจร้านนี้ตามตารางใหม่ (เรียกหลังแก้เวลาเปิด-ปิด)
+func (sc *shopScheduler) Track(s *models.Shop, now time.Time) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.track(s, now)
+}
+
+// ต้องถือ lock อยู่
+func (sc *shopScheduler) track(s *models.Shop, now time.Time) {
+	if s.Hours == nil || s.IsDeleted() {
+		delete(sc.due, s.ID)
+		return
+	}
+	if next, _, ok := s.Hours.NextChange(now); ok {
+		sc.due[s.ID] = next
+	} else {
+		sc.due[s.ID] = now.Add(shopScheduleFullInterval)
+	}
+}
+
+// Run ตรวจร้านที่ถึงเวลา (หรือทุกร้านเมื่อครบรอบ) คืนจำนวนร้านที่ถูกเปิด/ปิด
+func (sc *shopScheduler) Run(ctx context.Context, now time.Time) (int, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	var ids []string
+	if now.Sub(sc.lastFull) >= shopScheduleFullInterval {
+		shops, err := config.DB.Shops().List(ctx)
+		if err != nil {
+			return 0, err
+		}
+		sc.due = map[string]time.Time{}
+		for _, s := range shops {
+			if s.Hours != nil && !s.IsDeleted() {
+				ids = append(ids, s.ID)
+			}
+		}
+		sc.lastFull = now
+	} else {
+		for id, at := range sc.due {
+			if !at.After(now) {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	changed := 0
+	for _, id := range ids {
+		s, err := ApplyShopSchedule(ctx, id, now)
+		if errors.Is(err, store.ErrNotFound) {
+			delete(sc.due, id)
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+		if s != nil {
+			changed++
+			ReindexShop(ctx, id)
+		} else if s, err = config.DB.Shops().Get(ctx, id); err != nil {
+			continue
+		}
+		sc.track(s, now)
+	}
+	return changed, nil
+}
+
+// StartShopScheduler รัน scheduler ทุก every จนกว่า ctx จะถูกยกเลิก
+func StartShopScheduler(ctx context.Context, every time.Duration) {
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		if n, err := ShopScheduler.Run(ctx, time.Now()); err != nil {
+			log.Println("shop scheduler:", err)
+		} else if n > 0 {
+			log.Printf("shop scheduler: opened/closed %d shops", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+// BackfillShopIndex เติมฟิลด์ที่ใช้ค้นหา/เรียงให้ร้านเก่า (index_v < models.ShopIndexVersion)
+//   - geohash จาก address (ไม่มี geohash = ไม่โผล่ใน GET /shops/nearby)
+//   - order_count, createdAt (Firestore ไม่คืนเอกสารที่ไม่มีฟิลด์ที่ใช้ orderBy)
+//   - price_min / price_max ที่เก็บเป็น string → number
+func BackfillShopIndex(ctx context.Context) (int, error) {
+	shops, err := config.DB.Shops().List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, s := range shops {
+		if s.IndexVersion >= models.ShopIndexVersion {
+			continue
+		}
+		fields := map[string]any{
+			"order_count": s.OrderCount,
+			"createdAt":   s.CreatedAt, // decodeShop เติมให้แล้วถ้าไม่มี
+			"updatedAt":   s.UpdatedAt,
+			"index_v":     models.ShopIndexVersion,
+		}
+		if s.Address != nil {
+			fields["geohash"] = models.EncodeGeohash(s.Address.Latitude, s.Address.Longitude, models.GeohashPrecision)
+		}
+		if s.PriceMin != nil {
+			fields["price_min"] = *s.PriceMin
+		}
+		if s.PriceMax != nil {
+			fields["price_max"] = *s.PriceMax
+		}
+		if err := config.DB.Shops().Update(ctx, s.ID, fields); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// StockChange คือเมนูที่สต็อกเปลี่ยนจนต้องแจ้งร้าน / คำนวณช่วงราคาใหม่
+type StockChange struct {
+	MenuID    string `json:"menuId"`
+	Name      string `json:"name"`
+	Remaining int    `json:"remaining"`
+	Threshold int    `json:"low_threshold,omitempty"`
+	Low       bool   `json:"low,omitempty"`       // เพิ่งลงถึงเกณฑ์แจ้งเตือน
+	SoldOut   bool   `json:"sold_out,omitempty"`  // เพิ่งหมด
+	Restocked bool   `json:"restocked,omitempty"` // เคยหมดแล้วกลับมาขายได้
+}
+
+// StockResult คือผลของการตัด/คืนสต็อกใน transaction
+type StockResult struct {
+	ShopID  string
+	Held    map[string]string // menuId -> วันของยอด (เก็บลง Order.StockHeld)
+	Changes []StockChange
+}
+
+// StockLocation คือ timezone ที่ใช้นับวันของสต็อก daily (ตามเวลาเปิด-ปิดร้าน)
+func StockLocation(s *models.Shop) *time.Location {
+	if s == nil {
+		return (*models.OpeningHours)(nil).Location()
+	}
+	return s.Hours.Location()
+}
+
+// qtyByMenu รวมจำนวนต่อเมนู (เมนูเดียวกันคนละตัวเลือก = หลายบรรทัด)
+func qtyByMenu(items []models.OrderItem) (map[string]int, []string) {
+	qty := map[string]int{}
+	var ids []string
+	for _, it := range items {
+		if _, ok := qty[it.ID]; !ok {
+			ids = append(ids, it.ID)
+		}
+		qty[it.ID] += it.Qty
+	}
+	return qty, ids
+}
+
+// CheckStock ตรวจว่าสต็อกพอสำหรับทุกรายการ ณ วัน day (ไม่เขียนอะไร)
+func CheckStock(priced *PricedItems, shopID, day string) error {
+	qty, ids := qtyByMenu(priced.Items)
+	var problems []ItemProblem
+	for _, id := range ids {
+		m := priced.Menus[id]
+		if m == nil || m.Stock == nil {
+			continue
+		}
+		cur := m.Stock.Current(day)
+		switch {
+		case cur.Quantity <= 0:
+			problems = append(problems, ItemProblem{MenuID: id, Name: m.Name, Reason: ItemSoldOut})
+		case cur.Quantity < qty[id]:
+			problems = append(problems, ItemProblem{
+				MenuID: id, Name: m.Name, Reason: ItemLowStock,
+				Detail: fmt.Sprintf("only %d left", cur.Quantity),
+			})
+		}
+	}
+	if len(problems) > 0 {
+		return &ItemsUnavailableError{ShopID: shopID, Problems: problems}
+	}
+	return nil
+}
+
+// TakeStock ตัดสต็อกของทุกรายการ (ต้องเรียกใน transaction หลังอ่านครบแล้ว; ใช้เมนูจาก priced.Menus)
+// สต็อกไม่พอ → *ItemsUnavailableError
+func TakeStock(ctx context.Context, tx store.Repos, shop *models.Shop, priced *PricedItems, at time.Time) (*StockResult, error) {
+	day := models.StockDay(at, StockLocation(shop))
+	if err := CheckStock(priced, shop.ID, day); err != nil {
+		return nil, err
+	}
+	res := &StockResult{ShopID: shop.ID, Held: map[string]string{}}
+	qty, ids := qtyByMenu(priced.Items)
+	for _, id := range ids {
+		m := priced.Menus[id]
+		if m == nil || m.Stock == nil {
+			continue
+		}
+		cur := m.Stock.Current(day)
+		if err := writeStock(ctx, tx, res, m, cur, cur.Quantity-qty[id], at); err != nil {
+			return nil, err
+		}
+		res.Held[id] = ""
+		if cur.Mode == models.StockDaily {
+			res.Held[id] = day
+		}
+	}
+	return res, nil
+}
+
+// LoadHeldStock อ่านเมนูที่ออเดอร์ตัดสต็อกไว้ (ช่วงอ่านของ transaction ก่อน ReturnStock)
+func LoadHeldStock(ctx context.Context, tx store.Repos, ord *models.Order) (map[string]*models.MenuItem, error) {
+	menus := map[string]*models.MenuItem{}
+	for id := range ord.StockHeld {
+		m, err := tx.Menus().Get(ctx, ord.ShopID, id)
+		if errors.Is(err, store.ErrNotFound) {
+			continue // เมนูถูกลบไปแล้ว
+		}
+		if err != nil {
+			return nil, err
+		}
+		menus[id] = m
+	}
+	return menus, nil
+}
+
+// ReturnStock คืนสต็อกของออเดอร์ที่ถูกยกเลิก
+// ยอด daily คืนเฉพาะเมื่อยังเป็นวันเดียวกับที่ตัดไป (ข้ามวันแล้วยอดถูกรีเซ็ตไปแล้ว)
+func ReturnStock(ctx context.Context, tx store.Repos, ord *models.Order, menus map[string]*models.MenuItem, at time.Time) (*StockResult, error) {
+	res := &StockResult{ShopID: ord.ShopID}
+	qty, ids := qtyByMenu(ord.Items)
+	for _, id := range ids {
+		day, held := ord.StockHeld[id]
+		m := menus[id]
+		if !held || m == nil || m.Stock == nil {
+			continue
+		}
+		if m.Stock.Mode == models.StockDaily && m.Stock.Day != day {
+			continue
+		}
+		if err := writeStock(ctx, tx, res, m, *m.Stock, m.Stock.Quantity+qty[id], at); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// writeStock เขียนยอดใหม่ของเมนู พร้อม sold_out / สถานะแจ้งเตือน และจดสิ่งที่ต้องแจ้งลง res
+func writeStock(ctx context.Context, tx store.Repos, res *StockResult, m *models.MenuItem, cur models.MenuStock, remaining int, at time.Time) error {
+	if remaining < 0 {
+		remaining = 0
+	}
+	next := cur
+	next.Quantity = remaining
+	ch := StockChange{MenuID: m.ID, Name: m.Name, Remaining: remaining, Threshold: next.LowThreshold}
+	switch {
+	case remaining == 0:
+		ch.SoldOut = !m.SoldOut
+	case m.SoldOut:
+		ch.Restocked = true
+	}
+	if next.IsLow() && !next.LowAlerted {
+		next.LowAlerted, ch.Low = true, true
+	} else if remaining > next.LowThreshold {
+		next.LowAlerted = false
+	}
+	if err := tx.Menus().Update(ctx, m.ShopID, m.ID, map[string]any{
+		"stock":     next,
+		"sold_out":  remaining == 0,
+		"updatedAt": at,
+	}); err != nil {
+		return err
+	}
+	if ch.Low || ch.SoldOut || ch.Restocked {
+		res.Changes = append(res.Changes, ch)
+	}
+	return nil
+}
+
+// AfterStockChange เรียกหลัง commit: แจ้งร้านผ่าน stream ของร้าน และคำนวณช่วงราคา/ค้นหาใหม่เมื่อเมนูหมดหรือกลับมาขาย
+func AfterStockChange(ctx context.Context, res *StockResult) {
+	if res == nil || len(res.Changes) == 0 {
+		return
+	}
+	availability := false
+	for i := range res.Changes {
+		ch := res.Changes[i]
+		if ch.SoldOut || ch.Restocked {
+			availability = true
+			ReindexMenuItem(ctx, res.ShopID, ch.MenuID)
+		}
+		if ch.Low || ch.SoldOut {
+			OrderEvents.PublishStock(res.ShopID, &ch)
+		}
+	}
+	if availability {
+		if err := UpdateShopPriceRange(ctx, res.ShopID); err != nil {
+			log.Println("stock: price range", res.ShopID, err)
+		}
+	}
+}
+
+// SetMenuStock ตั้ง/เติม/ปิดการนับสต็อกของเมนู
+func SetMenuStock(ctx context.Context, shop *models.Shop, menuID string, req models.SetStockReq, at time.Time) (*models.MenuItem, *StockResult, error) {
+	res := &StockResult{ShopID: shop.ID}
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		res.Changes = nil
+		m, err := tx.Menus().Get(ctx, shop.ID, menuID)
+		if err != nil {
+			return err
+		}
+		if req.Mode == "off" {
+			if m.SoldOut {
+				res.Changes = append(res.Changes, StockChange{MenuID: m.ID, Name: m.Name, Restocked: true})
+			}
+			return tx.Menus().Update(ctx, shop.ID, menuID, map[string]any{
+				"stock":     nil,
+				"sold_out":  false,
+				"updatedAt": at,
+			})
+		}
+		st := models.MenuStock{Mode: req.Mode, Quantity: req.Quantity, LowThreshold: req.LowThreshold}
+		if req.Mode == models.StockDaily {
+			st.DailyQuantity = req.Quantity
+			st.Day = models.StockDay(at, StockLocation(shop))
+		}
+		return writeStock(ctx, tx, res, m, st, req.Quantity, at)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := config.DB.Menus().Get(ctx, shop.ID, menuID)
+	return m, res, err
+}
+
+// LowStockMenus คือเมนูที่สต็อกเหลือน้อยหรือหมดแล้ว ณ เวลา at (น้อยสุดขึ้นก่อน)
+func LowStockMenus(ctx context.Context, shop *models.Shop, at time.Time) ([]models.MenuItem, error) {
+	items, err := config.DB.Menus().List(ctx, shop.ID)
+	if err != nil {
+		return nil, err
+	}
+	day := models.StockDay(at, StockLocation(shop))
+	out := []models.MenuItem{}
+	for _, m := range items {
+		if m.Stock == nil {
+			continue
+		}
+		cur := m.Stock.Current(day)
+		if cur.Quantity <= 0 || cur.IsLow() {
+			m.Stock = &cur
+			m.SoldOut = cur.Quantity <= 0
+			out = append(out, m)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Stock.Quantity < out[j].Stock.Quantity })
+	return out, nil
+}
+
+/* ---------------- daily reset ---------------- */
+
+// stockResetter จำวันล่าสุดที่รีเซ็ตของแต่ละร้าน จะได้อ่านเมนูเฉพาะร้านที่ขึ้นวันใหม่
+type stockResetter struct {
+	mu   sync.Mutex
+	days map[string]string
+}
+
+var StockResetter = &stockResetter{days: map[string]string{}}
+
+// Run รีเซ็ตสต็อก daily ของร้านที่ขึ้นวันใหม่ (ตามเวลาท้องถิ่นของร้าน) คืนจำนวนเมนูที่รีเซ็ต
+func (sr *stockResetter) Run(ctx context.Context, now time.Time) (int, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	shops, err := config.DB.Shops().List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := range shops {
+		s := &shops[i]
+		if s.IsDeleted() {
+			continue
+		}
+		day := models.StockDay(now, StockLocation(s))
+		if sr.days[s.ID] == day {
+			continue
+		}
+		reset, err := resetDailyStock(ctx, s, day, now)
+		if err != nil {
+			return n, err
+		}
+		sr.days[s.ID] = day
+		n += reset
+	}
+	return n, nil
+}
+
+func resetDailyStock(ctx context.Context, s *models.Shop, day string, now time.Time) (int, error) {
+	items, err := config.DB.Menus().List(ctx, s.ID)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	res := &StockResult{ShopID: s.ID}
+	for _, it := range items {
+		if it.Stock == nil || it.Stock.Mode != models.StockDaily || it.Stock.Day == day {
+			continue
+		}
+		var changes []StockChange
+		err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+			one := &StockResult{ShopID: s.ID}
+			m, err := tx.Menus().Get(ctx, s.ID, it.ID)
+			if err != nil {
+				return err
+			}
+			if m.Stock == nil || m.Stock.Mode != models.StockDaily || m.Stock.Day == day {
+				return nil
+			}
+			cur := m.Stock.Current(day)
+			if err := writeStock(ctx, tx, one, m, cur, cur.Quantity, now); err != nil {
+				return err
+			}
+			changes = one.Changes
+			return nil
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		res.Changes = append(res.Changes, changes...)
+		n++
+	}
+	AfterStockChange(ctx, res)
+	return n, nil
+}
+
+// StartStockResetter รีเซ็ตสต็อกรายวันทุก every จนกว่า ctx จะถูกยกเลิก
+func StartStockResetter(ctx context.Context, every time.Duration) {
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		if n, err := StockResetter.Run(ctx, time.Now()); err != nil {
+			log.Println("stock reset:", err)
+		} else if n > 0 {
+			log.Printf("stock reset: %d menu items", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
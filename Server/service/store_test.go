@@ -0,0 +1,18 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// useMemoryStore สลับ config.DB เป็น store ในหน่วยความจำตลอด test นี้
+func useMemoryStore(t *testing.T) *store.Memory {
+	t.Helper()
+	prev := config.DB
+	mem := store.NewMemory()
+	config.DB = mem
+	t.Cleanup(func() { config.DB = prev })
+	return mem
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+
+	// ผล SessionActive ถูก cache ไว้สั้น ๆ เพื่อไม่ต้องอ่าน store ทุก request
+	sessionCacheTTL = 30 * time.Second
+)
+
+var (
+	ErrInvalidRefresh = errors.New("invalid refresh token")
+	ErrSessionRevoked = errors.New("session revoked or expired")
+)
+
+// TokenPair คือสิ่งที่ส่งกลับให้ client หลัง login / refresh
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	SessionID    string
+	ExpiresIn    int64 // วินาที
+}
+
+// Into ใส่ token ลงใน response เดิม (คง key "token" ไว้ให้ client เก่า)
+func (p *TokenPair) Into(m fiber.Map) fiber.Map {
+	m["token"] = p.AccessToken
+	m["refresh_token"] = p.RefreshToken
+	m["session_id"] = p.SessionID
+	m["expires_in"] = p.ExpiresIn
+	return m
+}
+
+// ClientInfo คือข้อมูลอุปกรณ์ที่ใช้แสดงในรายการ session
+type ClientInfo struct {
+	Device    string
+	UserAgent string
+	IP        string
+}
+
+// ClientInfoFrom อ่านข้อมูลอุปกรณ์จาก request (header X-Device-Name ถ้ามี)
+func ClientInfoFrom(c *fiber.Ctx) ClientInfo {
+	return ClientInfo{
+		Device:    strings.TrimSpace(c.Get("X-Device-Name")),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		IP:        c.IP(),
+	}
+}
+
+/* ---------------- issue / refresh / revoke ---------------- */
+
+// IssueSession สร้าง session ใหม่ให้บัญชี แล้วคืน access + refresh token
+func IssueSession(ctx context.Context, acct *models.User, info ClientInfo) (*TokenPair, error) {
+	secret, hash, err := newRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+	nowT := time.Now()
+	sess := &models.Session{
+		UserID:      acct.ID,
+		Role:        acct.Role,
+		RefreshHash: hash,
+		Device:      info.Device,
+		UserAgent:   info.UserAgent,
+		IP:          info.IP,
+		CreatedAt:   nowT,
+		LastUsedAt:  nowT,
+		ExpiresAt:   nowT.Add(RefreshTokenTTL),
+	}
+	if err := config.DB.Sessions().Create(ctx, sess); err != nil {
+		return nil, err
+	}
+	return buildPair(acct, sess.ID, secret)
+}
+
+// RefreshSession ตรวจ refresh token แล้วหมุนเป็นตัวใหม่ (ตัวเก่าใช้ไม่ได้อีก)
+// ถ้ามีคนเอา refresh token เก่ามาใช้ซ้ำ ถือว่าหลุด → revoke ทั้ง session
+func RefreshSession(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	sid, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || sid == "" || secret == "" {
+		return nil, ErrInvalidRefresh
+	}
+
+	var (
+		pair  *TokenPair
+		reuse bool
+	)
+	err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+		reuse = false
+		sess, err := tx.Sessions().Get(ctx, sid)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrInvalidRefresh
+		}
+		if err != nil {
+			return err
+		}
+		nowT := time.Now()
+		if !sess.Active(nowT) {
+			return ErrSessionRevoked
+		}
+		if !sameHash(sess.RefreshHash, hashSecret(secret)) {
+			reuse = true
+			return ErrInvalidRefresh
+		}
+		acct, err := tx.Accounts().GetAs(ctx, sess.Role, sess.UserID)
+		if err != nil {
+			return err
+		}
+
+		next, hash, err := newRefreshSecret()
+		if err != nil {
+			return err
+		}
+		if err := tx.Sessions().Update(ctx, sid, map[string]any{
+			"refreshHash": hash,
+			"lastUsedAt":  nowT,
+			"expiresAt":   nowT.Add(RefreshTokenTTL),
+		}); err != nil {
+			return err
+		}
+		pair, err = buildPair(acct, sid, next)
+		return err
+	})
+	if reuse {
+		_ = RevokeSession(ctx, sid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// ReissueSession ออก token ชุดใหม่ให้ session เดิม (เช่นหลัง verify email ให้ claim verified อัปเดต)
+func ReissueSession(ctx context.Context, sid string, acct *models.User) (*TokenPair, error) {
+	next, hash, err := newRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.DB.Sessions().Update(ctx, sid, map[string]any{
+		"refreshHash": hash,
+		"lastUsedAt":  time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+	return buildPair(acct, sid, next)
+}
+
+// RevokeSession ปิด session ทันที (logout / ลบอุปกรณ์)
+func RevokeSession(ctx context.Context, sid string) error {
+	err := config.DB.Sessions().Update(ctx, sid, map[string]any{"revokedAt": time.Now()})
+	forgetSession(sid)
+	return err
+}
+
+// RevokeUserSessions ปิดทุก session ของ user ยกเว้น keep (ส่ง "" = ปิดหมด)
+func RevokeUserSessions(ctx context.Context, userID, keep string) (int, error) {
+	list, err := config.DB.Sessions().ListByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	nowT := time.Now()
+	for _, s := range list {
+		if s.ID == keep || !s.Active(nowT) {
+			continue
+		}
+		if err := RevokeSession(ctx, s.ID); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// ActiveSessions คืน session ที่ยังใช้ได้ของ user
+func ActiveSessions(ctx context.Context, userID string) ([]models.Session, error) {
+	list, err := config.DB.Sessions().ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	nowT := time.Now()
+	out := make([]models.Session, 0, len(list))
+	for _, s := range list {
+		if s.Active(nowT) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+/* ---------------- session check (ProtectedAuth) ---------------- */
+
+type cachedSession struct {
+	userID  string
+	active  bool
+	checked time.Time
+}
+
+var sessionCache sync.Map // sid -> cachedSession
+
+// SessionActive ตรวจว่า sid ยังไม่ถูก revoke และเป็นของ userID จริง
+func SessionActive(ctx context.Context, sid, userID string) (bool, error) {
+	if v, ok := sessionCache.Load(sid); ok {
+		cs := v.(cachedSession)
+		if time.Since(cs.checked) < sessionCacheTTL {
+			return cs.active && cs.userID == userID, nil
+		}
+	}
+	sess, err := config.DB.Sessions().Get(ctx, sid)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	active := sess.Active(time.Now())
+	sessionCache.Store(sid, cachedSession{userID: sess.UserID, active: active, checked: time.Now()})
+	return active && sess.UserID == userID, nil
+}
+
+func forgetSession(sid string) { sessionCache.Delete(sid) }
+
+/* ---------------- helpers ---------------- */
+
+func buildPair(acct *models.User, sid, secret string) (*TokenPair, error) {
+	claims := jwt.MapClaims{
+		"user_id":  acct.ID,
+		"email":    acct.Email,
+		"username": acct.Username,
+		"verified": acct.Verified,
+		"role":     acct.Role,
+		"sid":      sid,
+		"exp":      time.Now().Add(AccessTokenTTL).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  t,
+		RefreshToken: sid + "." + secret,
+		SessionID:    sid,
+		ExpiresIn:    int64(AccessTokenTTL / time.Second),
+	}, nil
+}
+
+func newRefreshSecret() (secret, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret = base64.RawURLEncoding.EncodeToString(b)
+	return secret, hashSecret(secret), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func sameHash(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+// InsufficientFundsError: ยอดเงินไม่พอสำหรับการตัดเงิน
+type InsufficientFundsError struct {
+	Balance float64
+	Need    float64
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient funds: have %.2f, need %.2f", e.Balance, e.Need)
+}
+
+// WalletEntry คือรายการที่จะลง ledger (Amount เป็นค่าบวกเสมอ ทิศทางมาจาก Type)
+type WalletEntry struct {
+	Type    string
+	Amount  float64
+	RefType string
+	RefID   string
+	Note    string
+}
+
+// roundMoney ปัดเป็นสตางค์
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func signedAmount(e WalletEntry) (float64, error) {
+	amt := roundMoney(e.Amount)
+	switch e.Type {
+	case models.WalletTopUp, models.WalletRefund, models.WalletIncome:
+		if amt <= 0 {
+			return 0, fmt.Errorf("wallet %s amount must be > 0", e.Type)
+		}
+		return amt, nil
+	case models.WalletDebit:
+		if amt <= 0 {
+			return 0, fmt.Errorf("wallet %s amount must be > 0", e.Type)
+		}
+		return -amt, nil
+	case models.WalletAdjustment:
+		// adjustment ใช้เครื่องหมายตามที่ส่งมา (บวก/ลบได้)
+		if amt == 0 {
+			return 0, fmt.Errorf("wallet adjustment amount must not be 0")
+		}
+		return amt, nil
+	}
+	return 0, fmt.Errorf("unknown wallet entry type %q", e.Type)
+}
+
+// ApplyWalletEntry ลงรายการใน ledger และอัปเดต Cost ของ acct ในคราวเดียว
+// ต้องเรียกภายใน transaction และ acct ต้องอ่านมาจาก tx เดียวกัน (ฟังก์ชันนี้เขียนอย่างเดียว ไม่อ่าน)
+// ถ้า user เดิมยังไม่เคยมี ledger จะลงรายการยกยอด Cost เดิมให้ก่อน เพื่อให้ผลรวม ledger == Cost
+// acct ที่อ่านด้วย RoleVendor ลงบัญชี/ledger ฝั่ง vendors
+func ApplyWalletEntry(ctx context.Context, tx store.Repos, acct *models.User, e WalletEntry) (*models.WalletTxn, error) {
+	delta, err := signedAmount(e)
+	if err != nil {
+		return nil, err
+	}
+	role := store.RoleUser
+	if acct.Role == store.RoleVendor {
+		role = store.RoleVendor
+	}
+	balance := roundMoney(acct.Cost)
+	next := roundMoney(balance + delta)
+	if delta < 0 && next < 0 {
+		return nil, &InsufficientFundsError{Balance: balance, Need: -delta}
+	}
+
+	nowT := time.Now()
+	if !acct.WalletOpened && balance != 0 {
+		if err := tx.Wallet().Append(ctx, &models.WalletTxn{
+			UserID:       acct.ID,
+			Account:      role,
+			Type:         models.WalletAdjustment,
+			Amount:       balance,
+			BalanceAfter: balance,
+			RefType:      models.WalletRefOpening,
+			Note:         "opening balance",
+			CreatedAt:    nowT.Add(-time.Millisecond), // ให้อยู่ก่อนรายการจริงเสมอ
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	txn := &models.WalletTxn{
+		UserID:       acct.ID,
+		Account:      role,
+		Type:         e.Type,
+		Amount:       delta,
+		BalanceAfter: next,
+		RefType:      e.RefType,
+		RefID:        e.RefID,
+		Note:         e.Note,
+		CreatedAt:    nowT,
+	}
+	if err := tx.Wallet().Append(ctx, txn); err != nil {
+		return nil, err
+	}
+	if err := tx.Accounts().Update(ctx, role, acct.ID, map[string]any{
+		"Cost":          next,
+		"wallet_opened": true,
+		"updatedAt":     nowT,
+	}); err != nil {
+		return nil, err
+	}
+
+	acct.Cost = next
+	acct.WalletOpened = true
+	return txn, nil
+}
+
+// ApplyWallet อ่านบัญชี user แล้วลงรายการ (ใช้กับงานที่ไม่มี read อื่นใน tx เช่น top-up / refund)
+func ApplyWallet(ctx context.Context, tx store.Repos, userID string, e WalletEntry) (*models.WalletTxn, error) {
+	acct, err := tx.Accounts().GetAs(ctx, store.RoleUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyWalletEntry(ctx, tx, acct, e)
+}
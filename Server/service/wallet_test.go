@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/config"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/store"
+)
+
+func TestSignedAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   WalletEntry
+		want    float64
+		wantErr bool
+	}{
+		{"topup positive", WalletEntry{Type: models.WalletTopUp, Amount: 100}, 100, false},
+		{"refund positive", WalletEntry{Type: models.WalletRefund, Amount: 20.5}, 20.5, false},
+		{"income positive", WalletEntry{Type: models.WalletIncome, Amount: 7}, 7, false},
+		{"debit negated", WalletEntry{Type: models.WalletDebit, Amount: 45}, -45, false},
+		{"rounded to satang", WalletEntry{Type: models.WalletTopUp, Amount: 10.005}, 10.01, false},
+		{"adjustment keeps sign", WalletEntry{Type: models.WalletAdjustment, Amount: -3}, -3, false},
+		{"topup zero", WalletEntry{Type: models.WalletTopUp, Amount: 0}, 0, true},
+		{"debit negative", WalletEntry{Type: models.WalletDebit, Amount: -5}, 0, true},
+		{"rounds to zero", WalletEntry{Type: models.WalletRefund, Amount: 0.001}, 0, true},
+		{"adjustment zero", WalletEntry{Type: models.WalletAdjustment, Amount: 0}, 0, true},
+		{"unknown type", WalletEntry{Type: "gift", Amount: 1}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := signedAmount(tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyWalletEntry(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		role     string
+		opened   bool
+		cost     float64
+		entry    WalletEntry
+		wantCost float64
+		wantTxns []string // ชนิดรายการใน ledger เรียงตามเวลา
+		wantErr  bool
+	}{
+		{
+			name: "legacy balance gets opening entry", role: store.RoleUser, cost: 50,
+			entry:    WalletEntry{Type: models.WalletDebit, Amount: 30, RefType: models.WalletRefOrder, RefID: "o1"},
+			wantCost: 20, wantTxns: []string{models.WalletRefOpening, models.WalletDebit},
+		},
+		{
+			name: "opened wallet has no opening entry", role: store.RoleUser, opened: true, cost: 50,
+			entry:    WalletEntry{Type: models.WalletTopUp, Amount: 25},
+			wantCost: 75, wantTxns: []string{models.WalletTopUp},
+		},
+		{
+			name: "zero balance has no opening entry", role: store.RoleUser,
+			entry:    WalletEntry{Type: models.WalletTopUp, Amount: 10},
+			wantCost: 10, wantTxns: []string{models.WalletTopUp},
+		},
+		{
+			name: "vendor income", role: store.RoleVendor, cost: 5,
+			entry:    WalletEntry{Type: models.WalletIncome, Amount: 95},
+			wantCost: 100, wantTxns: []string{models.WalletRefOpening, models.WalletIncome},
+		},
+		{
+			name: "insufficient funds writes nothing", role: store.RoleUser, cost: 10,
+			entry:    WalletEntry{Type: models.WalletDebit, Amount: 10.01},
+			wantCost: 10, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMemoryStore(t)
+			u := &models.User{Role: tt.role, Cost: tt.cost, WalletOpened: tt.opened}
+			if err := config.DB.Accounts().Create(ctx, u); err != nil {
+				t.Fatal(err)
+			}
+
+			err := config.DB.RunTransaction(ctx, func(ctx context.Context, tx store.Repos) error {
+				acct, err := tx.Accounts().GetAs(ctx, tt.role, u.ID)
+				if err != nil {
+					return err
+				}
+				_, err = ApplyWalletEntry(ctx, tx, acct, tt.entry)
+				return err
+			})
+			if tt.wantErr {
+				var ife *InsufficientFundsError
+				if !errors.As(err, &ife) {
+					t.Fatalf("want *InsufficientFundsError, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			acct, err := config.DB.Accounts().GetAs(ctx, tt.role, u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if acct.Cost != tt.wantCost {
+				t.Fatalf("Cost = %v, want %v", acct.Cost, tt.wantCost)
+			}
+			txns, err := config.DB.Wallet().List(ctx, u.ID, store.WalletQuery{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(txns) != len(tt.wantTxns) {
+				t.Fatalf("got %d ledger entries, want %d (%+v)", len(txns), len(tt.wantTxns), txns)
+			}
+			sum := 0.0
+			for i, txn := range txns {
+				// List คืนรายการใหม่สุดก่อน
+				want := tt.wantTxns[len(txns)-1-i]
+				got := txn.Type
+				if txn.RefType == models.WalletRefOpening {
+					got = models.WalletRefOpening
+				}
+				if got != want {
+					t.Fatalf("entry %d = %s, want %s", i, got, want)
+				}
+				if txn.Account != tt.role {
+					t.Fatalf("entry %d account = %s, want %s", i, txn.Account, tt.role)
+				}
+				sum += txn.Amount
+			}
+			// ledger ต้องรวมได้เท่ากับ Cost (บัญชีที่เปิดไว้แล้วในเคสนี้ไม่มีรายการเก่าใน store)
+			if !tt.opened && len(txns) > 0 && roundMoney(sum) != tt.wantCost {
+				t.Fatalf("ledger sum = %v, want %v", sum, tt.wantCost)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// applyFields ทำงานแบบ firestore Update บน struct ใน memory:
+// key คือชื่อใน tag `firestore` (ไม่รองรับ nested path), key ที่ไม่รู้จักจะถูกข้าม
+func applyFields(dst any, fields map[string]any) error {
+	v := reflect.ValueOf(dst).Elem()
+	t := v.Type()
+
+	index := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("firestore"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		index[name] = i
+	}
+
+	for key, val := range fields {
+		i, ok := index[key]
+		if !ok {
+			continue
+		}
+		if err := setValue(v.Field(i), val); err != nil {
+			return fmt.Errorf("store: field %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func setValue(f reflect.Value, val any) error {
+	if val == nil {
+		f.Set(reflect.Zero(f.Type()))
+		return nil
+	}
+	rv := reflect.ValueOf(val)
+
+	target := f.Type()
+	if target.Kind() == reflect.Pointer && rv.Type() != target {
+		// ค่า scalar ที่จะเก็บลง pointer field เช่น float64 -> *float64
+		elem := reflect.New(target.Elem())
+		if err := setValue(elem.Elem(), val); err != nil {
+			return err
+		}
+		f.Set(elem)
+		return nil
+	}
+
+	switch {
+	case rv.Type().AssignableTo(target):
+		f.Set(rv)
+	case isNumber(rv.Kind()) && isNumber(target.Kind()):
+		f.Set(rv.Convert(target))
+	default:
+		// เช่น map จาก JSON body -> struct: แปลงผ่าน JSON
+		raw, err := json.Marshal(val)
+		if err != nil {
+			return err
+		}
+		ptr := reflect.New(target)
+		if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+			return err
+		}
+		f.Set(ptr.Elem())
+	}
+	return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
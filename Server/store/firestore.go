@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Firestore คือ Store ที่อ่าน/เขียนลง Cloud Firestore
+type Firestore struct {
+	fsRepo
+}
+
+// NewFirestore ห่อ client ที่สร้างไว้แล้ว (ดู config.InitFirebase)
+func NewFirestore(client *firestore.Client) *Firestore {
+	return &Firestore{fsRepo{client: client}}
+}
+
+func (f *Firestore) RunTransaction(ctx context.Context, fn TxFunc) error {
+	return f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return fn(ctx, fsRepo{client: f.client, tx: tx})
+	})
+}
+
+func (f *Firestore) Close() error { return f.client.Close() }
+
+// fsRepo คือฐานของทุก repository ฝั่ง Firestore
+// ถ้า tx != nil ทุกการอ่าน/เขียนจะผ่าน transaction นั้น
+type fsRepo struct {
+	client *firestore.Client
+	tx     *firestore.Transaction
+}
+
+func (r fsRepo) Shops() ShopStore                  { return fsShops{r} }
+func (r fsRepo) Menus() MenuStore                  { return fsMenus{r} }
+func (r fsRepo) MenuCategories() MenuCategoryStore { return fsMenuCategories{r} }
+func (r fsRepo) Orders() OrderStore                { return fsOrders{r} }
+func (r fsRepo) Carts() CartStore                  { return fsCarts{r} }
+func (r fsRepo) History() HistoryStore             { return fsHistory{r} }
+func (r fsRepo) Reservations() ReservationStore    { return fsReservations{r} }
+func (r fsRepo) Queue() QueueStore                 { return fsQueue{r} }
+func (r fsRepo) CalendarFeeds() CalendarFeedStore  { return fsCalendarFeeds{r} }
+func (r fsRepo) Accounts() AccountStore            { return fsAccounts{r} }
+func (r fsRepo) OTPs() OTPStore                    { return fsOTPs{r} }
+func (r fsRepo) Wallet() WalletStore               { return fsWallet{r} }
+func (r fsRepo) Sessions() SessionStore            { return fsSessions{r} }
+func (r fsRepo) Idempotency() IdempotencyStore     { return fsIdempotency{r} }
+func (r fsRepo) Audit() AuditStore                 { return fsAudit{r} }
+
+// -------- helpers --------
+
+func isNotFound(err error) bool { return status.Code(err) == codes.NotFound }
+
+func (r fsRepo) get(ctx context.Context, ref *firestore.DocumentRef) (*firestore.DocumentSnapshot, error) {
+	var (
+		snap *firestore.DocumentSnapshot
+		err  error
+	)
+	if r.tx != nil {
+		snap, err = r.tx.Get(ref)
+	} else {
+		snap, err = ref.Get(ctx)
+	}
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if !snap.Exists() {
+		return nil, ErrNotFound
+	}
+	return snap, nil
+}
+
+func (r fsRepo) all(ctx context.Context, q firestore.Query) ([]*firestore.DocumentSnapshot, error) {
+	if r.tx != nil {
+		return r.tx.Documents(q).GetAll()
+	}
+	return q.Documents(ctx).GetAll()
+}
+
+func (r fsRepo) set(ctx context.Context, ref *firestore.DocumentRef, data any, opts ...firestore.SetOption) error {
+	if r.tx != nil {
+		return r.tx.Set(ref, data, opts...)
+	}
+	_, err := ref.Set(ctx, data, opts...)
+	return err
+}
+
+func (r fsRepo) update(ctx context.Context, ref *firestore.DocumentRef, fields map[string]any) error {
+	updates := make([]firestore.Update, 0, len(fields))
+	for k, v := range fields {
+		updates = append(updates, firestore.Update{Path: k, Value: v})
+	}
+	var err error
+	if r.tx != nil {
+		err = r.tx.Update(ref, updates)
+	} else {
+		_, err = ref.Update(ctx, updates)
+	}
+	if isNotFound(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r fsRepo) delete(ctx context.Context, ref *firestore.DocumentRef) error {
+	if r.tx != nil {
+		return r.tx.Delete(ref)
+	}
+	_, err := ref.Delete(ctx)
+	return err
+}
+
+// atomically รัน fn ใน transaction ปัจจุบัน หรือเปิด transaction ใหม่ถ้ายังไม่มี
+// ใช้กับงานที่ต้องเขียนหลายเอกสารพร้อมกัน
+func (r fsRepo) atomically(ctx context.Context, fn func(tx *firestore.Transaction) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return fn(tx)
+	})
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+const colOTP = "otp"
+
+/* ---------------- ACCOUNT ---------------- */
+
+type fsAccounts struct{ fsRepo }
+
+func (r fsAccounts) col(role string) *firestore.CollectionRef {
+	if role == RoleVendor {
+		return r.client.Collection(colVendors)
+	}
+	return r.client.Collection(colUsers)
+}
+
+func decodeAccount(snap *firestore.DocumentSnapshot, role string) (*models.User, error) {
+	var u models.User
+	if err := snap.DataTo(&u); err != nil {
+		return nil, err
+	}
+	u.ID = snap.Ref.ID
+	u.Role = role
+	return &u, nil
+}
+
+func (r fsAccounts) Create(ctx context.Context, u *models.User) error {
+	if u.Role != RoleVendor {
+		u.Role = RoleUser
+	}
+	ref := r.col(u.Role).NewDoc()
+	if err := r.set(ctx, ref, map[string]interface{}{
+		"id":            ref.ID,
+		"email":         u.Email,
+		"firstname":     u.Firstname,
+		"lastname":      u.Lastname,
+		"username":      u.Username,
+		"password":      u.Password,
+		"verified":      u.Verified,
+		"Cost":          u.Cost,
+		"wallet_opened": u.WalletOpened,
+		"createdat":     firestore.ServerTimestamp,
+	}); err != nil {
+		return err
+	}
+	u.ID = ref.ID
+	return nil
+}
+
+func (r fsAccounts) Get(ctx context.Context, id string) (*models.User, error) {
+	u, err := r.GetAs(ctx, RoleUser, id)
+	if err == ErrNotFound {
+		return r.GetAs(ctx, RoleVendor, id)
+	}
+	return u, err
+}
+
+func (r fsAccounts) GetAs(ctx context.Context, role, id string) (*models.User, error) {
+	snap, err := r.get(ctx, r.col(role).Doc(id))
+	if err != nil {
+		return nil, err
+	}
+	return decodeAccount(snap, role)
+}
+
+func (r fsAccounts) findBy(ctx context.Context, field, value string) (*models.User, error) {
+	for _, role := range []string{RoleUser, RoleVendor} {
+		docs, err := r.all(ctx, r.col(role).Where(field, "==", value).Limit(1))
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) > 0 {
+			return decodeAccount(docs[0], role)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r fsAccounts) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findBy(ctx, "email", email)
+}
+
+func (r fsAccounts) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findBy(ctx, "username", username)
+}
+
+func (r fsAccounts) Update(ctx context.Context, role, id string, fields map[string]any) error {
+	return r.update(ctx, r.col(role).Doc(id), fields)
+}
+
+/* ---------------- OTP ---------------- */
+
+type fsOTPs struct{ fsRepo }
+
+func (r fsOTPs) doc(purpose, email string) *firestore.DocumentRef {
+	return r.client.Collection(colOTP).Doc(OTPKey(purpose, email))
+}
+
+func (r fsOTPs) Put(ctx context.Context, rec *models.OTPRecord) error {
+	return r.set(ctx, r.doc(rec.Purpose, rec.Email), rec)
+}
+
+func (r fsOTPs) Get(ctx context.Context, purpose, email string) (*models.OTPRecord, error) {
+	snap, err := r.get(ctx, r.doc(purpose, email))
+	if err != nil {
+		return nil, err
+	}
+	var rec models.OTPRecord
+	if err := snap.DataTo(&rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (r fsOTPs) Delete(ctx context.Context, purpose, email string) error {
+	return r.delete(ctx, r.doc(purpose, email))
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+const colAudit = "audit_logs"
+
+/* ---------------- AUDIT ---------------- */
+
+type fsAudit struct{ fsRepo }
+
+func (r fsAudit) Create(ctx context.Context, a *models.AuditRecord) error {
+	ref := r.client.Collection(colAudit).NewDoc()
+	if err := r.set(ctx, ref, a); err != nil {
+		return err
+	}
+	a.ID = ref.ID
+	return nil
+}
+
+func (r fsAudit) ListByTarget(ctx context.Context, targetType, targetID string) ([]models.AuditRecord, error) {
+	q := r.client.Collection(colAudit).Where("target_type", "==", targetType).Where("target_id", "==", targetID)
+	docs, err := r.all(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.AuditRecord, 0, len(docs))
+	for _, d := range docs {
+		var a models.AuditRecord
+		if err := d.DataTo(&a); err != nil {
+			return nil, err
+		}
+		a.ID = d.Ref.ID
+		out = append(out, a)
+	}
+	// เรียงในโค้ด ไม่ต้องมี composite index
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- CALENDAR FEED ---------------- */
+
+type fsCalendarFeeds struct{ fsRepo }
+
+func (r fsCalendarFeeds) doc(kind, ownerID string) *firestore.DocumentRef {
+	return r.client.Collection(models.ColCalendarFeeds).Doc(CalendarFeedKey(kind, ownerID))
+}
+
+func (r fsCalendarFeeds) Put(ctx context.Context, f *models.CalendarFeed) error {
+	return r.set(ctx, r.doc(f.Kind, f.OwnerID), f)
+}
+
+func (r fsCalendarFeeds) Get(ctx context.Context, kind, ownerID string) (*models.CalendarFeed, error) {
+	snap, err := r.get(ctx, r.doc(kind, ownerID))
+	if err != nil {
+		return nil, err
+	}
+	var f models.CalendarFeed
+	if err := snap.DataTo(&f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r fsCalendarFeeds) Delete(ctx context.Context, kind, ownerID string) error {
+	return r.delete(ctx, r.doc(kind, ownerID))
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+// ตั้ง TTL policy ของ Firestore บนฟิลด์ expiresAt ได้ด้วย (DeleteExpired ยังใช้เป็นตัวสำรอง)
+const colIdempotency = "idempotency_keys"
+
+/* ---------------- IDEMPOTENCY ---------------- */
+
+type fsIdempotency struct{ fsRepo }
+
+func (r fsIdempotency) doc(userID, key string) *firestore.DocumentRef {
+	return r.client.Collection(colIdempotency).Doc(IdempotencyDocID(userID, key))
+}
+
+func (r fsIdempotency) Get(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error) {
+	snap, err := r.get(ctx, r.doc(userID, key))
+	if err != nil {
+		return nil, err
+	}
+	var rec models.IdempotencyRecord
+	if err := snap.DataTo(&rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (r fsIdempotency) Put(ctx context.Context, rec *models.IdempotencyRecord) error {
+	return r.set(ctx, r.doc(rec.UserID, rec.Key), rec)
+}
+
+func (r fsIdempotency) Delete(ctx context.Context, userID, key string) error {
+	return r.delete(ctx, r.doc(userID, key))
+}
+
+func (r fsIdempotency) DeleteExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	return r.deleteQuery(ctx, r.client.Collection(colIdempotency).Where("expiresAt", "<", before), limit, nil)
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"cloud.google.com/go/firestore"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- MENU CATEGORY ---------------- */
+
+type fsMenuCategories struct{ fsRepo }
+
+func (r fsMenuCategories) col(shopID string) *firestore.CollectionRef {
+	return r.client.Collection(models.ColShops).Doc(shopID).Collection(models.SubColMenuCategories)
+}
+
+func decodeMenuCategory(d *firestore.DocumentSnapshot) (models.MenuCategory, error) {
+	var c models.MenuCategory
+	if err := d.DataTo(&c); err != nil {
+		return c, err
+	}
+	c.ID = d.Ref.ID
+	return c, nil
+}
+
+func (r fsMenuCategories) Create(ctx context.Context, c *models.MenuCategory) error {
+	ref := r.col(c.ShopID).NewDoc()
+	if err := r.set(ctx, ref, c); err != nil {
+		return err
+	}
+	c.ID = ref.ID
+	return nil
+}
+
+func (r fsMenuCategories) Get(ctx context.Context, shopID, id string) (*models.MenuCategory, error) {
+	snap, err := r.get(ctx, r.col(shopID).Doc(id))
+	if err != nil {
+		return nil, err
+	}
+	c, err := decodeMenuCategory(snap)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r fsMenuCategories) List(ctx context.Context, shopID string) ([]models.MenuCategory, error) {
+	docs, err := r.all(ctx, r.col(shopID).Query)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.MenuCategory, 0, len(docs))
+	for _, d := range docs {
+		c, err := decodeMenuCategory(d)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	sortMenuCategories(out)
+	return out, nil
+}
+
+func (r fsMenuCategories) Update(ctx context.Context, shopID, id string, fields map[string]any) error {
+	return r.update(ctx, r.col(shopID).Doc(id), fields)
+}
+
+func (r fsMenuCategories) Delete(ctx context.Context, shopID, id string) error {
+	return r.delete(ctx, r.col(shopID).Doc(id))
+}
+
+func (r fsMenuCategories) DeleteByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	return r.deleteQuery(ctx, r.col(shopID).Query, limit, nil)
+}
+
+// sortMenuCategories เรียงตาม position แล้วตามเวลาที่สร้าง
+func sortMenuCategories(cs []models.MenuCategory) {
+	sort.SliceStable(cs, func(i, j int) bool {
+		if cs[i].Position != cs[j].Position {
+			return cs[i].Position < cs[j].Position
+		}
+		return cs[i].CreatedAt.Before(cs[j].CreatedAt)
+	})
+}
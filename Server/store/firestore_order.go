@@ -0,0 +1,388 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+const (
+	colOrders    = "orders"
+	colCart      = "cart"
+	colUsers     = "users"
+	subColHist   = "history"
+	subColResv   = "reservations"
+	fieldMovedAt = "movedToHistoryAt"
+)
+
+/* ---------------- ORDER ---------------- */
+
+type fsOrders struct{ fsRepo }
+
+func (r fsOrders) col() *firestore.CollectionRef { return r.client.Collection(colOrders) }
+
+func (r fsOrders) Create(ctx context.Context, o *models.Order) error {
+	ref := r.col().NewDoc()
+	if err := r.set(ctx, ref, o); err != nil {
+		return err
+	}
+	o.ID = ref.ID
+	return nil
+}
+
+func (r fsOrders) Get(ctx context.Context, id string) (*models.Order, error) {
+	snap, err := r.get(ctx, r.col().Doc(id))
+	if err != nil {
+		return nil, err
+	}
+	o, err := decodeOrder(snap)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r fsOrders) List(ctx context.Context, f OrderFilter) ([]models.Order, error) {
+	q := r.col().Query
+	if f.ShopID != "" {
+		q = q.Where("shopId", "==", f.ShopID)
+	}
+	if f.CustomerID != "" {
+		q = q.Where("customerId", "==", f.CustomerID)
+	}
+	if f.Status != "" {
+		q = q.Where("status", "==", f.Status)
+	}
+	docs, err := r.all(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Order, 0, len(docs))
+	for _, d := range docs {
+		if o, err := decodeOrder(d); err == nil {
+			out = append(out, o)
+		}
+	}
+	// เรียงใน Go แทน OrderBy เพื่อไม่ต้องพึ่ง composite index
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r fsOrders) Update(ctx context.Context, id string, fields map[string]any) error {
+	return r.update(ctx, r.col().Doc(id), fields)
+}
+
+func (r fsOrders) Delete(ctx context.Context, id string) error {
+	return r.delete(ctx, r.col().Doc(id))
+}
+
+// decodeOrder อ่านออเดอร์พร้อม fallback ชื่อฟิลด์ของข้อมูลรุ่นเก่า
+func decodeOrder(d *firestore.DocumentSnapshot) (models.Order, error) {
+	var o models.Order
+	if err := d.DataTo(&o); err != nil {
+		return o, err
+	}
+	o.ID = d.Ref.ID
+	data := d.Data()
+
+	if o.CustomerID == "" {
+		if v, ok := data["userId"].(string); ok {
+			o.CustomerID = v
+		}
+	}
+	if strings.TrimSpace(o.ShopName) == "" {
+		if v, ok := data["shopName"].(string); ok {
+			o.ShopName = v
+		}
+	}
+	if len(o.Items) == 0 {
+		if raw, ok := data["order_items"]; ok && raw != nil { // เผื่อชื่ออื่น
+			o.Items = normalizeItems(raw)
+		}
+	}
+	return o, nil
+}
+
+// normalizeItems แปลงรายการเมนูแบบ map (qty=int, price=float64, เก็บ extras)
+func normalizeItems(raw any) []models.OrderItem {
+	mapToItem := func(m map[string]interface{}) models.OrderItem {
+		it := models.OrderItem{}
+		if s, ok := m["id"].(string); ok && s != "" {
+			it.ID = s
+		} else if s, ok := m["menuId"].(string); ok {
+			it.ID = s
+		}
+		it.Name, _ = m["name"].(string)
+		it.Image, _ = m["image"].(string)
+		it.Description, _ = m["description"].(string)
+		it.Price, _ = asFloat(m["price"])
+		if q, ok := asFloat(m["qty"]); ok {
+			it.Qty = int(q)
+		}
+		if ex, ok := m["extras"]; ok {
+			it.Extras = ex
+		}
+		it.BasePrice, _ = asFloat(m["basePrice"])
+		if raw, ok := m["options"].([]interface{}); ok {
+			for _, one := range raw {
+				om, ok := one.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				o := models.SelectedOption{}
+				o.GroupID, _ = om["group_id"].(string)
+				o.OptionID, _ = om["option_id"].(string)
+				o.GroupName, _ = om["group_name"].(string)
+				o.Name, _ = om["name"].(string)
+				o.PriceDelta, _ = asFloat(om["price_delta"])
+				it.Options = append(it.Options, o)
+			}
+		}
+		return it
+	}
+
+	out := make([]models.OrderItem, 0)
+	switch v := raw.(type) {
+	case []interface{}:
+		for _, one := range v {
+			if m, ok := one.(map[string]interface{}); ok {
+				out = append(out, mapToItem(m))
+			}
+		}
+	case []map[string]interface{}:
+		for _, m := range v {
+			out = append(out, mapToItem(m))
+		}
+	}
+	return out
+}
+
+/* ---------------- CART ---------------- */
+
+type fsCarts struct{ fsRepo }
+
+func (r fsCarts) Get(ctx context.Context, customerID string) (*models.Cart, error) {
+	snap, err := r.get(ctx, r.client.Collection(colCart).Doc(customerID))
+	if err != nil {
+		return nil, err
+	}
+	var cart models.Cart
+	if err := snap.DataTo(&cart); err != nil {
+		return nil, err
+	}
+	if cart.CustomerID == "" {
+		cart.CustomerID = customerID
+	}
+	return &cart, nil
+}
+
+func (r fsCarts) Put(ctx context.Context, cart *models.Cart) error {
+	return r.set(ctx, r.client.Collection(colCart).Doc(cart.CustomerID), cart)
+}
+
+/* ---------------- HISTORY ---------------- */
+
+type fsHistory struct{ fsRepo }
+
+func (r fsHistory) shopCol(shopID string) *firestore.CollectionRef {
+	return r.client.Collection(models.ColShops).Doc(shopID).Collection(subColHist)
+}
+
+func (r fsHistory) userCol(userID string) *firestore.CollectionRef {
+	return r.client.Collection(colUsers).Doc(userID).Collection(subColHist)
+}
+
+func (r fsHistory) Put(ctx context.Context, h *models.HistoryItem) error {
+	if h.ID == "" {
+		h.ID = h.HistoryID
+	}
+	return r.atomically(ctx, func(tx *firestore.Transaction) error {
+		if err := tx.Set(r.shopCol(h.ShopID).Doc(h.ID), h); err != nil {
+			return err
+		}
+		return tx.Set(r.userCol(h.UserID).Doc(h.ID), h)
+	})
+}
+
+func (r fsHistory) list(ctx context.Context, col *firestore.CollectionRef, hq HistoryQuery) ([]models.HistoryItem, error) {
+	q := col.OrderBy(fieldMovedAt, firestore.Desc)
+	if hq.Status != "" {
+		q = q.Where("status", "==", hq.Status)
+	}
+	if hq.Limit > 0 {
+		q = q.Limit(hq.Limit)
+	}
+	if !hq.StartAfter.IsZero() {
+		q = q.StartAfter(hq.StartAfter)
+	}
+	docs, err := r.all(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.HistoryItem, 0, len(docs))
+	for _, d := range docs {
+		var it models.HistoryItem
+		if err := d.DataTo(&it); err != nil {
+			continue
+		}
+		it.ID = d.Ref.ID
+		out = append(out, it)
+	}
+	return out, nil
+}
+
+func (r fsHistory) ListByShop(ctx context.Context, shopID string, q HistoryQuery) ([]models.HistoryItem, error) {
+	return r.list(ctx, r.shopCol(shopID), q)
+}
+
+func (r fsHistory) ListByUser(ctx context.Context, userID string, q HistoryQuery) ([]models.HistoryItem, error) {
+	return r.list(ctx, r.userCol(userID), q)
+}
+
+func (r fsHistory) GetForUser(ctx context.Context, userID, historyID string) (*models.HistoryItem, error) {
+	snap, err := r.get(ctx, r.userCol(userID).Doc(historyID))
+	if err != nil {
+		return nil, err
+	}
+	var it models.HistoryItem
+	if err := snap.DataTo(&it); err != nil {
+		return nil, err
+	}
+	it.ID = snap.Ref.ID
+	return &it, nil
+}
+
+/* ---------------- RESERVATION ---------------- */
+
+type fsReservations struct{ fsRepo }
+
+func (r fsReservations) Create(ctx context.Context, resv *models.Reservation) error {
+	ref := r.client.Collection(models.ColReservations).NewDoc()
+	resv.ID = ref.ID
+	userRef := r.client.Collection(colUsers).Doc(resv.UserID).Collection(subColResv).Doc(ref.ID)
+	return r.atomically(ctx, func(tx *firestore.Transaction) error {
+		if err := tx.Set(ref, resv); err != nil {
+			return err
+		}
+		return tx.Set(userRef, resv)
+	})
+}
+
+func (r fsReservations) Get(ctx context.Context, id string) (*models.Reservation, error) {
+	snap, err := r.get(ctx, r.client.Collection(models.ColReservations).Doc(id))
+	if err != nil {
+		return nil, err
+	}
+	out := r.decodeAll([]*firestore.DocumentSnapshot{snap})
+	if len(out) == 0 {
+		return nil, ErrNotFound
+	}
+	return &out[0], nil
+}
+
+func (r fsReservations) Save(ctx context.Context, resv *models.Reservation) error {
+	ref := r.client.Collection(models.ColReservations).Doc(resv.ID)
+	userRef := r.client.Collection(colUsers).Doc(resv.UserID).Collection(subColResv).Doc(resv.ID)
+	return r.atomically(ctx, func(tx *firestore.Transaction) error {
+		if err := tx.Set(ref, resv); err != nil {
+			return err
+		}
+		return tx.Set(userRef, resv)
+	})
+}
+
+func (r fsReservations) decodeAll(docs []*firestore.DocumentSnapshot) []models.Reservation {
+	out := make([]models.Reservation, 0, len(docs))
+	for _, d := range docs {
+		var resv models.Reservation
+		if err := d.DataTo(&resv); err != nil {
+			continue
+		}
+		if resv.ID == "" {
+			resv.ID = d.Ref.ID
+		}
+		out = append(out, resv)
+	}
+	return out
+}
+
+func (r fsReservations) ListByShop(ctx context.Context, shopID string) ([]models.Reservation, error) {
+	docs, err := r.all(ctx, r.client.Collection(models.ColReservations).Where("shop_id", "==", shopID))
+	if err != nil {
+		return nil, err
+	}
+	out := r.decodeAll(docs)
+	for i := range out {
+		if out[i].ShopID == "" {
+			out[i].ShopID = shopID
+		}
+	}
+	return out, nil
+}
+
+func (r fsReservations) ListOverdue(ctx context.Context, before time.Time, limit int) ([]models.Reservation, error) {
+	q := r.client.Collection(models.ColReservations).
+		Where("status", "in", []string{models.ReservationConfirmed, models.ReservationSeated}).
+		Where("slot_end", "<", before).
+		Limit(limit)
+	docs, err := r.all(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return r.decodeAll(docs), nil
+}
+
+func (r fsReservations) ListByShopDays(ctx context.Context, shopID string, days []string) ([]models.Reservation, error) {
+	if len(days) == 0 {
+		return []models.Reservation{}, nil
+	}
+	q := r.client.Collection(models.ColReservations).Where("shop_id", "==", shopID).Where("dayKey", "in", days)
+	docs, err := r.all(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return r.decodeAll(docs), nil
+}
+
+func (r fsReservations) LockDays(ctx context.Context, shopID string, days []string) error {
+	refs := make([]*firestore.DocumentRef, len(days))
+	for i, day := range days {
+		refs[i] = r.client.Collection(models.ColReservationDays).Doc(shopID + "_" + day)
+	}
+	return r.atomically(ctx, func(tx *firestore.Transaction) error {
+		// อ่านครบทุกวันก่อนเขียน (transaction ห้ามอ่านหลังเขียน)
+		counts := make([]int64, len(refs))
+		for i, ref := range refs {
+			snap, err := tx.Get(ref)
+			if err != nil && !isNotFound(err) {
+				return err
+			}
+			if err == nil && snap.Exists() {
+				counts[i], _ = snap.Data()["bookings"].(int64)
+			}
+		}
+		for i, ref := range refs {
+			if err := tx.Set(ref, map[string]any{
+				"shop_id":   shopID,
+				"day":       days[i],
+				"bookings":  counts[i] + 1,
+				"updatedAt": time.Now(),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r fsReservations) ListByUser(ctx context.Context, userID string) ([]models.Reservation, error) {
+	docs, err := r.all(ctx, r.client.Collection(colUsers).Doc(userID).Collection(subColResv).Query)
+	if err != nil {
+		return nil, err
+	}
+	return r.decodeAll(docs), nil
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- PURGE (ลบข้อมูลที่ผูกกับร้านเป็นรอบ ๆ) ----------------
+   ทุกเมธอดในไฟล์นี้เขียนด้วย BulkWriter ไม่ผ่าน transaction (จำนวนเอกสารเกินขีดจำกัดของ transaction ได้) */
+
+// bulk ส่งทุก write ที่ op สร้างแล้วรอผล (เอกสารที่หายไปแล้วไม่ถือว่าผิด)
+func (r fsRepo) bulk(ctx context.Context, op func(bw *firestore.BulkWriter) ([]*firestore.BulkWriterJob, error)) error {
+	bw := r.client.BulkWriter(ctx)
+	jobs, err := op(bw)
+	bw.End()
+	if err != nil {
+		return err
+	}
+	for _, j := range jobs {
+		if _, err := j.Results(); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteQuery ลบเอกสารจาก q สูงสุด limit ตัว พร้อมเอกสารที่ extra คืนมา (เช่น สำเนาใต้ users/{id})
+func (r fsRepo) deleteQuery(ctx context.Context, q firestore.Query, limit int, extra func(*firestore.DocumentSnapshot) []*firestore.DocumentRef) (int, error) {
+	docs, err := q.Limit(limit).Documents(ctx).GetAll()
+	if err != nil || len(docs) == 0 {
+		return 0, err
+	}
+	err = r.bulk(ctx, func(bw *firestore.BulkWriter) ([]*firestore.BulkWriterJob, error) {
+		jobs := make([]*firestore.BulkWriterJob, 0, len(docs))
+		for _, d := range docs {
+			refs := []*firestore.DocumentRef{d.Ref}
+			if extra != nil {
+				refs = append(refs, extra(d)...)
+			}
+			for _, ref := range refs {
+				j, err := bw.Delete(ref)
+				if err != nil {
+					return jobs, err
+				}
+				jobs = append(jobs, j)
+			}
+		}
+		return jobs, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(docs), nil
+}
+
+func (r fsShops) ListPurgeable(ctx context.Context, before time.Time) ([]models.Shop, error) {
+	docs, err := r.all(ctx, r.col().Where("purge_after", "<=", before))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Shop, 0, len(docs))
+	for _, d := range docs {
+		if s := decodeShop(d); s.IsDeleted() {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (r fsMenus) DeleteByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	return r.deleteQuery(ctx, r.col(shopID).Query, limit, nil)
+}
+
+func (r fsOrders) DeleteByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	return r.deleteQuery(ctx, r.col().Where("shopId", "==", shopID), limit, nil)
+}
+
+func (r fsHistory) DeleteByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	return r.deleteQuery(ctx, r.shopCol(shopID).Query, limit, nil)
+}
+
+func (r fsReservations) DeleteByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	q := r.client.Collection(models.ColReservations).Where("shop_id", "==", shopID)
+	n, err := r.deleteQuery(ctx, q, limit, func(d *firestore.DocumentSnapshot) []*firestore.DocumentRef {
+		uid, _ := d.Data()["user_id"].(string)
+		if uid == "" {
+			return nil
+		}
+		return []*firestore.DocumentRef{r.client.Collection(colUsers).Doc(uid).Collection(subColResv).Doc(d.Ref.ID)}
+	})
+	if err != nil || n > 0 {
+		return n, err
+	}
+	// การจองหมดแล้ว: ลบเอกสาร lock รายวันต่อ
+	return r.deleteQuery(ctx, r.client.Collection(models.ColReservationDays).Where("shop_id", "==", shopID), limit, nil)
+}
+
+func (r fsQueue) DeleteByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	n, err := r.deleteQuery(ctx, r.col().Where("shop_id", "==", shopID), limit, nil)
+	if err != nil || n > 0 {
+		return n, err
+	}
+	// บัตรหมดแล้ว: ลบตัวนับเลขคิวรายวันต่อ
+	return r.deleteQuery(ctx, r.client.Collection(models.ColQueueDays).Where("shop_id", "==", shopID), limit, nil)
+}
+
+func (r fsCarts) ReleaseByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	docs, err := r.client.Collection(colCart).Where("shopId", "==", shopID).Limit(limit).Documents(ctx).GetAll()
+	if err != nil || len(docs) == 0 {
+		return 0, err
+	}
+	now := time.Now()
+	err = r.bulk(ctx, func(bw *firestore.BulkWriter) ([]*firestore.BulkWriterJob, error) {
+		jobs := make([]*firestore.BulkWriterJob, 0, len(docs))
+		for _, d := range docs {
+			j, err := bw.Update(d.Ref, []firestore.Update{
+				{Path: "items", Value: []models.CartItem{}},
+				{Path: "shopId", Value: ""},
+				{Path: "shop_name", Value: ""},
+				{Path: "vendorId", Value: ""},
+				{Path: "total", Value: 0},
+				{Path: "updatedAt", Value: now},
+			})
+			if err != nil {
+				return jobs, err
+			}
+			jobs = append(jobs, j)
+		}
+		return jobs, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(docs), nil
+}
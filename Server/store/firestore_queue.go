@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- WALK-IN QUEUE ---------------- */
+
+type fsQueue struct{ fsRepo }
+
+func (r fsQueue) col() *firestore.CollectionRef {
+	return r.client.Collection(models.ColQueueTickets)
+}
+
+func decodeQueueTickets(docs []*firestore.DocumentSnapshot) []models.QueueTicket {
+	out := make([]models.QueueTicket, 0, len(docs))
+	for _, d := range docs {
+		var t models.QueueTicket
+		if err := d.DataTo(&t); err != nil {
+			continue
+		}
+		t.ID = d.Ref.ID
+		out = append(out, t)
+	}
+	sortQueueTickets(out)
+	return out
+}
+
+func (r fsQueue) Create(ctx context.Context, t *models.QueueTicket) error {
+	ref := r.col().NewDoc()
+	if err := r.set(ctx, ref, t); err != nil {
+		return err
+	}
+	t.ID = ref.ID
+	return nil
+}
+
+func (r fsQueue) Get(ctx context.Context, id string) (*models.QueueTicket, error) {
+	snap, err := r.get(ctx, r.col().Doc(id))
+	if err != nil {
+		return nil, err
+	}
+	out := decodeQueueTickets([]*firestore.DocumentSnapshot{snap})
+	if len(out) == 0 {
+		return nil, ErrNotFound
+	}
+	return &out[0], nil
+}
+
+func (r fsQueue) Update(ctx context.Context, id string, fields map[string]any) error {
+	return r.update(ctx, r.col().Doc(id), fields)
+}
+
+var queueActiveStatuses = []string{models.QueueWaiting, models.QueueCalled}
+
+func (r fsQueue) ListActive(ctx context.Context, shopID string) ([]models.QueueTicket, error) {
+	docs, err := r.all(ctx, r.col().Where("shop_id", "==", shopID).Where("status", "in", queueActiveStatuses))
+	if err != nil {
+		return nil, err
+	}
+	return decodeQueueTickets(docs), nil
+}
+
+func (r fsQueue) ListActiveByUser(ctx context.Context, userID string) ([]models.QueueTicket, error) {
+	docs, err := r.all(ctx, r.col().Where("user_id", "==", userID).Where("status", "in", queueActiveStatuses))
+	if err != nil {
+		return nil, err
+	}
+	return decodeQueueTickets(docs), nil
+}
+
+func (r fsQueue) NextNumber(ctx context.Context, shopID, day string) (int, error) {
+	ref := r.client.Collection(models.ColQueueDays).Doc(shopID + "_" + day)
+	var n int64
+	err := r.atomically(ctx, func(tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		n = 0
+		if err == nil && snap.Exists() {
+			n, _ = snap.Data()["last"].(int64)
+		}
+		n++
+		return tx.Set(ref, map[string]any{
+			"shop_id":   shopID,
+			"day":       day,
+			"last":      n,
+			"updatedAt": time.Now(),
+		})
+	})
+	return int(n), err
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"cloud.google.com/go/firestore"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+const colSessions = "sessions"
+
+/* ---------------- SESSION ---------------- */
+
+type fsSessions struct{ fsRepo }
+
+func (r fsSessions) col() *firestore.CollectionRef { return r.client.Collection(colSessions) }
+
+func (r fsSessions) Create(ctx context.Context, s *models.Session) error {
+	ref := r.col().NewDoc()
+	if err := r.set(ctx, ref, s); err != nil {
+		return err
+	}
+	s.ID = ref.ID
+	return nil
+}
+
+func (r fsSessions) Get(ctx context.Context, id string) (*models.Session, error) {
+	snap, err := r.get(ctx, r.col().Doc(id))
+	if err != nil {
+		return nil, err
+	}
+	var s models.Session
+	if err := snap.DataTo(&s); err != nil {
+		return nil, err
+	}
+	s.ID = snap.Ref.ID
+	return &s, nil
+}
+
+func (r fsSessions) ListByUser(ctx context.Context, userID string) ([]models.Session, error) {
+	docs, err := r.all(ctx, r.col().Where("userId", "==", userID))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Session, 0, len(docs))
+	for _, d := range docs {
+		var s models.Session
+		if err := d.DataTo(&s); err != nil {
+			continue
+		}
+		s.ID = d.Ref.ID
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastUsedAt.After(out[j].LastUsedAt) })
+	return out, nil
+}
+
+func (r fsSessions) Update(ctx context.Context, id string, fields map[string]any) error {
+	return r.update(ctx, r.col().Doc(id), fields)
+}
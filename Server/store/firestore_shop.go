@@ -0,0 +1,391 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+const colVendors = "vendors"
+
+/* ---------------- SHOP ---------------- */
+
+type fsShops struct{ fsRepo }
+
+func (r fsShops) col() *firestore.CollectionRef { return r.client.Collection(models.ColShops) }
+
+func (r fsShops) Create(ctx context.Context, s *models.Shop) error {
+	if s.VendorID != "" {
+		s.VendorRef = r.client.Collection(colVendors).Doc(s.VendorID)
+	}
+	ref := r.col().NewDoc()
+	if err := r.set(ctx, ref, s); err != nil {
+		return err
+	}
+	s.ID = ref.ID
+	return nil
+}
+
+func (r fsShops) Get(ctx context.Context, id string) (*models.Shop, error) {
+	snap, err := r.get(ctx, r.col().Doc(id))
+	if err != nil {
+		return nil, err
+	}
+	s := decodeShop(snap)
+	return &s, nil
+}
+
+func (r fsShops) GetByVendor(ctx context.Context, vendorID string) (*models.Shop, error) {
+	vendorRef := r.client.Collection(colVendors).Doc(vendorID)
+	docs, err := r.all(ctx, r.col().Where("vendor_id", "==", vendorRef).Limit(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, ErrNotFound
+	}
+	s := decodeShop(docs[0])
+	return &s, nil
+}
+
+func (r fsShops) List(ctx context.Context) ([]models.Shop, error) {
+	docs, err := r.all(ctx, r.col().Query)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Shop, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, decodeShop(d))
+	}
+	return out, nil
+}
+
+func (r fsShops) Page(ctx context.Context, q ShopQuery) ([]models.Shop, error) {
+	fq := r.col().Query
+	if q.Type != "" {
+		fq = fq.Where("type", "==", q.Type)
+	}
+	if q.Open != nil {
+		fq = fq.Where("status", "==", *q.Open)
+	}
+	if q.OrderActive != nil {
+		fq = fq.Where("order_active", "==", *q.OrderActive)
+	}
+	if q.ReserveActive != nil {
+		fq = fq.Where("reserve_active", "==", *q.ReserveActive)
+	}
+	if q.QueueActive != nil {
+		fq = fq.Where("queue_active", "==", *q.QueueActive)
+	}
+
+	// filter + orderBy แต่ละชุดต้องมี composite index ใน Firestore
+	var field string
+	dir := firestore.Desc
+	switch q.Sort {
+	case ShopSortPriceAsc:
+		field, dir = "price_min", firestore.Asc
+	case ShopSortPriceDesc:
+		field = "price_min"
+	case ShopSortPopular:
+		field = "order_count"
+	default:
+		field = "createdAt"
+	}
+	fq = fq.OrderBy(field, dir).OrderBy(firestore.DocumentID, dir)
+	if a := q.After; a != nil {
+		var v any = a.N
+		if field == "createdAt" {
+			v = a.T
+		}
+		fq = fq.StartAfter(v, r.col().Doc(a.ID))
+	}
+	if q.Limit > 0 {
+		fq = fq.Limit(q.Limit)
+	}
+
+	docs, err := r.all(ctx, fq)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Shop, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, decodeShop(d))
+	}
+	return out, nil
+}
+
+func (r fsShops) AddOrderCount(ctx context.Context, id string, delta int) error {
+	return r.update(ctx, r.col().Doc(id), map[string]any{"order_count": firestore.Increment(delta)})
+}
+
+func (r fsShops) ListByGeohash(ctx context.Context, prefixes []string) ([]models.Shop, error) {
+	seen := map[string]bool{}
+	out := make([]models.Shop, 0)
+	for _, p := range prefixes {
+		// ช่วง [p, p+"~") = ทุก geohash ที่ขึ้นต้นด้วย p ("~" มากกว่าทุกตัวใน base32)
+		q := r.col().Where("geohash", ">=", p).Where("geohash", "<", p+"~")
+		docs, err := r.all(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range docs {
+			if seen[d.Ref.ID] {
+				continue
+			}
+			seen[d.Ref.ID] = true
+			out = append(out, decodeShop(d))
+		}
+	}
+	return out, nil
+}
+
+func (r fsShops) Update(ctx context.Context, id string, fields map[string]any) error {
+	return r.update(ctx, r.col().Doc(id), fields)
+}
+
+func (r fsShops) Delete(ctx context.Context, id string) error {
+	return r.delete(ctx, r.col().Doc(id))
+}
+
+// decodeShop แปลงเอกสารร้านแบบยืดหยุ่น (ข้อมูลเก่ามีทั้ง string/number/map ปนกัน)
+func decodeShop(d *firestore.DocumentSnapshot) models.Shop {
+	data := d.Data()
+
+	var s models.Shop
+	s.ID = d.Ref.ID
+
+	// --- Strings ---
+	if v, ok := data["shop_name"].(string); ok {
+		s.ShopName = v
+	}
+	if v, ok := data["description"].(string); ok {
+		s.Description = v
+	}
+	if v, ok := data["type"].(string); ok {
+		s.Type = v
+	}
+	if v, ok := data["image"].(string); ok {
+		s.Image = v
+	}
+	if v, ok := data["status"].(bool); ok {
+		s.Status = v
+	}
+	if v, ok := data["geohash"].(string); ok {
+		s.Geohash = v
+	}
+	if f, ok := asFloat(data["order_count"]); ok {
+		s.OrderCount = int(f)
+	}
+	if f, ok := asFloat(data["index_v"]); ok {
+		s.IndexVersion = int(f)
+	}
+
+	// --- Numbers (float64) ---
+	s.PriceMin = asFloatPtr(data["price_min"])
+	s.PriceMax = asFloatPtr(data["price_max"])
+	if f := asFloatPtr(data["menu_active_count"]); f != nil {
+		n := int(*f)
+		s.MenuActiveCnt = &n
+	}
+
+	// --- Booleans ---
+	s.OrderActive = asBool(data["order_active"])
+	s.ReserveActive = asBool(data["reserve_active"])
+	s.QueueActive = asBool(data["queue_active"])
+
+	// --- Address (ละติจูด/ลองจิจูด) ---
+	switch v := data["address"].(type) {
+	case *latlng.LatLng:
+		s.Address = v
+	case map[string]interface{}:
+		lat, ok1 := asFloat(v["latitude"])
+		lng, ok2 := asFloat(v["longitude"])
+		if !ok1 || !ok2 {
+			lat, ok1 = asFloat(v["Latitude"])
+			lng, ok2 = asFloat(v["Longitude"])
+		}
+		if ok1 && ok2 {
+			s.Address = &latlng.LatLng{Latitude: lat, Longitude: lng}
+		}
+	}
+
+	// --- VendorRef ---
+	switch v := data["vendor_id"].(type) {
+	case *firestore.DocumentRef:
+		s.VendorRef = v
+		s.VendorID = v.ID
+	case string:
+		s.VendorID = v
+	}
+
+	// --- Opening hours / reservation settings (nested map) ---
+	var sched struct {
+		Hours       *models.OpeningHours        `firestore:"hours"`
+		Schedule    *models.ShopSchedule        `firestore:"schedule"`
+		Reservation *models.ReservationSettings `firestore:"reservation_settings"`
+	}
+	if d.DataTo(&sched) == nil {
+		s.Hours, s.Schedule, s.ReservationSettings = sched.Hours, sched.Schedule, sched.Reservation
+	}
+
+	// --- Soft delete ---
+	if t := asTime(data["deleted_at"]); !t.IsZero() {
+		s.DeletedAt = &t
+	}
+	if t := asTime(data["purge_after"]); !t.IsZero() {
+		s.PurgeAfter = &t
+	}
+	if v, ok := data["deleted_by"].(string); ok {
+		s.DeletedBy = v
+	}
+
+	// --- Timestamps ---
+	s.CreatedAt = asTime(data["createdAt"])
+	s.UpdatedAt = asTime(data["updatedAt"])
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now()
+	}
+	if s.UpdatedAt.IsZero() {
+		s.UpdatedAt = s.CreatedAt
+	}
+	return s
+}
+
+/* ---------------- MENU ---------------- */
+
+type fsMenus struct{ fsRepo }
+
+func (r fsMenus) col(shopID string) *firestore.CollectionRef {
+	return r.client.Collection(models.ColShops).Doc(shopID).Collection(models.SubColMenu)
+}
+
+func (r fsMenus) Create(ctx context.Context, item *models.MenuItem) error {
+	ref := r.col(item.ShopID).NewDoc()
+	item.ID = ref.ID
+	return r.set(ctx, ref, item)
+}
+
+func (r fsMenus) Get(ctx context.Context, shopID, menuID string) (*models.MenuItem, error) {
+	snap, err := r.get(ctx, r.col(shopID).Doc(menuID))
+	if err != nil {
+		return nil, err
+	}
+	m := decodeMenuItem(snap, shopID)
+	return &m, nil
+}
+
+func (r fsMenus) List(ctx context.Context, shopID string) ([]models.MenuItem, error) {
+	docs, err := r.all(ctx, r.col(shopID).Query)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.MenuItem, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, decodeMenuItem(d, shopID))
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r fsMenus) Update(ctx context.Context, shopID, menuID string, fields map[string]any) error {
+	return r.update(ctx, r.col(shopID).Doc(menuID), fields)
+}
+
+func (r fsMenus) Delete(ctx context.Context, shopID, menuID string) error {
+	return r.delete(ctx, r.col(shopID).Doc(menuID))
+}
+
+// decodeMenuItem รองรับเอกสารเก่าที่ใช้ชื่อฟิลด์ตัวใหญ่ (Name/Price/...)
+func decodeMenuItem(d *firestore.DocumentSnapshot, shopID string) models.MenuItem {
+	data := d.Data()
+	pick := func(keys ...string) any {
+		for _, k := range keys {
+			if v, ok := data[k]; ok && v != nil {
+				return v
+			}
+		}
+		return nil
+	}
+
+	m := models.MenuItem{ID: d.Ref.ID, ShopID: shopID}
+	m.Name, _ = pick("name", "Name").(string)
+	m.Description, _ = pick("description", "Description").(string)
+	m.Image, _ = pick("image", "Image").(string)
+	m.Price, _ = asFloat(pick("price", "Price"))
+	m.Active = asBool(pick("active", "Active"))
+	m.CreatedAt = asTime(pick("createdAt", "CreatedAt"))
+	m.UpdatedAt = asTime(pick("updatedAt", "UpdatedAt"))
+	m.Extra, _ = data["extra"].(map[string]interface{})
+	m.CategoryID, _ = data["category_id"].(string)
+	if f, ok := asFloat(data["position"]); ok {
+		m.Position = int(f)
+	}
+	var nested struct {
+		OptionGroups []models.OptionGroup        `firestore:"option_groups"`
+		Stock        *models.MenuStock           `firestore:"stock"`
+		Availability []models.AvailabilityWindow `firestore:"availability"`
+	}
+	if d.DataTo(&nested) == nil {
+		m.OptionGroups, m.Stock, m.Availability = nested.OptionGroups, nested.Stock, nested.Availability
+	}
+	m.SoldOut = asBool(data["sold_out"])
+	return m
+}
+
+/* ---------------- value helpers ---------------- */
+
+func asFloat(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case int64:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func asFloatPtr(v any) *float64 {
+	if f, ok := asFloat(v); ok {
+		return &f
+	}
+	return nil
+}
+
+func asBool(v any) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true" || b == "1"
+	case float64:
+		return b == 1
+	case int64:
+		return b == 1
+	case int:
+		return b == 1
+	}
+	return false
+}
+
+func asTime(v any) time.Time {
+	switch t := v.(type) {
+	case time.Time:
+		return t
+	case string:
+		if p, err := time.Parse(time.RFC3339, t); err == nil {
+			return p
+		}
+	}
+	return time.Time{}
+}
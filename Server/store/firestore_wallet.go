@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+const subColWallet = "wallet_transactions"
+
+/* ---------------- WALLET ---------------- */
+
+type fsWallet struct{ fsRepo }
+
+func (r fsWallet) col(userID string) *firestore.CollectionRef {
+	return r.client.Collection(colUsers).Doc(userID).Collection(subColWallet)
+}
+
+func (r fsWallet) Append(ctx context.Context, t *models.WalletTxn) error {
+	ref := r.col(t.UserID).NewDoc()
+	if t.Account == RoleVendor {
+		ref = r.client.Collection(colVendors).Doc(t.UserID).Collection(subColWallet).NewDoc()
+	}
+	if err := r.set(ctx, ref, t); err != nil {
+		return err
+	}
+	t.ID = ref.ID
+	return nil
+}
+
+func (r fsWallet) List(ctx context.Context, userID string, wq WalletQuery) ([]models.WalletTxn, error) {
+	q := r.col(userID).OrderBy("createdAt", firestore.Desc)
+	if wq.Type != "" {
+		q = q.Where("type", "==", wq.Type)
+	}
+	if !wq.StartAfter.IsZero() {
+		q = q.StartAfter(wq.StartAfter)
+	}
+	if wq.Limit > 0 {
+		q = q.Limit(wq.Limit)
+	}
+	docs, err := r.all(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.WalletTxn, 0, len(docs))
+	for _, d := range docs {
+		var t models.WalletTxn
+		if err := d.DataTo(&t); err != nil {
+			continue
+		}
+		t.ID = d.Ref.ID
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func (r fsWallet) Sum(ctx context.Context, userID string) (float64, int, error) {
+	docs, err := r.all(ctx, r.col(userID).Select("amount"))
+	if err != nil {
+		return 0, 0, err
+	}
+	var sum float64
+	for _, d := range docs {
+		if v, ok := asFloat(d.Data()["amount"]); ok {
+			sum += v
+		}
+	}
+	return sum, len(docs), nil
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"sync"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+// Memory คือ Store ที่เก็บทุกอย่างไว้ใน process (ใช้รันเครื่อง local / เทสต์ โดยไม่ต้องต่อเน็ต)
+// ทุก transaction ถือ lock ตัวเดียวของทั้ง store และ rollback ด้วย undo log เมื่อ fn คืน error
+type Memory struct {
+	memRepo
+}
+
+func NewMemory() *Memory {
+	return &Memory{memRepo{db: newMemDB()}}
+}
+
+func (m *Memory) RunTransaction(ctx context.Context, fn TxFunc) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	tx := &memTx{}
+	if err := fn(ctx, memRepo{db: m.db, tx: tx}); err != nil {
+		tx.rollback()
+		return err
+	}
+	return nil
+}
+
+func (m *Memory) Close() error { return nil }
+
+type memDB struct {
+	mu sync.Mutex
+
+	shops            map[string]models.Shop
+	menus            map[string]map[string]models.MenuItem     // shopId -> menuId
+	menuCategories   map[string]map[string]models.MenuCategory // shopId -> categoryId
+	orders           map[string]models.Order
+	carts            map[string]models.Cart
+	shopHistory      map[string]map[string]models.HistoryItem // shopId -> historyId
+	userHistory      map[string]map[string]models.HistoryItem // userId -> historyId
+	reservations     map[string]models.Reservation
+	userReservations map[string]map[string]models.Reservation // userId -> reservationId
+	accounts         map[string]map[string]models.User        // role -> id
+	otps             map[string]models.OTPRecord              // OTPKey(purpose, email)
+	wallet           map[string]map[string]models.WalletTxn   // userId -> txnId
+	sessions         map[string]models.Session
+	idempotency      map[string]models.IdempotencyRecord // IdempotencyDocID(userId, key)
+	audit            map[string]models.AuditRecord
+	queueTickets     map[string]models.QueueTicket
+	queueDays        map[string]int // {shopId}_{day} -> เลขคิวล่าสุด
+	calendarFeeds    map[string]models.CalendarFeed
+}
+
+func newMemDB() *memDB {
+	return &memDB{
+		shops:            map[string]models.Shop{},
+		menus:            map[string]map[string]models.MenuItem{},
+		menuCategories:   map[string]map[string]models.MenuCategory{},
+		orders:           map[string]models.Order{},
+		carts:            map[string]models.Cart{},
+		shopHistory:      map[string]map[string]models.HistoryItem{},
+		userHistory:      map[string]map[string]models.HistoryItem{},
+		reservations:     map[string]models.Reservation{},
+		userReservations: map[string]map[string]models.Reservation{},
+		queueTickets:     map[string]models.QueueTicket{},
+		queueDays:        map[string]int{},
+		calendarFeeds:    map[string]models.CalendarFeed{},
+		accounts: map[string]map[string]models.User{
+			RoleUser:   {},
+			RoleVendor: {},
+		},
+		otps:        map[string]models.OTPRecord{},
+		wallet:      map[string]map[string]models.WalletTxn{},
+		sessions:    map[string]models.Session{},
+		idempotency: map[string]models.IdempotencyRecord{},
+		audit:       map[string]models.AuditRecord{},
+	}
+}
+
+// memTx เก็บ undo log ของ transaction ที่กำลังรัน
+type memTx struct {
+	undo []func()
+}
+
+func (tx *memTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = nil
+}
+
+// memRepo คือฐานของทุก repository ฝั่ง memory
+// ถ้า tx != nil แปลว่า lock ถูกถืออยู่แล้วโดย RunTransaction
+type memRepo struct {
+	db *memDB
+	tx *memTx
+}
+
+func (r memRepo) Shops() ShopStore                  { return memShops{r} }
+func (r memRepo) Menus() MenuStore                  { return memMenus{r} }
+func (r memRepo) MenuCategories() MenuCategoryStore { return memMenuCategories{r} }
+func (r memRepo) Orders() OrderStore                { return memOrders{r} }
+func (r memRepo) Carts() CartStore                  { return memCarts{r} }
+func (r memRepo) History() HistoryStore             { return memHistory{r} }
+func (r memRepo) Reservations() ReservationStore    { return memReservations{r} }
+func (r memRepo) Queue() QueueStore                 { return memQueue{r} }
+func (r memRepo) CalendarFeeds() CalendarFeedStore  { return memCalendarFeeds{r} }
+func (r memRepo) Accounts() AccountStore            { return memAccounts{r} }
+func (r memRepo) OTPs() OTPStore                    { return memOTPs{r} }
+func (r memRepo) Wallet() WalletStore               { return memWallet{r} }
+func (r memRepo) Sessions() SessionStore            { return memSessions{r} }
+func (r memRepo) Idempotency() IdempotencyStore     { return memIdempotency{r} }
+func (r memRepo) Audit() AuditStore                 { return memAudit{r} }
+
+// lock ใช้แบบ `defer r.lock()()`
+func (r memRepo) lock() func() {
+	if r.tx != nil {
+		return func() {}
+	}
+	r.db.mu.Lock()
+	return r.db.mu.Unlock
+}
+
+// -------- helpers --------
+
+func memPut[K comparable, V any](tx *memTx, m map[K]V, k K, v V) {
+	if tx != nil {
+		old, existed := m[k]
+		tx.undo = append(tx.undo, func() {
+			if existed {
+				m[k] = old
+			} else {
+				delete(m, k)
+			}
+		})
+	}
+	m[k] = v
+}
+
+func memDelete[K comparable, V any](tx *memTx, m map[K]V, k K) {
+	old, existed := m[k]
+	if !existed {
+		return
+	}
+	if tx != nil {
+		tx.undo = append(tx.undo, func() { m[k] = old })
+	}
+	delete(m, k)
+}
+
+func memSub[V any](m map[string]map[string]V, k string) map[string]V {
+	sub, ok := m[k]
+	if !ok {
+		sub = map[string]V{}
+		m[k] = sub
+	}
+	return sub
+}
+
+const idAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// newID สุ่ม id ยาว 20 ตัวแบบเดียวกับ auto-id ของ Firestore
+func newID() string {
+	b := make([]byte, 20)
+	max := big.NewInt(int64(len(idAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = idAlphabet[n.Int64()]
+	}
+	return string(b)
+}
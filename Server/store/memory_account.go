@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- ACCOUNT ---------------- */
+
+type memAccounts struct{ memRepo }
+
+func (r memAccounts) Create(ctx context.Context, u *models.User) error {
+	defer r.lock()()
+	if u.Role != RoleVendor {
+		u.Role = RoleUser
+	}
+	u.ID = newID()
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	memPut(r.tx, r.db.accounts[u.Role], u.ID, *u)
+	return nil
+}
+
+func (r memAccounts) Get(ctx context.Context, id string) (*models.User, error) {
+	defer r.lock()()
+	for _, role := range []string{RoleUser, RoleVendor} {
+		if u, ok := r.db.accounts[role][id]; ok {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memAccounts) GetAs(ctx context.Context, role, id string) (*models.User, error) {
+	defer r.lock()()
+	u, ok := r.db.accounts[accountRole(role)][id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+func (r memAccounts) findBy(match func(u models.User) bool) (*models.User, error) {
+	defer r.lock()()
+	for _, role := range []string{RoleUser, RoleVendor} {
+		for _, u := range r.db.accounts[role] {
+			if match(u) {
+				return &u, nil
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memAccounts) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findBy(func(u models.User) bool { return u.Email == email })
+}
+
+func (r memAccounts) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findBy(func(u models.User) bool { return u.Username == username })
+}
+
+func (r memAccounts) Update(ctx context.Context, role, id string, fields map[string]any) error {
+	defer r.lock()()
+	accounts := r.db.accounts[accountRole(role)]
+	u, ok := accounts[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := applyFields(&u, fields); err != nil {
+		return err
+	}
+	memPut(r.tx, accounts, id, u)
+	return nil
+}
+
+// accountRole แปลง role ที่ไม่รู้จักให้เป็น user เหมือนฝั่ง Firestore
+func accountRole(role string) string {
+	if role == RoleVendor {
+		return RoleVendor
+	}
+	return RoleUser
+}
+
+/* ---------------- OTP ---------------- */
+
+type memOTPs struct{ memRepo }
+
+func (r memOTPs) Put(ctx context.Context, rec *models.OTPRecord) error {
+	defer r.lock()()
+	memPut(r.tx, r.db.otps, OTPKey(rec.Purpose, rec.Email), *rec)
+	return nil
+}
+
+func (r memOTPs) Get(ctx context.Context, purpose, email string) (*models.OTPRecord, error) {
+	defer r.lock()()
+	rec, ok := r.db.otps[OTPKey(purpose, email)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &rec, nil
+}
+
+func (r memOTPs) Delete(ctx context.Context, purpose, email string) error {
+	defer r.lock()()
+	memDelete(r.tx, r.db.otps, OTPKey(purpose, email))
+	return nil
+}
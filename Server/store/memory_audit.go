@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- AUDIT ---------------- */
+
+type memAudit struct{ memRepo }
+
+func (r memAudit) Create(ctx context.Context, a *models.AuditRecord) error {
+	defer r.lock()()
+	a.ID = newID()
+	memPut(r.tx, r.db.audit, a.ID, *a)
+	return nil
+}
+
+func (r memAudit) ListByTarget(ctx context.Context, targetType, targetID string) ([]models.AuditRecord, error) {
+	defer r.lock()()
+	out := make([]models.AuditRecord, 0)
+	for _, a := range r.db.audit {
+		if a.TargetType == targetType && a.TargetID == targetID {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- CALENDAR FEED ---------------- */
+
+type memCalendarFeeds struct{ memRepo }
+
+func (r memCalendarFeeds) Put(ctx context.Context, f *models.CalendarFeed) error {
+	defer r.lock()()
+	memPut(r.tx, r.db.calendarFeeds, CalendarFeedKey(f.Kind, f.OwnerID), *f)
+	return nil
+}
+
+func (r memCalendarFeeds) Get(ctx context.Context, kind, ownerID string) (*models.CalendarFeed, error) {
+	defer r.lock()()
+	f, ok := r.db.calendarFeeds[CalendarFeedKey(kind, ownerID)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &f, nil
+}
+
+func (r memCalendarFeeds) Delete(ctx context.Context, kind, ownerID string) error {
+	defer r.lock()()
+	memDelete(r.tx, r.db.calendarFeeds, CalendarFeedKey(kind, ownerID))
+	return nil
+}
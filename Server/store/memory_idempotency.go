@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- IDEMPOTENCY ---------------- */
+
+type memIdempotency struct{ memRepo }
+
+func (r memIdempotency) Get(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error) {
+	defer r.lock()()
+	rec, ok := r.db.idempotency[IdempotencyDocID(userID, key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	rec.ResponseBody = append([]byte(nil), rec.ResponseBody...)
+	return &rec, nil
+}
+
+func (r memIdempotency) Put(ctx context.Context, rec *models.IdempotencyRecord) error {
+	defer r.lock()()
+	cp := *rec
+	cp.ResponseBody = append([]byte(nil), rec.ResponseBody...)
+	memPut(r.tx, r.db.idempotency, IdempotencyDocID(rec.UserID, rec.Key), cp)
+	return nil
+}
+
+func (r memIdempotency) Delete(ctx context.Context, userID, key string) error {
+	defer r.lock()()
+	memDelete(r.tx, r.db.idempotency, IdempotencyDocID(userID, key))
+	return nil
+}
+
+func (r memIdempotency) DeleteExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	defer r.lock()()
+	return memDeleteWhere(r.tx, r.db.idempotency, limit, func(rec models.IdempotencyRecord) bool {
+		return rec.ExpiresAt.Before(before)
+	}, nil), nil
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- MENU CATEGORY ---------------- */
+
+type memMenuCategories struct{ memRepo }
+
+func (r memMenuCategories) Create(ctx context.Context, c *models.MenuCategory) error {
+	defer r.lock()()
+	c.ID = newID()
+	memPut(r.tx, memSub(r.db.menuCategories, c.ShopID), c.ID, *c)
+	return nil
+}
+
+func (r memMenuCategories) Get(ctx context.Context, shopID, id string) (*models.MenuCategory, error) {
+	defer r.lock()()
+	c, ok := r.db.menuCategories[shopID][id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &c, nil
+}
+
+func (r memMenuCategories) List(ctx context.Context, shopID string) ([]models.MenuCategory, error) {
+	defer r.lock()()
+	items := r.db.menuCategories[shopID]
+	out := make([]models.MenuCategory, 0, len(items))
+	for _, c := range items {
+		out = append(out, c)
+	}
+	sortMenuCategories(out)
+	return out, nil
+}
+
+func (r memMenuCategories) Update(ctx context.Context, shopID, id string, fields map[string]any) error {
+	defer r.lock()()
+	items := r.db.menuCategories[shopID]
+	c, ok := items[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := applyFields(&c, fields); err != nil {
+		return err
+	}
+	memPut(r.tx, items, id, c)
+	return nil
+}
+
+func (r memMenuCategories) Delete(ctx context.Context, shopID, id string) error {
+	defer r.lock()()
+	memDelete(r.tx, r.db.menuCategories[shopID], id)
+	return nil
+}
+
+func (r memMenuCategories) DeleteByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	defer r.lock()()
+	all := func(models.MenuCategory) bool { return true }
+	return memDeleteWhere(r.tx, memSub(r.db.menuCategories, shopID), limit, all, nil), nil
+}
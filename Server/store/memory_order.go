@@ -0,0 +1,257 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- ORDER ---------------- */
+
+type memOrders struct{ memRepo }
+
+func cloneOrder(o models.Order) models.Order {
+	o.Items = append([]models.OrderItem(nil), o.Items...)
+	if o.StockHeld != nil {
+		held := make(map[string]string, len(o.StockHeld))
+		for k, v := range o.StockHeld {
+			held[k] = v
+		}
+		o.StockHeld = held
+	}
+	return o
+}
+
+func (r memOrders) Create(ctx context.Context, o *models.Order) error {
+	defer r.lock()()
+	o.ID = newID()
+	memPut(r.tx, r.db.orders, o.ID, cloneOrder(*o))
+	return nil
+}
+
+func (r memOrders) Get(ctx context.Context, id string) (*models.Order, error) {
+	defer r.lock()()
+	o, ok := r.db.orders[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	o = cloneOrder(o)
+	return &o, nil
+}
+
+func (r memOrders) List(ctx context.Context, f OrderFilter) ([]models.Order, error) {
+	defer r.lock()()
+	out := make([]models.Order, 0)
+	for _, o := range r.db.orders {
+		if f.ShopID != "" && o.ShopID != f.ShopID {
+			continue
+		}
+		if f.CustomerID != "" && o.CustomerID != f.CustomerID {
+			continue
+		}
+		if f.Status != "" && o.Status != f.Status {
+			continue
+		}
+		out = append(out, cloneOrder(o))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r memOrders) Update(ctx context.Context, id string, fields map[string]any) error {
+	defer r.lock()()
+	o, ok := r.db.orders[id]
+	if !ok {
+		return ErrNotFound
+	}
+	o = cloneOrder(o)
+	if err := applyFields(&o, fields); err != nil {
+		return err
+	}
+	memPut(r.tx, r.db.orders, id, o)
+	return nil
+}
+
+func (r memOrders) Delete(ctx context.Context, id string) error {
+	defer r.lock()()
+	memDelete(r.tx, r.db.orders, id)
+	return nil
+}
+
+/* ---------------- CART ---------------- */
+
+type memCarts struct{ memRepo }
+
+func cloneCart(c models.Cart) models.Cart {
+	c.Items = append([]models.CartItem(nil), c.Items...)
+	return c
+}
+
+func (r memCarts) Get(ctx context.Context, customerID string) (*models.Cart, error) {
+	defer r.lock()()
+	c, ok := r.db.carts[customerID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c = cloneCart(c)
+	return &c, nil
+}
+
+func (r memCarts) Put(ctx context.Context, cart *models.Cart) error {
+	defer r.lock()()
+	memPut(r.tx, r.db.carts, cart.CustomerID, cloneCart(*cart))
+	return nil
+}
+
+/* ---------------- HISTORY ---------------- */
+
+type memHistory struct{ memRepo }
+
+func cloneHistory(h models.HistoryItem) models.HistoryItem {
+	h.Items = append([]models.OrderItem(nil), h.Items...)
+	return h
+}
+
+func (r memHistory) Put(ctx context.Context, h *models.HistoryItem) error {
+	defer r.lock()()
+	if h.ID == "" {
+		h.ID = h.HistoryID
+	}
+	memPut(r.tx, memSub(r.db.shopHistory, h.ShopID), h.ID, cloneHistory(*h))
+	memPut(r.tx, memSub(r.db.userHistory, h.UserID), h.ID, cloneHistory(*h))
+	return nil
+}
+
+func filterHistory(items map[string]models.HistoryItem, q HistoryQuery) []models.HistoryItem {
+	out := make([]models.HistoryItem, 0, len(items))
+	for _, h := range items {
+		if q.Status != "" && h.Status != q.Status {
+			continue
+		}
+		if !q.StartAfter.IsZero() && !h.MovedToHistoryAt.Before(q.StartAfter) {
+			continue
+		}
+		out = append(out, cloneHistory(h))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].MovedToHistoryAt.After(out[j].MovedToHistoryAt) })
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out
+}
+
+func (r memHistory) ListByShop(ctx context.Context, shopID string, q HistoryQuery) ([]models.HistoryItem, error) {
+	defer r.lock()()
+	return filterHistory(r.db.shopHistory[shopID], q), nil
+}
+
+func (r memHistory) ListByUser(ctx context.Context, userID string, q HistoryQuery) ([]models.HistoryItem, error) {
+	defer r.lock()()
+	return filterHistory(r.db.userHistory[userID], q), nil
+}
+
+func (r memHistory) GetForUser(ctx context.Context, userID, historyID string) (*models.HistoryItem, error) {
+	defer r.lock()()
+	h, ok := r.db.userHistory[userID][historyID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	h = cloneHistory(h)
+	return &h, nil
+}
+
+/* ---------------- RESERVATION ---------------- */
+
+type memReservations struct{ memRepo }
+
+func (r memReservations) Create(ctx context.Context, resv *models.Reservation) error {
+	defer r.lock()()
+	resv.ID = newID()
+	memPut(r.tx, r.db.reservations, resv.ID, *resv)
+	memPut(r.tx, memSub(r.db.userReservations, resv.UserID), resv.ID, *resv)
+	return nil
+}
+
+func (r memReservations) Get(ctx context.Context, id string) (*models.Reservation, error) {
+	defer r.lock()()
+	resv, ok := r.db.reservations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	resv.History = append([]models.ReservationStatusChange(nil), resv.History...)
+	return &resv, nil
+}
+
+func (r memReservations) Save(ctx context.Context, resv *models.Reservation) error {
+	defer r.lock()()
+	if _, ok := r.db.reservations[resv.ID]; !ok {
+		return ErrNotFound
+	}
+	cp := *resv
+	cp.History = append([]models.ReservationStatusChange(nil), resv.History...)
+	memPut(r.tx, r.db.reservations, cp.ID, cp)
+	memPut(r.tx, memSub(r.db.userReservations, cp.UserID), cp.ID, cp)
+	return nil
+}
+
+func (r memReservations) ListByShop(ctx context.Context, shopID string) ([]models.Reservation, error) {
+	defer r.lock()()
+	out := make([]models.Reservation, 0)
+	for _, resv := range r.db.reservations {
+		if resv.ShopID == shopID {
+			out = append(out, resv)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r memReservations) ListByShopDays(ctx context.Context, shopID string, days []string) ([]models.Reservation, error) {
+	defer r.lock()()
+	want := map[string]bool{}
+	for _, d := range days {
+		want[d] = true
+	}
+	out := make([]models.Reservation, 0)
+	for _, resv := range r.db.reservations {
+		if resv.ShopID == shopID && want[resv.DayKey] {
+			out = append(out, resv)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r memReservations) ListOverdue(ctx context.Context, before time.Time, limit int) ([]models.Reservation, error) {
+	defer r.lock()()
+	out := make([]models.Reservation, 0)
+	for _, resv := range r.db.reservations {
+		if len(out) >= limit {
+			break
+		}
+		if (resv.Status == models.ReservationConfirmed || resv.Status == models.ReservationSeated) &&
+			resv.SlotEnd != nil && resv.SlotEnd.Before(before) {
+			resv.History = append([]models.ReservationStatusChange(nil), resv.History...)
+			out = append(out, resv)
+		}
+	}
+	return out, nil
+}
+
+// LockDays ไม่ต้องทำอะไร: transaction ของ Memory ถือ lock ทั้ง store อยู่แล้ว
+func (r memReservations) LockDays(ctx context.Context, shopID string, days []string) error {
+	return nil
+}
+
+func (r memReservations) ListByUser(ctx context.Context, userID string) ([]models.Reservation, error) {
+	defer r.lock()()
+	items := r.db.userReservations[userID]
+	out := make([]models.Reservation, 0, len(items))
+	for _, resv := range items {
+		out = append(out, resv)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
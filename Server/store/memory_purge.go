@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- PURGE ---------------- */
+
+// memDeleteWhere ลบค่าใน m ที่ match สูงสุด limit ตัว (then ถูกเรียกกับทุกตัวที่ลบ) คืนจำนวนที่ลบ
+func memDeleteWhere[V any](tx *memTx, m map[string]V, limit int, match func(V) bool, then func(string, V)) int {
+	n := 0
+	for k, v := range m {
+		if n >= limit {
+			break
+		}
+		if !match(v) {
+			continue
+		}
+		memDelete(tx, m, k)
+		if then != nil {
+			then(k, v)
+		}
+		n++
+	}
+	return n
+}
+
+func (r memShops) ListPurgeable(ctx context.Context, before time.Time) ([]models.Shop, error) {
+	defer r.lock()()
+	out := make([]models.Shop, 0)
+	for _, s := range r.db.shops {
+		if s.IsDeleted() && s.PurgeAfter != nil && !s.PurgeAfter.After(before) {
+			out = append(out, cloneShop(s))
+		}
+	}
+	return out, nil
+}
+
+func (r memMenus) DeleteByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	defer r.lock()()
+	all := func(models.MenuItem) bool { return true }
+	return memDeleteWhere(r.tx, memSub(r.db.menus, shopID), limit, all, nil), nil
+}
+
+func (r memOrders) DeleteByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	defer r.lock()()
+	match := func(o models.Order) bool { return o.ShopID == shopID }
+	return memDeleteWhere(r.tx, r.db.orders, limit, match, nil), nil
+}
+
+func (r memHistory) DeleteByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	defer r.lock()()
+	all := func(models.HistoryItem) bool { return true }
+	return memDeleteWhere(r.tx, memSub(r.db.shopHistory, shopID), limit, all, nil), nil
+}
+
+func (r memReservations) DeleteByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	defer r.lock()()
+	match := func(resv models.Reservation) bool { return resv.ShopID == shopID }
+	return memDeleteWhere(r.tx, r.db.reservations, limit, match, func(id string, resv models.Reservation) {
+		memDelete(r.tx, memSub(r.db.userReservations, resv.UserID), id)
+	}), nil
+}
+
+func (r memQueue) DeleteByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	defer r.lock()()
+	match := func(t models.QueueTicket) bool { return t.ShopID == shopID }
+	if n := memDeleteWhere(r.tx, r.db.queueTickets, limit, match, nil); n > 0 {
+		return n, nil
+	}
+	n := 0
+	for key := range r.db.queueDays {
+		if n < limit && strings.HasPrefix(key, shopID+"_") {
+			memDelete(r.tx, r.db.queueDays, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r memCarts) ReleaseByShop(ctx context.Context, shopID string, limit int) (int, error) {
+	defer r.lock()()
+	n := 0
+	for id, cart := range r.db.carts {
+		if n >= limit {
+			break
+		}
+		if cart.ShopID != shopID {
+			continue
+		}
+		memPut(r.tx, r.db.carts, id, models.Cart{CustomerID: cart.CustomerID, Items: []models.CartItem{}, UpdatedAt: time.Now()})
+		n++
+	}
+	return n, nil
+}
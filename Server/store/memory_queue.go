@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- WALK-IN QUEUE ---------------- */
+
+type memQueue struct{ memRepo }
+
+// sortQueueTickets เรียงตามเวลาที่รับบัตร (เลขคิวเริ่มใหม่ทุกวัน จึงใช้เรียงข้ามวันไม่ได้)
+func sortQueueTickets(items []models.QueueTicket) {
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		}
+		return items[i].Number < items[j].Number
+	})
+}
+
+func (r memQueue) Create(ctx context.Context, t *models.QueueTicket) error {
+	defer r.lock()()
+	t.ID = newID()
+	memPut(r.tx, r.db.queueTickets, t.ID, *t)
+	return nil
+}
+
+func (r memQueue) Get(ctx context.Context, id string) (*models.QueueTicket, error) {
+	defer r.lock()()
+	t, ok := r.db.queueTickets[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &t, nil
+}
+
+func (r memQueue) Update(ctx context.Context, id string, fields map[string]any) error {
+	defer r.lock()()
+	t, ok := r.db.queueTickets[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := applyFields(&t, fields); err != nil {
+		return err
+	}
+	memPut(r.tx, r.db.queueTickets, id, t)
+	return nil
+}
+
+func (r memQueue) listActive(match func(models.QueueTicket) bool) []models.QueueTicket {
+	out := make([]models.QueueTicket, 0)
+	for _, t := range r.db.queueTickets {
+		if t.IsActive() && match(t) {
+			out = append(out, t)
+		}
+	}
+	sortQueueTickets(out)
+	return out
+}
+
+func (r memQueue) ListActive(ctx context.Context, shopID string) ([]models.QueueTicket, error) {
+	defer r.lock()()
+	return r.listActive(func(t models.QueueTicket) bool { return t.ShopID == shopID }), nil
+}
+
+func (r memQueue) ListActiveByUser(ctx context.Context, userID string) ([]models.QueueTicket, error) {
+	defer r.lock()()
+	return r.listActive(func(t models.QueueTicket) bool { return t.UserID == userID }), nil
+}
+
+func (r memQueue) NextNumber(ctx context.Context, shopID, day string) (int, error) {
+	defer r.lock()()
+	key := shopID + "_" + day
+	n := r.db.queueDays[key] + 1
+	memPut(r.tx, r.db.queueDays, key, n)
+	return n, nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- SESSION ---------------- */
+
+type memSessions struct{ memRepo }
+
+func cloneSession(s models.Session) models.Session {
+	if s.RevokedAt != nil {
+		t := *s.RevokedAt
+		s.RevokedAt = &t
+	}
+	return s
+}
+
+func (r memSessions) Create(ctx context.Context, s *models.Session) error {
+	defer r.lock()()
+	s.ID = newID()
+	memPut(r.tx, r.db.sessions, s.ID, cloneSession(*s))
+	return nil
+}
+
+func (r memSessions) Get(ctx context.Context, id string) (*models.Session, error) {
+	defer r.lock()()
+	s, ok := r.db.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	s = cloneSession(s)
+	return &s, nil
+}
+
+func (r memSessions) ListByUser(ctx context.Context, userID string) ([]models.Session, error) {
+	defer r.lock()()
+	out := make([]models.Session, 0)
+	for _, s := range r.db.sessions {
+		if s.UserID == userID {
+			out = append(out, cloneSession(s))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastUsedAt.After(out[j].LastUsedAt) })
+	return out, nil
+}
+
+func (r memSessions) Update(ctx context.Context, id string, fields map[string]any) error {
+	defer r.lock()()
+	s, ok := r.db.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	s = cloneSession(s)
+	if err := applyFields(&s, fields); err != nil {
+		return err
+	}
+	memPut(r.tx, r.db.sessions, id, s)
+	return nil
+}
@@ -0,0 +1,282 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+/* ---------------- SHOP ---------------- */
+
+type memShops struct{ memRepo }
+
+func cloneShop(s models.Shop) models.Shop {
+	if s.PriceMin != nil {
+		v := *s.PriceMin
+		s.PriceMin = &v
+	}
+	if s.PriceMax != nil {
+		v := *s.PriceMax
+		s.PriceMax = &v
+	}
+	if s.MenuActiveCnt != nil {
+		v := *s.MenuActiveCnt
+		s.MenuActiveCnt = &v
+	}
+	if s.Address != nil {
+		s.Address = &latlng.LatLng{Latitude: s.Address.Latitude, Longitude: s.Address.Longitude}
+	}
+	s.Hours = s.Hours.Clone()
+	s.ReservationSettings = s.ReservationSettings.Clone()
+	if s.DeletedAt != nil {
+		v := *s.DeletedAt
+		s.DeletedAt = &v
+	}
+	if s.PurgeAfter != nil {
+		v := *s.PurgeAfter
+		s.PurgeAfter = &v
+	}
+	if s.Schedule != nil {
+		v := *s.Schedule
+		s.Schedule = &v
+	}
+	return s
+}
+
+func (r memShops) Create(ctx context.Context, s *models.Shop) error {
+	defer r.lock()()
+	s.ID = newID()
+	memPut(r.tx, r.db.shops, s.ID, cloneShop(*s))
+	return nil
+}
+
+func (r memShops) Get(ctx context.Context, id string) (*models.Shop, error) {
+	defer r.lock()()
+	s, ok := r.db.shops[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	s = cloneShop(s)
+	return &s, nil
+}
+
+func (r memShops) GetByVendor(ctx context.Context, vendorID string) (*models.Shop, error) {
+	defer r.lock()()
+	for _, s := range r.db.shops {
+		if s.VendorID == vendorID {
+			s = cloneShop(s)
+			return &s, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memShops) List(ctx context.Context) ([]models.Shop, error) {
+	defer r.lock()()
+	out := make([]models.Shop, 0, len(r.db.shops))
+	for _, s := range r.db.shops {
+		out = append(out, cloneShop(s))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r memShops) Page(ctx context.Context, q ShopQuery) ([]models.Shop, error) {
+	defer r.lock()()
+	out := make([]models.Shop, 0)
+	for _, s := range r.db.shops {
+		switch {
+		case q.Type != "" && s.Type != q.Type,
+			q.Open != nil && s.Status != *q.Open,
+			q.OrderActive != nil && s.OrderActive != *q.OrderActive,
+			q.ReserveActive != nil && s.ReserveActive != *q.ReserveActive,
+			q.QueueActive != nil && s.QueueActive != *q.QueueActive:
+			continue
+		}
+		// เหมือน Firestore: orderBy ฟิลด์ที่ไม่มีค่า = ไม่อยู่ในผลลัพธ์
+		if (q.Sort == ShopSortPriceAsc || q.Sort == ShopSortPriceDesc) && s.PriceMin == nil {
+			continue
+		}
+		out = append(out, cloneShop(s))
+	}
+
+	// cmp < 0 = a มาก่อน b
+	cmp := func(a, b *models.Shop) int {
+		ca, cb := ShopCursorOf(a, q.Sort), ShopCursorOf(b, q.Sort)
+		return compareShopCursor(ca, cb, q.Sort)
+	}
+	sort.Slice(out, func(i, j int) bool { return cmp(&out[i], &out[j]) < 0 })
+
+	if a := q.After; a != nil {
+		i := 0
+		for i < len(out) && compareShopCursor(ShopCursorOf(&out[i], q.Sort), a, q.Sort) <= 0 {
+			i++
+		}
+		out = out[i:]
+	}
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+// compareShopCursor เรียงแบบเดียวกับ query ฝั่ง Firestore (ค่า sort แล้วตามด้วย id ทิศทางเดียวกัน)
+func compareShopCursor(a, b *ShopCursor, sortBy string) int {
+	c := 0
+	switch sortBy {
+	case ShopSortPriceAsc, ShopSortPriceDesc, ShopSortPopular:
+		c = cmpOrdered(a.N, b.N)
+	default:
+		c = a.T.Compare(b.T)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	if sortBy != ShopSortPriceAsc {
+		c = -c // desc
+	}
+	return c
+}
+
+func cmpOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (r memShops) AddOrderCount(ctx context.Context, id string, delta int) error {
+	defer r.lock()()
+	s, ok := r.db.shops[id]
+	if !ok {
+		return ErrNotFound
+	}
+	s = cloneShop(s)
+	s.OrderCount += delta
+	memPut(r.tx, r.db.shops, id, s)
+	return nil
+}
+
+func (r memShops) ListByGeohash(ctx context.Context, prefixes []string) ([]models.Shop, error) {
+	defer r.lock()()
+	out := make([]models.Shop, 0)
+	for _, s := range r.db.shops {
+		for _, p := range prefixes {
+			if s.Geohash != "" && strings.HasPrefix(s.Geohash, p) {
+				out = append(out, cloneShop(s))
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r memShops) Update(ctx context.Context, id string, fields map[string]any) error {
+	defer r.lock()()
+	s, ok := r.db.shops[id]
+	if !ok {
+		return ErrNotFound
+	}
+	s = cloneShop(s)
+	if err := applyFields(&s, fields); err != nil {
+		return err
+	}
+	memPut(r.tx, r.db.shops, id, s)
+	return nil
+}
+
+func (r memShops) Delete(ctx context.Context, id string) error {
+	defer r.lock()()
+	memDelete(r.tx, r.db.shops, id)
+	return nil
+}
+
+/* ---------------- MENU ---------------- */
+
+type memMenus struct{ memRepo }
+
+func cloneMenuItem(m models.MenuItem) models.MenuItem {
+	if m.Extra != nil {
+		extra := make(map[string]interface{}, len(m.Extra))
+		for k, v := range m.Extra {
+			extra[k] = v
+		}
+		m.Extra = extra
+	}
+	if m.OptionGroups != nil {
+		groups := make([]models.OptionGroup, len(m.OptionGroups))
+		for i, g := range m.OptionGroups {
+			g.Options = append([]models.MenuOption(nil), g.Options...)
+			groups[i] = g
+		}
+		m.OptionGroups = groups
+	}
+	if m.Stock != nil {
+		v := *m.Stock
+		m.Stock = &v
+	}
+	if m.Availability != nil {
+		windows := make([]models.AvailabilityWindow, len(m.Availability))
+		for i, w := range m.Availability {
+			w.Days = append([]string(nil), w.Days...)
+			windows[i] = w
+		}
+		m.Availability = windows
+	}
+	return m
+}
+
+func (r memMenus) Create(ctx context.Context, item *models.MenuItem) error {
+	defer r.lock()()
+	item.ID = newID()
+	memPut(r.tx, memSub(r.db.menus, item.ShopID), item.ID, cloneMenuItem(*item))
+	return nil
+}
+
+func (r memMenus) Get(ctx context.Context, shopID, menuID string) (*models.MenuItem, error) {
+	defer r.lock()()
+	m, ok := r.db.menus[shopID][menuID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	m = cloneMenuItem(m)
+	return &m, nil
+}
+
+func (r memMenus) List(ctx context.Context, shopID string) ([]models.MenuItem, error) {
+	defer r.lock()()
+	items := r.db.menus[shopID]
+	out := make([]models.MenuItem, 0, len(items))
+	for _, m := range items {
+		out = append(out, cloneMenuItem(m))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r memMenus) Update(ctx context.Context, shopID, menuID string, fields map[string]any) error {
+	defer r.lock()()
+	items := r.db.menus[shopID]
+	m, ok := items[menuID]
+	if !ok {
+		return ErrNotFound
+	}
+	m = cloneMenuItem(m)
+	if err := applyFields(&m, fields); err != nil {
+		return err
+	}
+	memPut(r.tx, items, menuID, m)
+	return nil
+}
+
+func (r memMenus) Delete(ctx context.Context, shopID, menuID string) error {
+	defer r.lock()()
+	memDelete(r.tx, r.db.menus[shopID], menuID)
+	return nil
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+/* ---------------- WALLET ---------------- */
+
+type memWallet struct{ memRepo }
+
+func (r memWallet) Append(ctx context.Context, t *models.WalletTxn) error {
+	defer r.lock()()
+	t.ID = newID()
+	memPut(r.tx, memSub(r.db.wallet, t.UserID), t.ID, *t)
+	return nil
+}
+
+func (r memWallet) List(ctx context.Context, userID string, q WalletQuery) ([]models.WalletTxn, error) {
+	defer r.lock()()
+	out := make([]models.WalletTxn, 0)
+	for _, t := range r.db.wallet[userID] {
+		if q.Type != "" && t.Type != q.Type {
+			continue
+		}
+		if !q.StartAfter.IsZero() && !t.CreatedAt.Before(q.StartAfter) {
+			continue
+		}
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+func (r memWallet) Sum(ctx context.Context, userID string) (float64, int, error) {
+	defer r.lock()()
+	var sum float64
+	for _, t := range r.db.wallet[userID] {
+		sum += t.Amount
+	}
+	return sum, len(r.db.wallet[userID]), nil
+}
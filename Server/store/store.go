@@ -0,0 +1,348 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/PPEACH21/MoblieApp_MeebleProject/models"
+)
+
+// ErrNotFound ถูกคืนเมื่อไม่พบเอกสารที่ต้องการ (ทุก implementation ใช้ค่าเดียวกัน)
+var ErrNotFound = errors.New("store: not found")
+
+// Repos คือชุด repository ที่ handler ใช้งาน
+// ได้มาจาก Store ตรง ๆ หรือจากภายใน RunTransaction
+type Repos interface {
+	Shops() ShopStore
+	Menus() MenuStore
+	MenuCategories() MenuCategoryStore
+	Orders() OrderStore
+	Carts() CartStore
+	History() HistoryStore
+	Reservations() ReservationStore
+	Queue() QueueStore
+	CalendarFeeds() CalendarFeedStore
+	Accounts() AccountStore
+	OTPs() OTPStore
+	Wallet() WalletStore
+	Sessions() SessionStore
+	Idempotency() IdempotencyStore
+	Audit() AuditStore
+}
+
+// TxFunc คือฟังก์ชันที่รันภายใน transaction
+// ต้องใช้ repos ที่ได้รับเท่านั้น และ (ตามข้อจำกัดของ Firestore) ต้องอ่านให้ครบก่อนเขียน
+type TxFunc func(ctx context.Context, tx Repos) error
+
+// Store คือ entry point ของ persistence layer
+type Store interface {
+	Repos
+	// RunTransaction รัน fn แบบ atomic; ถ้า fn คืน error จะ rollback และคืน error นั้นตรง ๆ
+	// ห้ามเรียกซ้อนกัน
+	RunTransaction(ctx context.Context, fn TxFunc) error
+	Close() error
+}
+
+/* ---------------- SHOP / MENU ---------------- */
+
+type ShopStore interface {
+	// Create บันทึกร้านใหม่และตั้งค่า s.ID
+	Create(ctx context.Context, s *models.Shop) error
+	Get(ctx context.Context, id string) (*models.Shop, error)
+	GetByVendor(ctx context.Context, vendorID string) (*models.Shop, error)
+	List(ctx context.Context) ([]models.Shop, error)
+	// Page คืนร้านหนึ่งหน้าตาม q (เรียงตาม q.Sort ต่อจาก q.After)
+	Page(ctx context.Context, q ShopQuery) ([]models.Shop, error)
+	// AddOrderCount เพิ่ม order_count แบบ atomic (ไม่ต้องอ่านก่อน)
+	AddOrderCount(ctx context.Context, id string, delta int) error
+	// ListByGeohash คืนร้านที่ geohash ขึ้นต้นด้วย prefix ใด prefix หนึ่ง (ไม่ซ้ำ)
+	ListByGeohash(ctx context.Context, prefixes []string) ([]models.Shop, error)
+	// Update อัปเดตบางฟิลด์ (key = ชื่อฟิลด์ใน Firestore เช่น "shop_name")
+	Update(ctx context.Context, id string, fields map[string]any) error
+	Delete(ctx context.Context, id string) error
+	// ListPurgeable คืนร้านที่ถูก soft delete และ purge_after <= before
+	ListPurgeable(ctx context.Context, before time.Time) ([]models.Shop, error)
+}
+
+// PurgeBatchSize คือจำนวนเอกสารต่อรอบของเมธอด DeleteByShop / ReleaseByShop
+// (เรียกซ้ำจนได้น้อยกว่า limit = หมดแล้ว)
+const PurgeBatchSize = 200
+
+// ลำดับของ ShopStore.Page
+const (
+	ShopSortNewest    = "newest"     // createdAt ใหม่ → เก่า (ค่าเริ่มต้น)
+	ShopSortPriceAsc  = "price_asc"  // price_min น้อย → มาก (เฉพาะร้านที่มี price_min)
+	ShopSortPriceDesc = "price_desc" // price_min มาก → น้อย (เฉพาะร้านที่มี price_min)
+	ShopSortPopular   = "popular"    // order_count มาก → น้อย
+)
+
+func IsShopSort(s string) bool {
+	switch s {
+	case ShopSortNewest, ShopSortPriceAsc, ShopSortPriceDesc, ShopSortPopular:
+		return true
+	}
+	return false
+}
+
+// ShopQuery ใช้กับ ShopStore.Page (ฟิลด์ว่าง / nil = ไม่กรอง)
+// ทุกเงื่อนไขเป็น equality เพื่อให้ Firestore ใช้ index ได้ (ช่วงราคากรองต่อที่ชั้นบน)
+type ShopQuery struct {
+	Type          string
+	Open          *bool
+	OrderActive   *bool
+	ReserveActive *bool
+	QueueActive   *bool
+	Sort          string
+	Limit         int
+	After         *ShopCursor
+}
+
+// ShopCursor คือตำแหน่งของร้านตัวสุดท้ายในหน้าก่อน (ค่าที่ใช้เรียง + id กันค่าซ้ำ)
+type ShopCursor struct {
+	ID string    `json:"i"`
+	T  time.Time `json:"t,omitempty"` // newest
+	N  float64   `json:"n,omitempty"` // price / popular
+}
+
+// ShopCursorOf สร้าง cursor จากร้านตามลำดับ sort
+func ShopCursorOf(s *models.Shop, sort string) *ShopCursor {
+	c := &ShopCursor{ID: s.ID}
+	switch sort {
+	case ShopSortPriceAsc, ShopSortPriceDesc:
+		if s.PriceMin != nil {
+			c.N = *s.PriceMin
+		}
+	case ShopSortPopular:
+		c.N = float64(s.OrderCount)
+	default:
+		c.T = s.CreatedAt
+	}
+	return c
+}
+
+// Encode แปลง cursor เป็น string ส่งให้ client (ไม่ต้องอ่านเข้าใจ)
+func (c *ShopCursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeShopCursor(v string) (*ShopCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var c ShopCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	return &c, nil
+}
+
+type MenuStore interface {
+	// Create บันทึกเมนูใหม่ใต้ item.ShopID และตั้งค่า item.ID
+	Create(ctx context.Context, item *models.MenuItem) error
+	Get(ctx context.Context, shopID, menuID string) (*models.MenuItem, error)
+	List(ctx context.Context, shopID string) ([]models.MenuItem, error)
+	Update(ctx context.Context, shopID, menuID string, fields map[string]any) error
+	Delete(ctx context.Context, shopID, menuID string) error
+	// DeleteByShop ลบเมนูของร้านสูงสุด limit รายการ คืนจำนวนที่ลบ
+	DeleteByShop(ctx context.Context, shopID string, limit int) (int, error)
+}
+
+type MenuCategoryStore interface {
+	// Create บันทึกหมวดใหม่ใต้ c.ShopID และตั้งค่า c.ID
+	Create(ctx context.Context, c *models.MenuCategory) error
+	Get(ctx context.Context, shopID, id string) (*models.MenuCategory, error)
+	// List คืนทุกหมวดของร้านเรียงตาม position
+	List(ctx context.Context, shopID string) ([]models.MenuCategory, error)
+	Update(ctx context.Context, shopID, id string, fields map[string]any) error
+	Delete(ctx context.Context, shopID, id string) error
+	// DeleteByShop ลบหมวดของร้านสูงสุด limit รายการ คืนจำนวนที่ลบ
+	DeleteByShop(ctx context.Context, shopID string, limit int) (int, error)
+}
+
+/* ---------------- ORDER / CART / HISTORY ---------------- */
+
+// OrderFilter ใช้กรองรายการออเดอร์ (ฟิลด์ว่าง = ไม่กรอง)
+type OrderFilter struct {
+	ShopID     string
+	CustomerID string
+	Status     string
+}
+
+type OrderStore interface {
+	// Create บันทึกออเดอร์ใหม่และตั้งค่า o.ID
+	Create(ctx context.Context, o *models.Order) error
+	Get(ctx context.Context, id string) (*models.Order, error)
+	// List คืนออเดอร์ที่ตรง filter เรียงจากใหม่ไปเก่า
+	List(ctx context.Context, f OrderFilter) ([]models.Order, error)
+	Update(ctx context.Context, id string, fields map[string]any) error
+	Delete(ctx context.Context, id string) error
+	// DeleteByShop ลบออเดอร์ของร้านสูงสุด limit รายการ คืนจำนวนที่ลบ
+	DeleteByShop(ctx context.Context, shopID string, limit int) (int, error)
+}
+
+type CartStore interface {
+	Get(ctx context.Context, customerID string) (*models.Cart, error)
+	Put(ctx context.Context, cart *models.Cart) error
+	// ReleaseByShop ล้างตะกร้าที่ผูกกับร้านนี้สูงสุด limit ใบ (ให้ลูกค้าเริ่มตะกร้าใหม่กับร้านอื่นได้) คืนจำนวนใบ
+	ReleaseByShop(ctx context.Context, shopID string, limit int) (int, error)
+}
+
+// HistoryQuery ใช้แบ่งหน้า history (เรียงตาม movedToHistoryAt ใหม่ไปเก่า)
+type HistoryQuery struct {
+	Status     string
+	Limit      int
+	StartAfter time.Time
+}
+
+type HistoryStore interface {
+	// Put เขียน history ทั้งฝั่งร้าน (shops/{shopId}/history) และฝั่ง user (users/{userId}/history)
+	Put(ctx context.Context, h *models.HistoryItem) error
+	ListByShop(ctx context.Context, shopID string, q HistoryQuery) ([]models.HistoryItem, error)
+	ListByUser(ctx context.Context, userID string, q HistoryQuery) ([]models.HistoryItem, error)
+	GetForUser(ctx context.Context, userID, historyID string) (*models.HistoryItem, error)
+	// DeleteByShop ลบ history ฝั่งร้านสูงสุด limit รายการ (ฝั่ง user เก็บไว้เป็นประวัติการซื้อ) คืนจำนวนที่ลบ
+	DeleteByShop(ctx context.Context, shopID string, limit int) (int, error)
+}
+
+/* ---------------- RESERVATION ---------------- */
+
+type ReservationStore interface {
+	// Create เขียนทั้ง reservations/{id} และ users/{userId}/reservations/{id} แล้วตั้งค่า r.ID
+	Create(ctx context.Context, r *models.Reservation) error
+	Get(ctx context.Context, id string) (*models.Reservation, error)
+	// Save เขียนทับทั้งสองที่ใน batch เดียว (เปลี่ยนสถานะ) — สำเนาฝั่ง user ตรงกับเอกสารหลักเสมอ
+	Save(ctx context.Context, r *models.Reservation) error
+	ListByShop(ctx context.Context, shopID string) ([]models.Reservation, error)
+	// ListByShopDays คืนการจองของร้านที่ DayKey อยู่ใน days (สูงสุด 10 วัน)
+	ListByShopDays(ctx context.Context, shopID string, days []string) ([]models.Reservation, error)
+	ListByUser(ctx context.Context, userID string) ([]models.Reservation, error)
+	// ListOverdue คืนการจองที่ยัง confirmed / seated และ slot_end ก่อน before (ทุกร้าน) สูงสุด limit รายการ
+	ListOverdue(ctx context.Context, before time.Time, limit int) ([]models.Reservation, error)
+	// LockDays อ่านแล้วเขียนเอกสาร reservation_days ของแต่ละวัน ใช้ใน transaction หลังอ่านอย่างอื่นครบแล้ว
+	// การจองวันเดียวกันที่รันพร้อมกันจะชนกันที่เอกสารนี้ (Firestore retry ให้) จึงไม่จองเกินที่ว่าง
+	LockDays(ctx context.Context, shopID string, days []string) error
+	// DeleteByShop ลบการจองของร้าน (ทั้งสองที่) สูงสุด limit รายการ คืนจำนวนที่ลบ
+	DeleteByShop(ctx context.Context, shopID string, limit int) (int, error)
+}
+
+/* ---------------- WALK-IN QUEUE ---------------- */
+
+type QueueStore interface {
+	// Create บันทึกบัตรคิวใหม่และตั้งค่า t.ID
+	Create(ctx context.Context, t *models.QueueTicket) error
+	Get(ctx context.Context, id string) (*models.QueueTicket, error)
+	Update(ctx context.Context, id string, fields map[string]any) error
+	// ListActive คืนบัตรที่ยังอยู่ในคิวของร้าน (waiting / called) เรียงตามเวลาที่รับบัตร
+	ListActive(ctx context.Context, shopID string) ([]models.QueueTicket, error)
+	// ListActiveByUser คืนบัตรที่ยังอยู่ในคิวของ user (ทุกร้าน)
+	ListActiveByUser(ctx context.Context, userID string) ([]models.QueueTicket, error)
+	// NextNumber อ่านแล้วเพิ่มเลขคิวของร้านในวัน day; ใน transaction ต้องเรียกหลังอ่านอย่างอื่นครบแล้ว
+	NextNumber(ctx context.Context, shopID, day string) (int, error)
+	// DeleteByShop ลบบัตรคิวของร้าน (แล้วตามด้วยตัวนับรายวัน) สูงสุด limit รายการ คืนจำนวนที่ลบ
+	DeleteByShop(ctx context.Context, shopID string, limit int) (int, error)
+}
+
+/* ---------------- CALENDAR FEED ---------------- */
+
+// CalendarFeedStore เก็บ token ของ feed .ics หนึ่งตัวต่อ (kind, ownerID)
+type CalendarFeedStore interface {
+	// Put เขียนทับ token เดิมของ feed (ถ้ามี)
+	Put(ctx context.Context, f *models.CalendarFeed) error
+	Get(ctx context.Context, kind, ownerID string) (*models.CalendarFeed, error)
+	Delete(ctx context.Context, kind, ownerID string) error
+}
+
+// CalendarFeedKey คือ id ของเอกสาร calendar_feeds
+func CalendarFeedKey(kind, ownerID string) string { return kind + "_" + ownerID }
+
+/* ---------------- ACCOUNT / OTP ---------------- */
+
+const (
+	RoleUser   = "user"
+	RoleVendor = "vendor"
+)
+
+// AccountStore ครอบทั้ง collection users และ vendors
+// ค่า Role ของ models.User ที่คืนมาจะบอกว่ามาจาก collection ไหน
+type AccountStore interface {
+	// Create บันทึกบัญชีใหม่ (Role == "vendor" -> vendors, อื่น ๆ -> users) และตั้งค่า u.ID
+	Create(ctx context.Context, u *models.User) error
+	// Get หาใน users ก่อนแล้วค่อย vendors
+	Get(ctx context.Context, id string) (*models.User, error)
+	GetAs(ctx context.Context, role, id string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	Update(ctx context.Context, role, id string, fields map[string]any) error
+}
+
+// OTPStore เก็บ OTP หนึ่งตัวต่อ (purpose, email)
+type OTPStore interface {
+	Put(ctx context.Context, rec *models.OTPRecord) error
+	Get(ctx context.Context, purpose, email string) (*models.OTPRecord, error)
+	Delete(ctx context.Context, purpose, email string) error
+}
+
+// OTPKey คือ id ของเอกสาร OTP
+func OTPKey(purpose, email string) string { return purpose + ":" + email }
+
+/* ---------------- WALLET ---------------- */
+
+// WalletQuery ใช้แบ่งหน้า ledger (เรียงตาม createdAt ใหม่ไปเก่า)
+type WalletQuery struct {
+	Type       string
+	Limit      int
+	StartAfter time.Time
+}
+
+// WalletStore คือ ledger แบบ append-only ของแต่ละ user (ไม่มี Update/Delete)
+type WalletStore interface {
+	// Append บันทึกรายการใหม่และตั้งค่า t.ID
+	Append(ctx context.Context, t *models.WalletTxn) error
+	List(ctx context.Context, userID string, q WalletQuery) ([]models.WalletTxn, error)
+	// Sum คืนผลรวม Amount และจำนวนรายการทั้งหมดของ user
+	Sum(ctx context.Context, userID string) (float64, int, error)
+}
+
+/* ---------------- SESSION ---------------- */
+
+type SessionStore interface {
+	// Create บันทึก session ใหม่และตั้งค่า s.ID
+	Create(ctx context.Context, s *models.Session) error
+	Get(ctx context.Context, id string) (*models.Session, error)
+	// ListByUser คืนทุก session ของ user (รวมที่ revoke/หมดอายุแล้ว) เรียงจากใช้ล่าสุด
+	ListByUser(ctx context.Context, userID string) ([]models.Session, error)
+	Update(ctx context.Context, id string, fields map[string]any) error
+}
+
+/* ---------------- IDEMPOTENCY ---------------- */
+
+type IdempotencyStore interface {
+	Get(ctx context.Context, userID, key string) (*models.IdempotencyRecord, error)
+	// Put เขียนทับ record ของ rec.UserID + rec.Key
+	Put(ctx context.Context, rec *models.IdempotencyRecord) error
+	Delete(ctx context.Context, userID, key string) error
+	// DeleteExpired ลบ record ที่ expiresAt ก่อน before สูงสุด limit รายการ คืนจำนวนที่ลบ
+	DeleteExpired(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+// IdempotencyDocID คือ id ของเอกสาร (hash เพราะ key มาจาก client อาจมีตัวอักษรที่ใช้เป็น doc id ไม่ได้)
+func IdempotencyDocID(userID, key string) string {
+	sum := sha256.Sum256([]byte(userID + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+/* ---------------- AUDIT ---------------- */
+
+// AuditStore คือบันทึกแบบ append-only (ไม่มี Update/Delete)
+type AuditStore interface {
+	// Create บันทึกรายการใหม่และตั้งค่า a.ID
+	Create(ctx context.Context, a *models.AuditRecord) error
+	ListByTarget(ctx context.Context, targetType, targetID string) ([]models.AuditRecord, error)
+}